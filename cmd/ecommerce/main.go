@@ -6,23 +6,51 @@ import (
 	"fmt"
 	"github.com/ardanlabs/conf/v3"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeyapp"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeystore/apikeydb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditstore/auditdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/auth/authapp"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/check/checkapp"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailstore/emaildb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutstore/lockoutdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfabus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfastore/mfadb"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderapp"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderstore/orderdb"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productapp"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productstore/productdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitstore/ratelimitdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitstore/ratelimitmem"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewapp"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewstore/reviewdb"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userapp"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userstore/userdb"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/debug"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/clients/oidcclient"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/mailer"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/notify"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/aesgcm"
 	"github.com/nhannguyenacademy/ecommerce/pkg/keystore"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"github.com/nhannguyenacademy/ecommerce/pkg/tracer"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 )
@@ -32,17 +60,86 @@ var build = "develop"
 type config struct {
 	conf.Version
 	Server struct {
-		ReadTimeout        time.Duration `conf:"default:5s"`
-		WriteTimeout       time.Duration `conf:"default:10s"`
-		IdleTimeout        time.Duration `conf:"default:120s"`
-		ShutdownTimeout    time.Duration `conf:"default:20s"`
-		Host               string        `conf:"default:0.0.0.0:8080"`
-		CORSAllowedOrigins []string      `conf:"default:*"`
+		ReadTimeout     time.Duration `conf:"default:5s"`
+		WriteTimeout    time.Duration `conf:"default:10s"`
+		IdleTimeout     time.Duration `conf:"default:120s"`
+		ShutdownTimeout time.Duration `conf:"default:20s"`
+		// DrainDelay is how long the service reports not ready before the
+		// server stops, for the load balancers to take it out.
+		DrainDelay time.Duration `conf:"default:5s"`
+		Host       string        `conf:"default:0.0.0.0:8080"`
+		DebugHost  string        `conf:"default:0.0.0.0:3010"`
+		// TrustedProxies are the addresses or CIDRs of the proxies in front
+		// of the service, the address of the client is only taken from the
+		// X-Forwarded-For header they set. None are trusted by default.
+		TrustedProxies []string
+		// CORSAllowedOrigins are exact origins, wildcard subdomains like
+		// https://*.example.com, or * for any, * can't go with credentials.
+		CORSAllowedOrigins   []string      `conf:"default:*"`
+		CORSAllowedMethods   []string      `conf:"default:GET;POST;PUT;PATCH;DELETE"`
+		CORSAllowedHeaders   []string      `conf:"default:Authorization;Content-Type;If-Match"`
+		CORSExposedHeaders   []string      `conf:"default:ETag;RateLimit-Policy;RateLimit-Limit;RateLimit-Remaining;RateLimit-Reset;Retry-After"`
+		CORSAllowCredentials bool          `conf:"default:false"`
+		CORSMaxAge           time.Duration `conf:"default:10m"`
 	}
 	Auth struct {
 		KeysFolder string `conf:"default:configs/keys/"`
 		ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
-		Issuer     string `conf:"default:Nhan Nguyen Academy"`
+		// KeyGracePeriod is how long a retired key still verifies tokens, it
+		// should outlive the access tokens it signed.
+		KeyGracePeriod time.Duration `conf:"default:30m"`
+		// KeysWatchInterval is how often the keys folder is checked for a
+		// rotation, 0 disables the watch and leaves SIGHUP as the only way.
+		KeysWatchInterval time.Duration `conf:"default:30s"`
+		Issuer            string        `conf:"default:ecommerce"`
+		Audiences         []string      `conf:"default:ecommerce-api"`
+		Leeway            time.Duration `conf:"default:30s"`
+		// UserCacheTTL is how long the state of a user is trusted without
+		// checking the database, 0 disables the cache.
+		UserCacheTTL    time.Duration `conf:"default:30s"`
+		UserCacheSize   int           `conf:"default:10000"`
+		AccessTokenTTL  time.Duration `conf:"default:15m"`
+		RefreshTokenTTL time.Duration `conf:"default:720h"`
+		// RolePermissions overrides the permissions of roles, entries are
+		// separated by ; and look like SUPPORT_AGENT=user:read,order:read.
+		RolePermissions []string
+	}
+	Lockout struct {
+		AccountThreshold int           `conf:"default:5"`
+		IPThreshold      int           `conf:"default:50"`
+		Window           time.Duration `conf:"default:15m"`
+		BaseLockout      time.Duration `conf:"default:1m"`
+		MaxLockout       time.Duration `conf:"default:24h"`
+	}
+	RateLimit struct {
+		Backend string `conf:"default:memory,help:memory or postgres"`
+		// Default is the policy of the routes not listed, like 600/1m for
+		// 600 requests a minute, 0 leaves them unlimited.
+		Default string `conf:"default:600/1m"`
+		// Routes are the policies of routes, entries are separated by ; and
+		// look like POST /api/v1/users/register=5/1h.
+		Routes        []string      `conf:"default:GET /api/v1/products=120/1m;POST /api/v1/users/register=5/1h;POST /api/v1/users/login=20/1m"`
+		PruneInterval time.Duration `conf:"default:5m"`
+	}
+	MFA struct {
+		EncryptionKey   string `conf:"required,mask,help:base64 encoded 32 byte key"`
+		Issuer          string `conf:"default:Ecommerce"`
+		RequireForAdmin bool   `conf:"default:false"`
+	}
+	OIDC struct {
+		StateKey     string `conf:"required,mask,help:base64 encoded 32 byte key"`
+		BaseURL      string `conf:"default:http://localhost:8080/api/v1"`
+		SecureCookie bool   `conf:"default:true"`
+		Google       struct {
+			IssuerURL    string `conf:"default:https://accounts.google.com"`
+			ClientID     string
+			ClientSecret string `conf:"mask"`
+		}
+		Facebook struct {
+			IssuerURL    string `conf:"default:https://www.facebook.com"`
+			ClientID     string
+			ClientSecret string `conf:"mask"`
+		}
 	}
 	DB struct {
 		User            string        `conf:"default:postgres"`
@@ -54,22 +151,34 @@ type config struct {
 		MaxConnLifeTime time.Duration `conf:"default:5m"`
 		DisableTLS      bool          `conf:"default:true"`
 	}
+	Mailer struct {
+		Driver         string `conf:"default:log,help:smtp or log"`
+		Host           string `conf:"default:mailpit:1025"`
+		Username       string
+		Password       string        `conf:"mask"`
+		From           string        `conf:"default:Ecommerce <no-reply@ecommerce.local>"`
+		Timeout        time.Duration `conf:"default:30s"`
+		Dir            string
+		BaseURL        string        `conf:"default:http://localhost:8080"`
+		WorkerInterval time.Duration `conf:"default:5s"`
+		BatchSize      int           `conf:"default:20"`
+		MaxAttempts    int           `conf:"default:5"`
+		RetryDelay     time.Duration `conf:"default:30s"`
+	}
 	Tempo struct {
+		Exporter    string  `conf:"default:otlp,help:otlp or stdout or none"`
 		Host        string  `conf:"default:tempo:4317"`
 		ServiceName string  `conf:"default:ecommerce"`
 		Probability float64 `conf:"default:0.05"`
 		// Shouldn't use a high Probability value in non-developer systems.
 		// 0.05 should be enough for most systems. Some might want to have
 		// this even lower.
+		ExcludedRoutes []string `conf:"default:/.well-known/jwks.json;/healthz;/readyz"`
 	}
 }
 
 func main() {
-	// todo: implement this
-	traceIDFn := func(ctx context.Context) string {
-		return ""
-	}
-	log := logger.New(os.Stdout, logger.LevelInfo, "ecommerce", traceIDFn)
+	log := logger.New(os.Stdout, logger.LevelInfo, "ecommerce", tracer.GetTraceID)
 
 	// -------------------------------------------------------------------------
 
@@ -132,14 +241,93 @@ func run(ctx context.Context, log *logger.Logger) error {
 
 	defer db.Close()
 
+	// -------------------------------------------------------------------------
+	// Start Tracing Support
+
+	log.Info(ctx, "startup", "status", "initializing tracing support", "exporter", cfg.Tempo.Exporter)
+
+	excludedRoutes := make(map[string]struct{}, len(cfg.Tempo.ExcludedRoutes))
+	for _, route := range cfg.Tempo.ExcludedRoutes {
+		excludedRoutes[route] = struct{}{}
+	}
+
+	traceProvider, err := tracer.InitTracing(tracer.Config{
+		Log:            log,
+		ServiceName:    cfg.Tempo.ServiceName,
+		Host:           cfg.Tempo.Host,
+		ExcludedRoutes: excludedRoutes,
+		Probability:    cfg.Tempo.Probability,
+		Exporter:       cfg.Tempo.Exporter,
+	})
+	if err != nil {
+		return fmt.Errorf("starting tracing: %w", err)
+	}
+
+	// The spans still buffered are flushed before leaving.
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := traceProvider.Shutdown(ctx); err != nil {
+			log.Error(ctx, "shutdown", "status", "flushing traces", "err", err)
+		}
+	}()
+
 	// -------------------------------------------------------------------------
 	// Init auth
-	ks := keystore.New()
-	if err := ks.LoadRSAKeys(os.DirFS(cfg.Auth.KeysFolder)); err != nil {
+	ks := keystore.New(keystore.Config{DefaultKID: cfg.Auth.ActiveKID, GracePeriod: cfg.Auth.KeyGracePeriod})
+	keysFS := os.DirFS(cfg.Auth.KeysFolder)
+	if err := ks.LoadKeys(keysFS); err != nil {
 		return fmt.Errorf("reading keys: %w", err)
 	}
 
-	ath, err := auth.New(auth.Config{Log: log, DB: db, KeyLookup: ks, Issuer: "ecommerce"})
+	keysReloaded := func(err error) {
+		if err != nil {
+			log.Error(ctx, "keys", "status", "reload failed, keeping the current keys", "err", err)
+			return
+		}
+		log.Info(ctx, "keys", "status", "keys reloaded", "active_kid", ks.ActiveKID(), "kids", ks.KIDs())
+	}
+
+	keysCtx, stopKeys := context.WithCancel(ctx)
+	defer stopKeys()
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	go func() {
+		for {
+			select {
+			case <-keysCtx.Done():
+				return
+			case <-reload:
+				keysReloaded(ks.LoadKeys(keysFS))
+			}
+		}
+	}()
+
+	if cfg.Auth.KeysWatchInterval > 0 {
+		go ks.Watch(keysCtx, keysFS, cfg.Auth.KeysWatchInterval, keysReloaded)
+	}
+
+	rolePerms, err := auth.ParseRolePermissions(cfg.Auth.RolePermissions)
+	if err != nil {
+		return fmt.Errorf("parsing role permissions: %w", err)
+	}
+
+	authCfg := auth.Config{
+		Log:             log,
+		DB:              db,
+		KeyLookup:       ks,
+		Issuer:          cfg.Auth.Issuer,
+		Audiences:       cfg.Auth.Audiences,
+		Leeway:          cfg.Auth.Leeway,
+		UserCacheTTL:    cfg.Auth.UserCacheTTL,
+		UserCacheSize:   cfg.Auth.UserCacheSize,
+		RolePermissions: rolePerms,
+	}
+
+	ath, err := auth.New(authCfg)
 	if err != nil {
 		return fmt.Errorf("creating auth: %w", err)
 	}
@@ -147,12 +335,203 @@ func run(ctx context.Context, log *logger.Logger) error {
 	// -------------------------------------------------------------------------
 	// Init businesses
 
+	notifySink := notify.NewLogSink(log)
+
+	var mail mailer.Mailer
+	switch cfg.Mailer.Driver {
+	case "smtp":
+		mail = mailer.NewSMTP(mailer.SMTPConfig{
+			Host:     cfg.Mailer.Host,
+			Username: cfg.Mailer.Username,
+			Password: cfg.Mailer.Password,
+			From:     cfg.Mailer.From,
+			Timeout:  cfg.Mailer.Timeout,
+		})
+	case "log":
+		mail = mailer.NewFile(log, cfg.Mailer.Dir, cfg.Mailer.From)
+	default:
+		return fmt.Errorf("unknown mailer driver %q", cfg.Mailer.Driver)
+	}
+
+	emailBus := emailbus.NewBusiness(log, emaildb.NewStore(log, db), mail, emailbus.Config{
+		BaseURL:     cfg.Mailer.BaseURL,
+		MaxAttempts: cfg.Mailer.MaxAttempts,
+		RetryDelay:  cfg.Mailer.RetryDelay,
+	})
+
+	auditBus := auditbus.NewBusiness(log, auditdb.NewStore(log, db))
+
+	lockoutBus := lockoutbus.NewBusiness(log, lockoutdb.NewStore(log, db), lockoutbus.Config{
+		AccountThreshold: cfg.Lockout.AccountThreshold,
+		IPThreshold:      cfg.Lockout.IPThreshold,
+		Window:           cfg.Lockout.Window,
+		BaseLockout:      cfg.Lockout.BaseLockout,
+		MaxLockout:       cfg.Lockout.MaxLockout,
+	})
+
+	mfaCipher, err := aesgcm.New(cfg.MFA.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("constructing mfa cipher: %w", err)
+	}
+
+	mfaBus := mfabus.NewBusiness(log, mfadb.NewStore(log, db), mfaCipher, cfg.MFA.Issuer)
+
+	oidcStateCipher, err := aesgcm.New(cfg.OIDC.StateKey)
+	if err != nil {
+		return fmt.Errorf("constructing oidc state cipher: %w", err)
+	}
+
+	// A provider is only offered once the client is registered with it.
+	oidcProviders := make(map[string]*oidcclient.Client)
+	for name, p := range map[string]oidcclient.Config{
+		"google": {
+			IssuerURL:    cfg.OIDC.Google.IssuerURL,
+			ClientID:     cfg.OIDC.Google.ClientID,
+			ClientSecret: cfg.OIDC.Google.ClientSecret,
+		},
+		"facebook": {
+			IssuerURL:    cfg.OIDC.Facebook.IssuerURL,
+			ClientID:     cfg.OIDC.Facebook.ClientID,
+			ClientSecret: cfg.OIDC.Facebook.ClientSecret,
+		},
+	} {
+		if p.ClientID == "" {
+			continue
+		}
+
+		p.RedirectURL = fmt.Sprintf("%s/users/oidc/%s/callback", cfg.OIDC.BaseURL, name)
+		oidcProviders[name] = oidcclient.New(p)
+		log.Info(ctx, "startup", "status", "oidc provider configured", "provider", name)
+	}
+
 	userBus := userbus.NewBusiness(log, userdb.NewStore(log, db))
+	userBus.OnChange(func(ctx context.Context, userID uuid.UUID) {
+		ath.InvalidateUser(userID)
+	})
 
-	productBus := productbus.NewBusiness(log, productdb.NewStore(log, db))
+	productBus := productbus.NewBusiness(log, productdb.NewStore(log, db), notifySink)
 
 	orderBus := orderbus.NewBusiness(log, orderdb.NewStore(log, db))
 
+	reviewBus := reviewbus.NewBusiness(log, reviewdb.NewStore(log, db), productBus)
+
+	apiKeyBus := apikeybus.NewBusiness(log, apikeydb.NewStore(log, db))
+
+	var rateLimitStore ratelimitbus.Storer
+	switch cfg.RateLimit.Backend {
+	case "postgres":
+		rateLimitStore = ratelimitdb.NewStore(log, db)
+	case "memory":
+		rateLimitStore = ratelimitmem.NewStore(log)
+	default:
+		return fmt.Errorf("unknown rate limit backend %q", cfg.RateLimit.Backend)
+	}
+
+	rateLimitBus := ratelimitbus.NewBusiness(log, rateLimitStore)
+
+	rateLimitRoutes, err := mid.ParseRateLimitRoutes(cfg.RateLimit.Routes)
+	if err != nil {
+		return fmt.Errorf("parsing rate limit routes: %w", err)
+	}
+
+	rateLimitDefault, err := ratelimitbus.ParsePolicy("default", cfg.RateLimit.Default)
+	if err != nil {
+		return fmt.Errorf("parsing rate limit default: %w", err)
+	}
+
+	rateLimitCfg := mid.RateLimitConfig{
+		Routes:  rateLimitRoutes,
+		Default: rateLimitDefault,
+	}
+
+	// -------------------------------------------------------------------------
+	// Start Debug Service
+
+	if err := metrics.RegisterDBStats(db.DB, cfg.DB.Name); err != nil {
+		return fmt.Errorf("registering db stats metrics: %w", err)
+	}
+
+	go func() {
+		log.Info(ctx, "startup", "status", "debug router started", "host", cfg.Server.DebugHost)
+
+		if err := http.ListenAndServe(cfg.Server.DebugHost, debug.Mux()); err != nil {
+			log.Error(ctx, "shutdown", "status", "debug router closed", "host", cfg.Server.DebugHost, "err", err)
+		}
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start User Change Listener
+
+	// Other replicas, and the admin tool, notify the changes of users the
+	// cached state must forget.
+	listenerCtx, stopListener := context.WithCancel(ctx)
+	listenerDone := make(chan struct{})
+	go func() {
+		defer close(listenerDone)
+		log.Info(ctx, "startup", "status", "user change listener started", "channel", userdb.ChangeChannel)
+		sqldb.Listen(listenerCtx, log, db, userdb.ChangeChannel, ath.InvalidateUsers, func(payload string) {
+			userID, err := uuid.Parse(payload)
+			if err != nil {
+				log.Error(ctx, "user change listener", "status", "invalid payload", "payload", payload, "err", err)
+				return
+			}
+			ath.InvalidateUser(userID)
+		})
+	}()
+	defer func() {
+		stopListener()
+		<-listenerDone
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Rate Limit Pruner
+
+	// Buckets idle for the longest period are full again, they are the same
+	// as no bucket.
+	rateLimitIdle := rateLimitDefault.Period
+	for _, p := range rateLimitRoutes {
+		rateLimitIdle = max(rateLimitIdle, p.Period)
+	}
+
+	prunerCtx, stopPruner := context.WithCancel(ctx)
+	prunerDone := make(chan struct{})
+	go func() {
+		defer close(prunerDone)
+
+		ticker := time.NewTicker(cfg.RateLimit.PruneInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-prunerCtx.Done():
+				return
+			case <-ticker.C:
+				if err := rateLimitBus.Prune(prunerCtx, rateLimitIdle); err != nil {
+					log.Error(ctx, "rate limit pruner", "err", err)
+				}
+			}
+		}
+	}()
+	defer func() {
+		stopPruner()
+		<-prunerDone
+	}()
+
+	// -------------------------------------------------------------------------
+	// Start Email Worker
+
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		log.Info(ctx, "startup", "status", "email worker started", "driver", cfg.Mailer.Driver)
+		emailbus.NewWorker(emailBus, sqldb.NewBeginner(db), cfg.Mailer.WorkerInterval, cfg.Mailer.BatchSize).Run(workerCtx)
+	}()
+	defer func() {
+		stopWorker()
+		<-workerDone
+	}()
+
 	// -------------------------------------------------------------------------
 	// Start API Service
 
@@ -163,14 +542,46 @@ func run(ctx context.Context, log *logger.Logger) error {
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
 	// Setup routes
-	// todo: cors, tracing, metrics
+	if cfg.Server.CORSAllowCredentials && slices.Contains(cfg.Server.CORSAllowedOrigins, "*") {
+		return errors.New("cors: any origin can't be allowed along with credentials, list the origins")
+	}
+
+	corsCfg := mid.CORSConfig{
+		AllowedOrigins:   cfg.Server.CORSAllowedOrigins,
+		AllowedMethods:   cfg.Server.CORSAllowedMethods,
+		AllowedHeaders:   cfg.Server.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.Server.CORSExposedHeaders,
+		AllowCredentials: cfg.Server.CORSAllowCredentials,
+		MaxAge:           cfg.Server.CORSMaxAge,
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	ginEngine := gin.New()
-	ginEngine.Use(mid.Logging(log, []string{}), mid.Panic(log))
+	if err := ginEngine.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("setting trusted proxies: %w", err)
+	}
+	ginEngine.Use(mid.Tracing(traceProvider.Tracer(cfg.Tempo.ServiceName)), mid.Metrics(), mid.Logging(log, []string{"/api/v1/products/import", "/api/v1/products/export", "/healthz", "/readyz"}), mid.Panic(log), mid.CORS(corsCfg), mid.RateLimit(log, ath, rateLimitBus, rateLimitCfg))
 	apiV1Router := ginEngine.Group("api/v1")
-	userapp.New(log, ath, cfg.Auth.ActiveKID, userBus).Routes(apiV1Router)
-	productapp.New(log, ath, productBus).Routes(apiV1Router)
-	orderapp.New(log, ath, sqldb.NewBeginner(db), orderBus, productBus, userBus).Routes(apiV1Router)
+	userCfg := userapp.Config{
+		AccessTokenTTL:   cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL:  cfg.Auth.RefreshTokenTTL,
+		RequireAdminMFA:  cfg.MFA.RequireForAdmin,
+		OIDCProviders:    oidcProviders,
+		OIDCStateCipher:  oidcStateCipher,
+		OIDCSecureCookie: cfg.OIDC.SecureCookie,
+	}
+	userapp.New(log, ath, userCfg, sqldb.NewBeginner(db), userBus, emailBus, auditBus, lockoutBus, mfaBus).Routes(apiV1Router)
+	productapp.New(log, ath, sqldb.NewBeginner(db), productBus).Routes(apiV1Router)
+	orderapp.New(log, ath, sqldb.NewBeginner(db), orderBus, productBus, userBus, emailBus).Routes(apiV1Router)
+	reviewapp.New(log, ath, sqldb.NewBeginner(db), reviewBus, productBus).Routes(apiV1Router)
+	apikeyapp.New(log, ath, sqldb.NewBeginner(db), apiKeyBus, auditBus).Routes(apiV1Router)
+	authapp.New(log, ks).Routes(ginEngine)
+
+	chk, err := checkapp.New(log, cfg.Build, db, ks)
+	if err != nil {
+		return fmt.Errorf("constructing checks: %w", err)
+	}
+	chk.Routes(ginEngine)
 
 	// Construct API server
 	api := http.Server{
@@ -198,6 +609,10 @@ func run(ctx context.Context, log *logger.Logger) error {
 		log.Info(ctx, "shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Info(ctx, "shutdown", "status", "shutdown complete", "signal", sig)
 
+		chk.Drain()
+		log.Info(ctx, "shutdown", "status", "draining", "delay", cfg.Server.DrainDelay)
+		time.Sleep(cfg.Server.DrainDelay)
+
 		ctx, cancel := context.WithTimeout(ctx, cfg.Server.ShutdownTimeout)
 		defer cancel()
 
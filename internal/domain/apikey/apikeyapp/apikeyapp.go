@@ -0,0 +1,247 @@
+// Package apikeyapp maintains the app layer api for the API keys of services.
+package apikeyapp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/query"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+type app struct {
+	log        *logger.Logger
+	auth       *auth.Auth
+	dbBeginner sqldb.Beginner
+	apiKeyBus  *apikeybus.Business
+	auditBus   *auditbus.Business
+}
+
+func New(
+	log *logger.Logger,
+	auth *auth.Auth,
+	dbBeginner sqldb.Beginner,
+	apiKeyBus *apikeybus.Business,
+	auditBus *auditbus.Business,
+) *app {
+	return &app{
+		log:        log,
+		auth:       auth,
+		dbBeginner: dbBeginner,
+		apiKeyBus:  apiKeyBus,
+		auditBus:   auditBus,
+	}
+}
+
+// newWithTx constructs a new app value using a store transaction that was created via middleware.
+func (a *app) newWithTx(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeyBusTx, err := a.apiKeyBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	auditBusTx, err := a.auditBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	app := app{
+		log:        a.log,
+		auth:       a.auth,
+		dbBeginner: a.dbBeginner,
+		apiKeyBus:  apiKeyBusTx,
+		auditBus:   auditBusTx,
+	}
+
+	return &app, nil
+}
+
+// createHandler creates a key for a service. The plaintext of the key is
+// only part of this response. A key can't be given permissions the caller
+// doesn't hold.
+func (a *app) createHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req newAPIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	nk, err := toBusNewAPIKey(req)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	perms, err := auth.ParsePermissions(req.Permissions)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	claims := mid.GetClaims(ctx)
+	for _, p := range perms {
+		holds, err := a.auth.Holds(claims, p)
+		if err != nil {
+			respond.Error(c, a.log, errs.New(errs.Internal, err))
+			return
+		}
+
+		if !holds {
+			respond.Error(c, a.log, errs.Newf(errs.PermissionDenied, "permission %s is not yours to grant", p))
+			return
+		}
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+	nk.CreatedBy = actorID
+
+	key, plain, err := a.apiKeyBus.Create(ctx, nk)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "create: %s", err))
+		return
+	}
+
+	details := map[string]any{"api_key_id": key.ID, "name": key.Name, "permissions": key.Permissions}
+	if err := a.audit(c, actorID, auditbus.ActionAPIKeyCreated, details); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, createdAPIKey{apiKey: toAppAPIKey(key), Key: plain})
+}
+
+func (a *app) queryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	keys, err := a.apiKeyBus.Query(ctx, page)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "query: %s", err))
+		return
+	}
+
+	total, err := a.apiKeyBus.Count(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "count: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, query.NewResult(toAppAPIKeys(keys), total, page))
+}
+
+func (a *app) queryByIDHandler(c *gin.Context) {
+	key, ok := a.queryKey(c)
+	if !ok {
+		return
+	}
+
+	respond.Success(c, a.log, toAppAPIKey(key))
+}
+
+// revokeHandler revokes a key, services using it are rejected right away.
+func (a *app) revokeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	key, ok := a.queryKey(c)
+	if !ok {
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	key, err = a.apiKeyBus.Revoke(ctx, key)
+	if err != nil {
+		if errors.Is(err, apikeybus.ErrRevoked) {
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, apikeybus.ErrRevoked))
+			return
+		}
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "revoke: %s", err))
+		return
+	}
+
+	details := map[string]any{"api_key_id": key.ID, "name": key.Name}
+	if err := a.audit(c, actorID, auditbus.ActionAPIKeyRevoked, details); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, toAppAPIKey(key))
+}
+
+// queryKey returns the key of the route, the error is responded when it
+// can't.
+func (a *app) queryKey(c *gin.Context) (apikeybus.APIKey, bool) {
+	keyID, err := uuid.Parse(c.Param("apikey_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid apikeyID: %s", err))
+		return apikeybus.APIKey{}, false
+	}
+
+	key, err := a.apiKeyBus.QueryByID(c.Request.Context(), keyID)
+	if err != nil {
+		if errors.Is(err, apikeybus.ErrNotFound) {
+			respond.Error(c, a.log, errs.New(errs.NotFound, apikeybus.ErrNotFound))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "query: keyID[%s]: %s", keyID, err))
+		}
+		return apikeybus.APIKey{}, false
+	}
+
+	return key, true
+}
+
+// audit records an action done by the actor on a key.
+func (a *app) audit(c *gin.Context, actorID uuid.UUID, action string, details map[string]any) error {
+	_, err := a.auditBus.Record(c.Request.Context(), auditbus.NewAudit{
+		ActorID:   actorID,
+		Action:    action,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	})
+
+	return err
+}
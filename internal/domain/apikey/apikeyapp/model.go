@@ -0,0 +1,105 @@
+package apikeyapp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+)
+
+// queryParams represents the set of possible query strings.
+type queryParams struct {
+	Page string
+	Rows string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	return queryParams{
+		Page: values.Get("page"),
+		Rows: values.Get("row"),
+	}
+}
+
+// =============================================================================
+
+type apiKey struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Prefix       string   `json:"prefix"`
+	Permissions  []string `json:"permissions"`
+	CreatedBy    string   `json:"created_by"`
+	DateExpires  string   `json:"date_expires,omitempty"`
+	DateLastUsed string   `json:"date_last_used,omitempty"`
+	DateRevoked  string   `json:"date_revoked,omitempty"`
+	DateCreated  string   `json:"date_created"`
+}
+
+func toAppAPIKey(bus apikeybus.APIKey) apiKey {
+	return apiKey{
+		ID:           bus.ID.String(),
+		Name:         bus.Name,
+		Prefix:       bus.Prefix,
+		Permissions:  bus.Permissions,
+		CreatedBy:    bus.CreatedBy.String(),
+		DateExpires:  formatTime(bus.DateExpires),
+		DateLastUsed: formatTime(bus.DateLastUsed),
+		DateRevoked:  formatTime(bus.DateRevoked),
+		DateCreated:  bus.DateCreated.Format(time.RFC3339),
+	}
+}
+
+func toAppAPIKeys(keys []apikeybus.APIKey) []apiKey {
+	app := make([]apiKey, len(keys))
+	for i, key := range keys {
+		app[i] = toAppAPIKey(key)
+	}
+
+	return app
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// createdAPIKey is the only response the plaintext of a key is part of.
+type createdAPIKey struct {
+	apiKey
+	Key string `json:"key"`
+}
+
+// =============================================================================
+
+type newAPIKeyReq struct {
+	Name        string   `json:"name" binding:"required"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+	DateExpires string   `json:"date_expires"`
+}
+
+func toBusNewAPIKey(app newAPIKeyReq) (apikeybus.NewAPIKey, error) {
+	nk := apikeybus.NewAPIKey{
+		Name:        app.Name,
+		Permissions: app.Permissions,
+	}
+
+	if app.DateExpires != "" {
+		t, err := time.Parse(time.RFC3339, app.DateExpires)
+		if err != nil {
+			return apikeybus.NewAPIKey{}, fmt.Errorf("parse date_expires: %w", err)
+		}
+
+		if !t.After(time.Now()) {
+			return apikeybus.NewAPIKey{}, fmt.Errorf("date_expires %s is in the past", app.DateExpires)
+		}
+
+		nk.DateExpires = t
+	}
+
+	return nk, nil
+}
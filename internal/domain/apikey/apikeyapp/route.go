@@ -0,0 +1,19 @@
+package apikeyapp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+)
+
+func (a *app) Routes(r gin.IRouter) {
+	authenticate := mid.Authenticate(a.log, a.auth)
+	requireUser := mid.RequireUser(a.log)
+	apiKeyManage := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.APIKeyManage))
+	transaction := mid.BeginCommitRollback(a.log, a.dbBeginner)
+
+	r.POST("/apikeys", authenticate, requireUser, apiKeyManage, transaction, a.createHandler)
+	r.GET("/apikeys", authenticate, apiKeyManage, a.queryHandler)
+	r.GET("/apikeys/:apikey_id", authenticate, apiKeyManage, a.queryByIDHandler)
+	r.POST("/apikeys/:apikey_id/revoke", authenticate, requireUser, apiKeyManage, transaction, a.revokeHandler)
+}
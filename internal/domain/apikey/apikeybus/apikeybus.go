@@ -0,0 +1,204 @@
+// Package apikeybus provides business access to the API keys of services.
+package apikeybus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound   = errors.New("api key not found")
+	ErrInvalidKey = errors.New("api key is invalid")
+	ErrExpired    = errors.New("api key has expired")
+	ErrRevoked    = errors.New("api key has been revoked")
+)
+
+// keyPrefix starts every key so they are easy to recognize, in logs and by
+// secret scanners.
+const keyPrefix = "ek"
+
+// LastUsedResolution is how often the last use of a key is recorded, a key
+// used all the time doesn't write on every request.
+const LastUsedResolution = time.Minute
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, key APIKey) error
+	Revoke(ctx context.Context, key APIKey) error
+	UpdateLastUsed(ctx context.Context, key APIKey) error
+	Query(ctx context.Context, page page.Page) ([]APIKey, error)
+	Count(ctx context.Context) (int, error)
+	QueryByID(ctx context.Context, keyID uuid.UUID) (APIKey, error)
+	QueryByPrefix(ctx context.Context, prefix string) (APIKey, error)
+}
+
+// Business manages the set of APIs for API key access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+}
+
+// NewBusiness constructs an API key business API for use.
+func NewBusiness(log *logger.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+	}
+
+	return &bus, nil
+}
+
+// Create creates a new key and returns it with its plaintext, which can't be
+// retrieved afterwards.
+func (b *Business) Create(ctx context.Context, nk NewAPIKey) (APIKey, string, error) {
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return APIKey{}, "", fmt.Errorf("generate key: %w", err)
+	}
+
+	plain := fmt.Sprintf("%s_%s_%s", keyPrefix, prefix, secret)
+
+	key := APIKey{
+		ID:          uuid.New(),
+		Name:        nk.Name,
+		Prefix:      prefix,
+		Hash:        hashKey(plain),
+		Permissions: nk.Permissions,
+		CreatedBy:   nk.CreatedBy,
+		DateExpires: nk.DateExpires,
+		DateCreated: time.Now(),
+	}
+
+	if err := b.storer.Create(ctx, key); err != nil {
+		return APIKey{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return key, plain, nil
+}
+
+// Revoke revokes the key, it can't authenticate anymore.
+func (b *Business) Revoke(ctx context.Context, key APIKey) (APIKey, error) {
+	if key.Revoked() {
+		return APIKey{}, fmt.Errorf("keyID[%s]: %w", key.ID, ErrRevoked)
+	}
+
+	key.DateRevoked = time.Now()
+
+	if err := b.storer.Revoke(ctx, key); err != nil {
+		return APIKey{}, fmt.Errorf("revoke: keyID[%s]: %w", key.ID, err)
+	}
+
+	return key, nil
+}
+
+// Authenticate returns the key matching the plaintext if it is still valid,
+// and records its use.
+func (b *Business) Authenticate(ctx context.Context, plain string) (APIKey, error) {
+	parts := strings.Split(plain, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix {
+		return APIKey{}, ErrInvalidKey
+	}
+
+	key, err := b.storer.QueryByPrefix(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return APIKey{}, ErrInvalidKey
+		}
+		return APIKey{}, fmt.Errorf("query: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashKey(plain))) != 1 {
+		return APIKey{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+
+	if key.Revoked() {
+		return APIKey{}, fmt.Errorf("keyID[%s]: %w", key.ID, ErrRevoked)
+	}
+
+	if key.Expired(now) {
+		return APIKey{}, fmt.Errorf("keyID[%s]: %w", key.ID, ErrExpired)
+	}
+
+	if now.Sub(key.DateLastUsed) >= LastUsedResolution {
+		key.DateLastUsed = now
+		if err := b.storer.UpdateLastUsed(ctx, key); err != nil {
+			return APIKey{}, fmt.Errorf("update last used: keyID[%s]: %w", key.ID, err)
+		}
+	}
+
+	return key, nil
+}
+
+func (b *Business) Query(ctx context.Context, page page.Page) ([]APIKey, error) {
+	keys, err := b.storer.Query(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (b *Business) Count(ctx context.Context) (int, error) {
+	return b.storer.Count(ctx)
+}
+
+func (b *Business) QueryByID(ctx context.Context, keyID uuid.UUID) (APIKey, error) {
+	key, err := b.storer.QueryByID(ctx, keyID)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("query: keyID[%s]: %w", keyID, err)
+	}
+
+	return key, nil
+}
+
+// generateKey returns the public prefix the key is looked up by, and the
+// secret part of the key.
+func generateKey() (string, string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	return hex.EncodeToString(prefix), hex.EncodeToString(secret), nil
+}
+
+// hashKey returns the hash of the key that is stored. Keys are random so a
+// fast hash is enough.
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,230 @@
+package apikeybus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+func Test_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	key, plain := createKey(t, bus, time.Time{})
+
+	got, err := bus.Authenticate(ctx, plain)
+	if err != nil {
+		t.Fatalf("Should authenticate the key: %s", err)
+	}
+
+	if got.ID != key.ID {
+		t.Errorf("Should return the key of the prefix: got %s, want %s", got.ID, key.ID)
+	}
+
+	if store.keys[key.Prefix].DateLastUsed.IsZero() {
+		t.Errorf("Should record the use of the key")
+	}
+}
+
+func Test_AuthenticateRejected(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	key, plain := createKey(t, bus, time.Time{})
+	_, other := createKey(t, bus, time.Time{})
+
+	// The secret of the other key under the prefix of the key.
+	forged := strings.Join([]string{keyPrefix, key.Prefix, strings.Split(other, "_")[2]}, "_")
+
+	tests := []struct {
+		name  string
+		plain string
+	}{
+		{name: "malformed", plain: "not-a-key"},
+		{name: "foreign prefix", plain: "xx_" + key.Prefix + "_secret"},
+		{name: "unknown prefix", plain: keyPrefix + "_000000000000_secret"},
+		{name: "wrong secret", plain: forged},
+		{name: "truncated", plain: plain[:len(plain)-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := bus.Authenticate(ctx, tt.plain); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Should reject the key as invalid: got %v", err)
+			}
+		})
+	}
+}
+
+func Test_AuthenticateRevoked(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	key, plain := createKey(t, bus, time.Time{})
+
+	key, err := bus.Revoke(ctx, key)
+	if err != nil {
+		t.Fatalf("Should revoke the key: %s", err)
+	}
+
+	if _, err := bus.Authenticate(ctx, plain); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Should reject a revoked key: got %v", err)
+	}
+
+	if _, err := bus.Revoke(ctx, key); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Should not revoke a key twice: got %v", err)
+	}
+}
+
+func Test_AuthenticateExpired(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	_, plain := createKey(t, bus, time.Now().Add(-time.Second))
+
+	if _, err := bus.Authenticate(ctx, plain); !errors.Is(err, ErrExpired) {
+		t.Errorf("Should reject an expired key: got %v", err)
+	}
+
+	_, plain = createKey(t, bus, time.Now().Add(time.Hour))
+
+	if _, err := bus.Authenticate(ctx, plain); err != nil {
+		t.Errorf("Should authenticate a key before it expires: %s", err)
+	}
+}
+
+func Test_AuthenticateLastUsed(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	key, plain := createKey(t, bus, time.Time{})
+
+	if _, err := bus.Authenticate(ctx, plain); err != nil {
+		t.Fatalf("Should authenticate the key: %s", err)
+	}
+
+	if _, err := bus.Authenticate(ctx, plain); err != nil {
+		t.Fatalf("Should authenticate the key: %s", err)
+	}
+
+	if store.lastUsedUpdates != 1 {
+		t.Errorf("Should record the use of the key once per resolution: got %d updates", store.lastUsedUpdates)
+	}
+
+	stored := store.keys[key.Prefix]
+	stored.DateLastUsed = time.Now().Add(-LastUsedResolution)
+	store.keys[key.Prefix] = stored
+
+	if _, err := bus.Authenticate(ctx, plain); err != nil {
+		t.Fatalf("Should authenticate the key: %s", err)
+	}
+
+	if store.lastUsedUpdates != 2 {
+		t.Errorf("Should record the use of the key once the resolution elapsed: got %d updates", store.lastUsedUpdates)
+	}
+}
+
+// =============================================================================
+
+func newTestBusiness(store *fakeStore) *Business {
+	log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	return NewBusiness(log, store)
+}
+
+func createKey(t *testing.T, bus *Business, expires time.Time) (APIKey, string) {
+	t.Helper()
+
+	key, plain, err := bus.Create(context.Background(), NewAPIKey{
+		Name:        "service",
+		Permissions: []string{"product:write"},
+		CreatedBy:   uuid.New(),
+		DateExpires: expires,
+	})
+	if err != nil {
+		t.Fatalf("Should create the key: %s", err)
+	}
+
+	return key, plain
+}
+
+// fakeStore keeps the keys in memory by prefix with the semantics of the
+// database store.
+type fakeStore struct {
+	keys            map[string]APIKey
+	lastUsedUpdates int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		keys: make(map[string]APIKey),
+	}
+}
+
+func (s *fakeStore) NewWithTx(tx sqldb.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, key APIKey) error {
+	if _, exists := s.keys[key.Prefix]; exists {
+		return errors.New("duplicated prefix")
+	}
+
+	s.keys[key.Prefix] = key
+
+	return nil
+}
+
+func (s *fakeStore) Revoke(ctx context.Context, key APIKey) error {
+	stored := s.keys[key.Prefix]
+	stored.DateRevoked = key.DateRevoked
+	s.keys[key.Prefix] = stored
+
+	return nil
+}
+
+func (s *fakeStore) UpdateLastUsed(ctx context.Context, key APIKey) error {
+	stored := s.keys[key.Prefix]
+	stored.DateLastUsed = key.DateLastUsed
+	s.keys[key.Prefix] = stored
+	s.lastUsedUpdates++
+
+	return nil
+}
+
+func (s *fakeStore) Query(ctx context.Context, page page.Page) ([]APIKey, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStore) Count(ctx context.Context) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (s *fakeStore) QueryByID(ctx context.Context, keyID uuid.UUID) (APIKey, error) {
+	for _, key := range s.keys {
+		if key.ID == keyID {
+			return key, nil
+		}
+	}
+	return APIKey{}, ErrNotFound
+}
+
+func (s *fakeStore) QueryByPrefix(ctx context.Context, prefix string) (APIKey, error) {
+	key, exists := s.keys[prefix]
+	if !exists {
+		return APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
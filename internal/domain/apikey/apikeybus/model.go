@@ -0,0 +1,41 @@
+package apikeybus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey represents a key a service authenticates with. Only the hash of
+// the key is stored, the plaintext is handed to the admin once.
+type APIKey struct {
+	ID           uuid.UUID
+	Name         string
+	Prefix       string
+	Hash         string
+	Permissions  []string
+	CreatedBy    uuid.UUID
+	DateExpires  time.Time
+	DateLastUsed time.Time
+	DateRevoked  time.Time
+	DateCreated  time.Time
+}
+
+// Expired reports whether the key has expired, keys without expiry never
+// do.
+func (k APIKey) Expired(now time.Time) bool {
+	return !k.DateExpires.IsZero() && now.After(k.DateExpires)
+}
+
+// Revoked reports whether the key has been revoked.
+func (k APIKey) Revoked() bool {
+	return !k.DateRevoked.IsZero()
+}
+
+// NewAPIKey contains information needed to create a new key.
+type NewAPIKey struct {
+	Name        string
+	Permissions []string
+	CreatedBy   uuid.UUID
+	DateExpires time.Time
+}
@@ -0,0 +1,191 @@
+// Package apikeydb contains API key related CRUD functionality.
+package apikeydb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for API key database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (apikeybus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Create(ctx context.Context, key apikeybus.APIKey) error {
+	const q = `
+	INSERT INTO api_keys
+		(api_key_id, name, prefix, key_hash, permissions, created_by, date_expires, date_last_used, date_revoked, date_created)
+	VALUES
+		(:api_key_id, :name, :prefix, :key_hash, :permissions, :created_by, :date_expires, :date_last_used, :date_revoked, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBAPIKey(key)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Revoke(ctx context.Context, key apikeybus.APIKey) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		api_key_id = :api_key_id AND date_revoked IS NULL
+	RETURNING
+		api_key_id`
+
+	var row struct {
+		ID uuid.UUID `db:"api_key_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBAPIKey(key), &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", apikeybus.ErrRevoked)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateLastUsed(ctx context.Context, key apikeybus.APIKey) error {
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		"date_last_used" = :date_last_used
+	WHERE
+		api_key_id = :api_key_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBAPIKey(key)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Query(ctx context.Context, page page.Page) ([]apikeybus.APIKey, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		api_key_id, name, prefix, key_hash, permissions, created_by, date_expires, date_last_used, date_revoked, date_created
+	FROM
+		api_keys
+	ORDER BY
+		date_created DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var rows []apiKeyRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusAPIKeys(rows), nil
+}
+
+func (s *Store) Count(ctx context.Context) (int, error) {
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		api_keys`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, map[string]any{}, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+func (s *Store) QueryByID(ctx context.Context, keyID uuid.UUID) (apikeybus.APIKey, error) {
+	data := struct {
+		ID string `db:"api_key_id"`
+	}{
+		ID: keyID.String(),
+	}
+
+	const q = `
+	SELECT
+		api_key_id, name, prefix, key_hash, permissions, created_by, date_expires, date_last_used, date_revoked, date_created
+	FROM
+		api_keys
+	WHERE
+		api_key_id = :api_key_id`
+
+	var row apiKeyRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return apikeybus.APIKey{}, fmt.Errorf("db: %w", apikeybus.ErrNotFound)
+		}
+		return apikeybus.APIKey{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusAPIKey(row), nil
+}
+
+func (s *Store) QueryByPrefix(ctx context.Context, prefix string) (apikeybus.APIKey, error) {
+	data := struct {
+		Prefix string `db:"prefix"`
+	}{
+		Prefix: prefix,
+	}
+
+	const q = `
+	SELECT
+		api_key_id, name, prefix, key_hash, permissions, created_by, date_expires, date_last_used, date_revoked, date_created
+	FROM
+		api_keys
+	WHERE
+		prefix = :prefix`
+
+	var row apiKeyRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return apikeybus.APIKey{}, fmt.Errorf("db: %w", apikeybus.ErrNotFound)
+		}
+		return apikeybus.APIKey{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusAPIKey(row), nil
+}
@@ -0,0 +1,79 @@
+package apikeydb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb/dbarray"
+)
+
+type apiKeyRow struct {
+	ID           uuid.UUID      `db:"api_key_id"`
+	Name         string         `db:"name"`
+	Prefix       string         `db:"prefix"`
+	Hash         string         `db:"key_hash"`
+	Permissions  dbarray.String `db:"permissions"`
+	CreatedBy    uuid.UUID      `db:"created_by"`
+	DateExpires  sql.NullTime   `db:"date_expires"`
+	DateLastUsed sql.NullTime   `db:"date_last_used"`
+	DateRevoked  sql.NullTime   `db:"date_revoked"`
+	DateCreated  time.Time      `db:"date_created"`
+}
+
+func toDBAPIKey(bus apikeybus.APIKey) apiKeyRow {
+	permissions := bus.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	return apiKeyRow{
+		ID:           bus.ID,
+		Name:         bus.Name,
+		Prefix:       bus.Prefix,
+		Hash:         bus.Hash,
+		Permissions:  permissions,
+		CreatedBy:    bus.CreatedBy,
+		DateExpires:  toNullTime(bus.DateExpires),
+		DateLastUsed: toNullTime(bus.DateLastUsed),
+		DateRevoked:  toNullTime(bus.DateRevoked),
+		DateCreated:  bus.DateCreated.UTC(),
+	}
+}
+
+func toBusAPIKey(row apiKeyRow) apikeybus.APIKey {
+	return apikeybus.APIKey{
+		ID:           row.ID,
+		Name:         row.Name,
+		Prefix:       row.Prefix,
+		Hash:         row.Hash,
+		Permissions:  row.Permissions,
+		CreatedBy:    row.CreatedBy,
+		DateExpires:  fromNullTime(row.DateExpires),
+		DateLastUsed: fromNullTime(row.DateLastUsed),
+		DateRevoked:  fromNullTime(row.DateRevoked),
+		DateCreated:  row.DateCreated.UTC(),
+	}
+}
+
+func toBusAPIKeys(rows []apiKeyRow) []apikeybus.APIKey {
+	bus := make([]apikeybus.APIKey, len(rows))
+	for i, row := range rows {
+		bus[i] = toBusAPIKey(row)
+	}
+
+	return bus
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func fromNullTime(nt sql.NullTime) time.Time {
+	if !nt.Valid {
+		return time.Time{}
+	}
+
+	return nt.Time.UTC()
+}
@@ -0,0 +1,67 @@
+// Package auditbus provides business access to the audit log.
+package auditbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, audit Audit) error
+}
+
+// Business manages the set of APIs for audit access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+}
+
+// NewBusiness constructs an audit business API for use.
+func NewBusiness(log *logger.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+	}
+
+	return &bus, nil
+}
+
+// Record stores a new audit record.
+func (b *Business) Record(ctx context.Context, na NewAudit) (Audit, error) {
+	audit := Audit{
+		ID:          uuid.New(),
+		UserID:      na.UserID,
+		ActorID:     na.ActorID,
+		Action:      na.Action,
+		IPAddress:   na.IPAddress,
+		UserAgent:   na.UserAgent,
+		Details:     na.Details,
+		DateCreated: time.Now(),
+	}
+
+	if err := b.storer.Create(ctx, audit); err != nil {
+		return Audit{}, fmt.Errorf("create: action[%s]: %w", na.Action, err)
+	}
+
+	return audit, nil
+}
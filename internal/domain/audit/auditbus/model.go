@@ -0,0 +1,47 @@
+package auditbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of actions that are recorded.
+const (
+	ActionPasswordResetRequested = "user.password_reset_requested"
+	ActionPasswordReset          = "user.password_reset"
+	ActionSessionsRevoked        = "user.sessions_revoked"
+	ActionUserEnabled            = "user.enabled"
+	ActionUserDisabled           = "user.disabled"
+	ActionRolesChanged           = "user.roles_changed"
+	ActionEmailConfirmed         = "user.email_confirmed"
+	ActionUserUnlocked           = "user.unlocked"
+	ActionMFAEnabled             = "user.mfa_enabled"
+	ActionMFADisabled            = "user.mfa_disabled"
+	ActionRecoveryCodesRenewed   = "user.mfa_recovery_codes_renewed"
+	ActionRecoveryCodeUsed       = "user.mfa_recovery_code_used"
+	ActionAPIKeyCreated          = "apikey.created"
+	ActionAPIKeyRevoked          = "apikey.revoked"
+)
+
+// Audit represents a security relevant event that happened to a user.
+type Audit struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ActorID     uuid.UUID
+	Action      string
+	IPAddress   string
+	UserAgent   string
+	Details     map[string]any
+	DateCreated time.Time
+}
+
+// NewAudit contains information needed to record an event.
+type NewAudit struct {
+	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Action    string
+	IPAddress string
+	UserAgent string
+	Details   map[string]any
+}
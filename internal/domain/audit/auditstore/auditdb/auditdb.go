@@ -0,0 +1,61 @@
+// Package auditdb contains audit log related CRUD functionality.
+package auditdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (auditbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Create(ctx context.Context, audit auditbus.Audit) error {
+	row, err := toDBAudit(audit)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO audit_logs
+		(audit_id, user_id, actor_id, action, ip_address, user_agent, details, date_created)
+	VALUES
+		(:audit_id, :user_id, :actor_id, :action, :ip_address, :user_agent, :details, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, row); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
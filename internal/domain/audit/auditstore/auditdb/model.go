@@ -0,0 +1,47 @@
+package auditdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+)
+
+type auditRow struct {
+	ID          uuid.UUID      `db:"audit_id"`
+	UserID      uuid.NullUUID  `db:"user_id"`
+	ActorID     uuid.NullUUID  `db:"actor_id"`
+	Action      string         `db:"action"`
+	IPAddress   sql.NullString `db:"ip_address"`
+	UserAgent   sql.NullString `db:"user_agent"`
+	Details     []byte         `db:"details"`
+	DateCreated time.Time      `db:"date_created"`
+}
+
+func toDBAudit(bus auditbus.Audit) (auditRow, error) {
+	details := bus.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return auditRow{}, fmt.Errorf("marshal details: %w", err)
+	}
+
+	row := auditRow{
+		ID:          bus.ID,
+		UserID:      uuid.NullUUID{UUID: bus.UserID, Valid: bus.UserID != uuid.Nil},
+		ActorID:     uuid.NullUUID{UUID: bus.ActorID, Valid: bus.ActorID != uuid.Nil},
+		Action:      bus.Action,
+		IPAddress:   sql.NullString{String: bus.IPAddress, Valid: bus.IPAddress != ""},
+		UserAgent:   sql.NullString{String: bus.UserAgent, Valid: bus.UserAgent != ""},
+		Details:     data,
+		DateCreated: bus.DateCreated.UTC(),
+	}
+
+	return row, nil
+}
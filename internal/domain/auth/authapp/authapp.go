@@ -0,0 +1,54 @@
+// Package authapp maintains the app layer api for the auth domain.
+package authapp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// KeyLookup declares the methods of the key store the app publishes the
+// verification keys from.
+type KeyLookup interface {
+	KIDs() []string
+	PublicKey(kid string) (string, error)
+}
+
+type app struct {
+	log  *logger.Logger
+	keys KeyLookup
+}
+
+func New(log *logger.Logger, keys KeyLookup) *app {
+	return &app{
+		log:  log,
+		keys: keys,
+	}
+}
+
+// jwksHandler publishes the public keys that verify the tokens we issue,
+// retired keys stay listed until their grace period ends.
+func (a *app) jwksHandler(c *gin.Context) {
+	set := jwkSet{Keys: []jwk{}}
+	for _, kid := range a.keys.KIDs() {
+		pem, err := a.keys.PublicKey(kid)
+		if err != nil {
+			// The key retired between the two calls.
+			continue
+		}
+
+		k, err := toJWK(kid, pem)
+		if err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "kid[%s]: %s", kid, err))
+			return
+		}
+		set.Keys = append(set.Keys, k)
+	}
+
+	// Verifiers may cache the set, but not for longer than it takes a
+	// rotated key to be used.
+	c.Header("Cache-Control", "public, max-age=300")
+
+	respond.Success(c, a.log, set)
+}
@@ -0,0 +1,9 @@
+package authapp
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (a *app) Routes(r gin.IRouter) {
+	r.GET("/.well-known/jwks.json", a.jwksHandler)
+}
@@ -0,0 +1,152 @@
+// Package checkapp maintains the app layer api for the check domain.
+package checkapp
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/migrate"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// KeyLookup declares the methods of the key store the readiness checks the
+// tokens can be signed with.
+type KeyLookup interface {
+	ActiveKID() string
+	PrivateKey(kid string) (string, error)
+}
+
+type app struct {
+	log              *logger.Logger
+	build            string
+	db               *sqlx.DB
+	keys             KeyLookup
+	migrationVersion uint
+	started          time.Time
+	draining         atomic.Bool
+}
+
+func New(log *logger.Logger, build string, db *sqlx.DB, keys KeyLookup) (*app, error) {
+	version, err := migrate.LatestVersion()
+	if err != nil {
+		return nil, fmt.Errorf("latest migration version: %w", err)
+	}
+
+	return &app{
+		log:              log,
+		build:            build,
+		db:               db,
+		keys:             keys,
+		migrationVersion: version,
+		started:          time.Now(),
+	}, nil
+}
+
+// Drain makes the service report it is not ready, it is called when the
+// shutdown starts so the load balancers stop sending requests before the
+// server stops accepting them.
+func (a *app) Drain() {
+	a.draining.Store(true)
+}
+
+// livenessHandler reports the service is up along with what runs, it
+// doesn't depend on anything the service uses so a broken database doesn't
+// get it restarted.
+func (a *app) livenessHandler(c *gin.Context) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	info := liveness{
+		Status:     "up",
+		Build:      a.build,
+		Host:       host,
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Uptime:     time.Since(a.started).Round(time.Second).String(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.time":
+				info.RevisionTime = s.Value
+			}
+		}
+	}
+
+	respond.Success(c, a.log, info)
+}
+
+// readinessHandler reports whether the service can handle requests, every
+// check is run so the response tells all that is wrong.
+func (a *app) readinessHandler(c *gin.Context) {
+	if a.draining.Load() {
+		respond.Success(c, a.log, readiness{Status: statusDraining})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Second)
+	defer cancel()
+
+	checks := map[string]error{
+		"database":   sqldb.StatusCheck(ctx, a.db),
+		"migrations": a.checkMigrations(ctx),
+		"keys":       a.checkKeys(),
+	}
+
+	rdy := readiness{
+		Status: statusReady,
+		Checks: make(map[string]string, len(checks)),
+	}
+	for name, err := range checks {
+		if err != nil {
+			a.log.Warn(ctx, "readiness", "check", name, "err", err)
+			rdy.Status = statusNotReady
+			rdy.Checks[name] = err.Error()
+			continue
+		}
+		rdy.Checks[name] = statusReady
+	}
+
+	respond.Success(c, a.log, rdy)
+}
+
+func (a *app) checkMigrations(ctx context.Context) error {
+	version, dirty, err := migrate.Version(ctx, a.db)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d failed half way", version)
+	}
+
+	// A database migrated further by a newer release during a rolling
+	// deploy is still compatible with this one.
+	if version < a.migrationVersion {
+		return fmt.Errorf("migrated to %d, expected at least %d", version, a.migrationVersion)
+	}
+
+	return nil
+}
+
+func (a *app) checkKeys() error {
+	kid := a.keys.ActiveKID()
+	if _, err := a.keys.PrivateKey(kid); err != nil {
+		return fmt.Errorf("active kid[%s]: %w", kid, err)
+	}
+
+	return nil
+}
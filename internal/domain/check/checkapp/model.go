@@ -0,0 +1,34 @@
+package checkapp
+
+import "net/http"
+
+const (
+	statusReady    = "ok"
+	statusNotReady = "not ready"
+	statusDraining = "draining"
+)
+
+type liveness struct {
+	Status       string `json:"status"`
+	Build        string `json:"build"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	Host         string `json:"host"`
+	GoVersion    string `json:"go_version"`
+	GOMAXPROCS   int    `json:"gomaxprocs"`
+	Uptime       string `json:"uptime"`
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HTTPStatus implements the respond package httpStatus interface.
+func (r readiness) HTTPStatus() int {
+	if r.Status != statusReady {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
@@ -0,0 +1,10 @@
+package checkapp
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func (a *app) Routes(r gin.IRouter) {
+	r.GET("/healthz", a.livenessHandler)
+	r.GET("/readyz", a.readinessHandler)
+}
@@ -0,0 +1,177 @@
+// Package emailbus provides business access to the email outbox. Emails are
+// queued in the same transaction as the business change that triggers them
+// and are delivered later by a worker.
+package emailbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/mailer"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// ErrUnknownTemplate is returned when an email is queued for a template that
+// does not exist.
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, email Email) error
+	Update(ctx context.Context, email Email) error
+	QueryDue(ctx context.Context, now time.Time, limit int) ([]Email, error)
+}
+
+// Config represents the delivery settings of the outbox.
+type Config struct {
+	BaseURL     string
+	MaxAttempts int
+	RetryDelay  time.Duration
+}
+
+// Business manages the set of APIs for email access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+	mailer mailer.Mailer
+	cfg    Config
+}
+
+// NewBusiness constructs a business API for use.
+func NewBusiness(log *logger.Logger, storer Storer, mailer mailer.Mailer, cfg Config) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+		mailer: mailer,
+		cfg:    cfg,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+		mailer: b.mailer,
+		cfg:    b.cfg,
+	}
+
+	return &bus, nil
+}
+
+// Enqueue writes the email to the outbox. It is delivered once the current
+// transaction, if any, is committed.
+func (b *Business) Enqueue(ctx context.Context, newEmail NewEmail) (Email, error) {
+	locale := newEmail.Locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	if _, exists := templates[DefaultLocale+"/"+newEmail.Template]; !exists {
+		return Email{}, fmt.Errorf("template[%s]: %w", newEmail.Template, ErrUnknownTemplate)
+	}
+
+	now := time.Now()
+
+	email := Email{
+		ID:            uuid.New(),
+		To:            newEmail.To,
+		Template:      newEmail.Template,
+		Locale:        locale,
+		Data:          newEmail.Data,
+		Status:        Statuses.Pending,
+		NextAttemptAt: now,
+		DateCreated:   now,
+	}
+
+	if err := b.storer.Create(ctx, email); err != nil {
+		return Email{}, fmt.Errorf("create: %w", err)
+	}
+
+	return email, nil
+}
+
+// Deliver sends up to limit emails that are due. Each email is locked while
+// it is being sent so several workers can drain the outbox concurrently.
+// Failed emails are retried with an exponential backoff until the maximum
+// number of attempts is reached. It returns the number of emails processed.
+func (b *Business) Deliver(ctx context.Context, bgn sqldb.Beginner, limit int) (int, error) {
+	tx, err := bgn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	busTx, err := b.NewWithTx(tx)
+	if err != nil {
+		return 0, fmt.Errorf("new with tx: %w", err)
+	}
+
+	emails, err := busTx.storer.QueryDue(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("query due: %w", err)
+	}
+
+	for _, email := range emails {
+		email = busTx.send(ctx, email)
+
+		if err := busTx.storer.Update(ctx, email); err != nil {
+			return 0, fmt.Errorf("update: emailID[%s]: %w", email.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+
+	return len(emails), nil
+}
+
+// send renders and delivers the email and returns it with its new state.
+func (b *Business) send(ctx context.Context, email Email) Email {
+	email.Attempts++
+
+	err := func() error {
+		subject, body, err := render(email, b.cfg.BaseURL)
+		if err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+
+		msg := mailer.Message{
+			To:      []string{email.To.String()},
+			Subject: subject,
+			HTML:    body,
+		}
+
+		return b.mailer.Send(ctx, msg)
+	}()
+
+	if err == nil {
+		email.Status = Statuses.Sent
+		email.LastError = ""
+		email.DateSent = time.Now()
+		return email
+	}
+
+	b.log.Error(ctx, "email delivery", "emailID", email.ID, "attempts", email.Attempts, "err", err)
+
+	email.LastError = err.Error()
+	if email.Attempts >= b.cfg.MaxAttempts {
+		email.Status = Statuses.Failed
+		return email
+	}
+
+	email.NextAttemptAt = time.Now().Add(b.cfg.RetryDelay << (email.Attempts - 1))
+
+	return email
+}
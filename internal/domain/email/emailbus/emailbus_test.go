@@ -0,0 +1,191 @@
+package emailbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/mailer"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+func Test_DeliverRetry(t *testing.T) {
+	const retryDelay = time.Minute
+
+	store := newFakeStore()
+	mlr := &fakeMailer{failures: 2}
+	bus := newTestBusiness(store, mlr, Config{MaxAttempts: 5, RetryDelay: retryDelay})
+
+	email := enqueue(t, bus)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+
+		n, err := bus.Deliver(context.Background(), fakeBeginner{}, 10)
+		if err != nil {
+			t.Fatalf("Should deliver the outbox: %s", err)
+		}
+		if n != 1 {
+			t.Fatalf("Should process the due email: got %d", n)
+		}
+
+		got := store.emails[email.ID]
+		if !got.Status.Equal(Statuses.Pending) || got.Attempts != attempt || got.LastError == "" {
+			t.Fatalf("Should keep the failed email pending: got %+v", got)
+		}
+
+		wantDelay := retryDelay << (attempt - 1)
+		if delay := got.NextAttemptAt.Sub(before); delay < wantDelay || delay > wantDelay+time.Second {
+			t.Errorf("Should back off %s after attempt %d: got %s", wantDelay, attempt, delay)
+		}
+
+		n, err = bus.Deliver(context.Background(), fakeBeginner{}, 10)
+		if err != nil {
+			t.Fatalf("Should deliver the outbox: %s", err)
+		}
+		if n != 0 {
+			t.Fatalf("Should not retry before the backoff elapsed: got %d", n)
+		}
+
+		got.NextAttemptAt = time.Now()
+		store.emails[email.ID] = got
+	}
+
+	if _, err := bus.Deliver(context.Background(), fakeBeginner{}, 10); err != nil {
+		t.Fatalf("Should deliver the outbox: %s", err)
+	}
+
+	got := store.emails[email.ID]
+	if !got.Status.Equal(Statuses.Sent) || got.Attempts != 3 || got.LastError != "" || got.DateSent.IsZero() {
+		t.Fatalf("Should send the email on the third attempt: got %+v", got)
+	}
+
+	if len(mlr.sent) != 1 || mlr.sent[0].To[0] != email.To.String() {
+		t.Errorf("Should hand the email to the mailer once: got %+v", mlr.sent)
+	}
+}
+
+func Test_DeliverMaxAttempts(t *testing.T) {
+	store := newFakeStore()
+	bus := newTestBusiness(store, &fakeMailer{failures: 10}, Config{MaxAttempts: 2, RetryDelay: time.Minute})
+
+	email := enqueue(t, bus)
+
+	for i := 0; i < 2; i++ {
+		if _, err := bus.Deliver(context.Background(), fakeBeginner{}, 10); err != nil {
+			t.Fatalf("Should deliver the outbox: %s", err)
+		}
+
+		got := store.emails[email.ID]
+		got.NextAttemptAt = time.Now()
+		store.emails[email.ID] = got
+	}
+
+	got := store.emails[email.ID]
+	if !got.Status.Equal(Statuses.Failed) || got.Attempts != 2 {
+		t.Fatalf("Should give up after the maximum number of attempts: got %+v", got)
+	}
+
+	n, err := bus.Deliver(context.Background(), fakeBeginner{}, 10)
+	if err != nil {
+		t.Fatalf("Should deliver the outbox: %s", err)
+	}
+	if n != 0 {
+		t.Errorf("Should not retry a failed email: got %d", n)
+	}
+}
+
+func Test_EnqueueUnknownTemplate(t *testing.T) {
+	bus := newTestBusiness(newFakeStore(), &fakeMailer{}, Config{MaxAttempts: 1})
+
+	_, err := bus.Enqueue(context.Background(), NewEmail{Template: "unknown"})
+	if !errors.Is(err, ErrUnknownTemplate) {
+		t.Fatalf("Should reject an unknown template: got %v", err)
+	}
+}
+
+// =============================================================================
+
+func newTestBusiness(store *fakeStore, mlr mailer.Mailer, cfg Config) *Business {
+	log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	return NewBusiness(log, store, mlr, cfg)
+}
+
+func enqueue(t *testing.T, bus *Business) Email {
+	t.Helper()
+
+	email, err := bus.Enqueue(context.Background(), NewEmail{
+		To:       mail.Address{Name: "Bill", Address: "bill@example.com"},
+		Template: TemplatePasswordChanged,
+		Data:     map[string]any{"name": "Bill"},
+	})
+	if err != nil {
+		t.Fatalf("Should enqueue the email: %s", err)
+	}
+
+	return email
+}
+
+type fakeStore struct {
+	emails map[uuid.UUID]Email
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		emails: make(map[uuid.UUID]Email),
+	}
+}
+
+func (s *fakeStore) NewWithTx(tx sqldb.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, email Email) error {
+	s.emails[email.ID] = email
+	return nil
+}
+
+func (s *fakeStore) Update(ctx context.Context, email Email) error {
+	s.emails[email.ID] = email
+	return nil
+}
+
+func (s *fakeStore) QueryDue(ctx context.Context, now time.Time, limit int) ([]Email, error) {
+	var emails []Email
+	for _, email := range s.emails {
+		if email.Status.Equal(Statuses.Pending) && !email.NextAttemptAt.After(now) && len(emails) < limit {
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+type fakeMailer struct {
+	failures int
+	sent     []mailer.Message
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection refused")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+type fakeBeginner struct{}
+
+func (fakeBeginner) Begin() (sqldb.CommitRollbacker, error) {
+	return fakeTx{}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
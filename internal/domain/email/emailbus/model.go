@@ -0,0 +1,31 @@
+package emailbus
+
+import (
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Email represents an email waiting in the outbox to be delivered.
+type Email struct {
+	ID            uuid.UUID
+	To            mail.Address
+	Template      string
+	Locale        string
+	Data          map[string]any
+	Status        Status
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	DateCreated   time.Time
+	DateSent      time.Time
+}
+
+// NewEmail contains information needed to queue a new email.
+type NewEmail struct {
+	To       mail.Address
+	Template string
+	Locale   string
+	Data     map[string]any
+}
@@ -0,0 +1,64 @@
+package emailbus
+
+import "fmt"
+
+type statusSet struct {
+	Pending Status
+	Sent    Status
+	Failed  Status
+}
+
+// Statuses represents the set of delivery states an email can be in.
+var Statuses = statusSet{
+	Pending: newStatus("PENDING"),
+	Sent:    newStatus("SENT"),
+	Failed:  newStatus("FAILED"),
+}
+
+// =============================================================================
+
+var statuses = make(map[string]Status)
+
+// Status represents the delivery state of an email.
+type Status struct {
+	name string
+}
+
+func newStatus(status string) Status {
+	s := Status{status}
+	statuses[status] = s
+	return s
+}
+
+// String returns the name of the status.
+func (s Status) String() string {
+	return s.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (s Status) Equal(s2 Status) bool {
+	return s.name == s2.name
+}
+
+// =============================================================================
+
+// ParseStatus parses the string value and returns a status if one exists.
+func ParseStatus(value string) (Status, error) {
+	status, exists := statuses[value]
+	if !exists {
+		return Status{}, fmt.Errorf("invalid status %q", value)
+	}
+
+	return status, nil
+}
+
+// MustParseStatus parses the string value and returns a status if one exists.
+// If an error occurs the function panics.
+func MustParseStatus(value string) Status {
+	status, err := ParseStatus(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return status
+}
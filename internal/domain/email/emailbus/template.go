@@ -0,0 +1,92 @@
+package emailbus
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// Set of templates that can be sent.
+const (
+	TemplateConfirmEmail      = "confirm_email"
+	TemplateOrderConfirmation = "order_confirmation"
+	TemplateOrderStatus       = "order_status"
+	TemplatePasswordReset     = "password_reset"
+	TemplatePasswordChanged   = "password_changed"
+)
+
+// DefaultLocale is used when no template exists for the requested locale.
+const DefaultLocale = "en"
+
+// Locales represents the set of locales templates are translated in.
+var Locales = []string{"en", "vi"}
+
+//go:embed templates
+var templateFS embed.FS
+
+var templates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	tmpls := make(map[string]*template.Template)
+
+	for _, locale := range Locales {
+		for _, name := range []string{TemplateConfirmEmail, TemplateOrderConfirmation, TemplateOrderStatus, TemplatePasswordReset, TemplatePasswordChanged} {
+			tmpl := template.Must(template.ParseFS(templateFS, "templates/layout.html", fmt.Sprintf("templates/%s/%s.html", locale, name)))
+			tmpls[locale+"/"+name] = tmpl
+		}
+	}
+
+	return tmpls
+}
+
+// MatchLocale returns the first supported locale from an Accept-Language
+// header value, or the default locale.
+func MatchLocale(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		lang := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+
+		for _, locale := range Locales {
+			if lang == locale {
+				return locale
+			}
+		}
+	}
+
+	return DefaultLocale
+}
+
+// render executes the template of the email in its locale and returns the
+// subject and the html body.
+func render(email Email, baseURL string) (string, string, error) {
+	tmpl, exists := templates[email.Locale+"/"+email.Template]
+	if !exists {
+		tmpl, exists = templates[DefaultLocale+"/"+email.Template]
+		if !exists {
+			return "", "", fmt.Errorf("unknown template %q", email.Template)
+		}
+	}
+
+	data := struct {
+		BaseURL string
+		Data    map[string]any
+	}{
+		BaseURL: baseURL,
+		Data:    email.Data,
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("execute subject: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&body, "layout.html", data); err != nil {
+		return "", "", fmt.Errorf("execute body: %w", err)
+	}
+
+	return html.UnescapeString(strings.TrimSpace(subject.String())), body.String(), nil
+}
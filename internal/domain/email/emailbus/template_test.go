@@ -0,0 +1,83 @@
+package emailbus
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Render(t *testing.T) {
+	for _, locale := range Locales {
+		for _, name := range []string{TemplateConfirmEmail, TemplateOrderConfirmation, TemplateOrderStatus, TemplatePasswordReset, TemplatePasswordChanged} {
+			email := Email{Template: name, Locale: locale, Data: map[string]any{"name": "Bill & Co"}}
+
+			subject, body, err := render(email, "https://shop.test")
+			if err != nil {
+				t.Fatalf("Should render %s/%s: %s", locale, name, err)
+			}
+
+			if subject == "" || strings.Contains(subject, "<") {
+				t.Errorf("Should get a plain text subject for %s/%s: got %q", locale, name, subject)
+			}
+
+			if !strings.Contains(body, "<html") {
+				t.Errorf("Should wrap %s/%s in the layout", locale, name)
+			}
+		}
+	}
+}
+
+func Test_RenderData(t *testing.T) {
+	email := Email{
+		Template: TemplateConfirmEmail,
+		Locale:   "en",
+		Data:     map[string]any{"name": "<b>Bill</b>", "token": "abc"},
+	}
+
+	_, body, err := render(email, "https://shop.test")
+	if err != nil {
+		t.Fatalf("Should render the email: %s", err)
+	}
+
+	if !strings.Contains(body, "https://shop.test/confirm-email?token=abc") {
+		t.Errorf("Should include the confirmation link: got %s", body)
+	}
+
+	if strings.Contains(body, "<b>Bill</b>") || !strings.Contains(body, "&lt;b&gt;Bill&lt;/b&gt;") {
+		t.Errorf("Should escape the data: got %s", body)
+	}
+}
+
+func Test_RenderLocaleFallback(t *testing.T) {
+	en, _, err := render(Email{Template: TemplatePasswordReset, Locale: "en"}, "")
+	if err != nil {
+		t.Fatalf("Should render the email: %s", err)
+	}
+
+	fr, _, err := render(Email{Template: TemplatePasswordReset, Locale: "fr"}, "")
+	if err != nil {
+		t.Fatalf("Should render an unknown locale: %s", err)
+	}
+
+	if fr != en {
+		t.Errorf("Should fall back to the default locale: got %q, want %q", fr, en)
+	}
+
+	if _, _, err := render(Email{Template: "unknown", Locale: "en"}, ""); err == nil {
+		t.Errorf("Should fail to render an unknown template")
+	}
+}
+
+func Test_MatchLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                        DefaultLocale,
+		"vi-VN,vi;q=0.9,en;q=0.8": "vi",
+		"fr-FR, en-US;q=0.5":      "en",
+		"de":                      DefaultLocale,
+	}
+
+	for header, want := range tests {
+		if got := MatchLocale(header); got != want {
+			t.Errorf("Should match %q to %s: got %s", header, want, got)
+		}
+	}
+}
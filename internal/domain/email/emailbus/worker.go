@@ -0,0 +1,51 @@
+package emailbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+// Worker drains the outbox at a regular interval.
+type Worker struct {
+	bus       *Business
+	bgn       sqldb.Beginner
+	interval  time.Duration
+	batchSize int
+}
+
+// NewWorker constructs a worker that delivers up to batchSize emails every
+// interval.
+func NewWorker(bus *Business, bgn sqldb.Beginner, interval time.Duration, batchSize int) *Worker {
+	return &Worker{
+		bus:       bus,
+		bgn:       bgn,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run delivers emails until the context is canceled. A full batch is
+// followed immediately by the next one so a backlog drains quickly.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := w.bus.Deliver(ctx, w.bgn, w.batchSize)
+		if err != nil {
+			w.bus.log.Error(ctx, "email worker", "err", err)
+		}
+
+		if err == nil && n == w.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
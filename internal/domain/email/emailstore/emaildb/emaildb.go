@@ -0,0 +1,117 @@
+// Package emaildb contains email outbox related CRUD functionality.
+package emaildb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (emailbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Create(ctx context.Context, email emailbus.Email) error {
+	row, err := toDBEmail(email)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO email_outbox
+		(email_id, recipient, template, locale, data, status, attempts, last_error, next_attempt_at, date_created, date_sent)
+	VALUES
+		(:email_id, :recipient, :template, :locale, :data, :status, :attempts, :last_error, :next_attempt_at, :date_created, :date_sent)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, row); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Update(ctx context.Context, email emailbus.Email) error {
+	row, err := toDBEmail(email)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		email_outbox
+	SET
+		"status" = :status,
+		"attempts" = :attempts,
+		"last_error" = :last_error,
+		"next_attempt_at" = :next_attempt_at,
+		"date_sent" = :date_sent
+	WHERE
+		email_id = :email_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, row); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDue retrieves pending emails whose next attempt is due, oldest first.
+// The rows stay locked until the transaction ends and rows locked by other
+// transactions are skipped.
+func (s *Store) QueryDue(ctx context.Context, now time.Time, limit int) ([]emailbus.Email, error) {
+	data := map[string]any{
+		"status": emailbus.Statuses.Pending.String(),
+		"now":    now.UTC(),
+		"limit":  limit,
+	}
+
+	const q = `
+	SELECT
+		email_id, recipient, template, locale, data, status, attempts, last_error, next_attempt_at, date_created, date_sent
+	FROM
+		email_outbox
+	WHERE
+		status = :status AND next_attempt_at <= :now
+	ORDER BY
+		next_attempt_at
+	LIMIT :limit
+	FOR UPDATE SKIP LOCKED`
+
+	var rows []emailRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusEmails(rows)
+}
@@ -0,0 +1,103 @@
+package emaildb
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailbus"
+)
+
+type emailRow struct {
+	ID            uuid.UUID      `db:"email_id"`
+	Recipient     string         `db:"recipient"`
+	Template      string         `db:"template"`
+	Locale        string         `db:"locale"`
+	Data          []byte         `db:"data"`
+	Status        string         `db:"status"`
+	Attempts      int            `db:"attempts"`
+	LastError     sql.NullString `db:"last_error"`
+	NextAttemptAt time.Time      `db:"next_attempt_at"`
+	DateCreated   time.Time      `db:"date_created"`
+	DateSent      sql.NullTime   `db:"date_sent"`
+}
+
+func toDBEmail(bus emailbus.Email) (emailRow, error) {
+	data, err := json.Marshal(bus.Data)
+	if err != nil {
+		return emailRow{}, fmt.Errorf("marshal data: %w", err)
+	}
+
+	row := emailRow{
+		ID:            bus.ID,
+		Recipient:     bus.To.String(),
+		Template:      bus.Template,
+		Locale:        bus.Locale,
+		Data:          data,
+		Status:        bus.Status.String(),
+		Attempts:      bus.Attempts,
+		LastError:     sql.NullString{String: bus.LastError, Valid: bus.LastError != ""},
+		NextAttemptAt: bus.NextAttemptAt.UTC(),
+		DateCreated:   bus.DateCreated.UTC(),
+		DateSent:      sql.NullTime{Time: bus.DateSent.UTC(), Valid: !bus.DateSent.IsZero()},
+	}
+
+	return row, nil
+}
+
+func toBusEmail(row emailRow) (emailbus.Email, error) {
+	addr, err := mail.ParseAddress(row.Recipient)
+	if err != nil {
+		return emailbus.Email{}, fmt.Errorf("parse recipient: %w", err)
+	}
+
+	status, err := emailbus.ParseStatus(row.Status)
+	if err != nil {
+		return emailbus.Email{}, fmt.Errorf("parse status: %w", err)
+	}
+
+	// Numbers are kept as json.Number so large amounts render as written.
+	var data map[string]any
+	dec := json.NewDecoder(bytes.NewReader(row.Data))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return emailbus.Email{}, fmt.Errorf("unmarshal data: %w", err)
+	}
+
+	bus := emailbus.Email{
+		ID:            row.ID,
+		To:            *addr,
+		Template:      row.Template,
+		Locale:        row.Locale,
+		Data:          data,
+		Status:        status,
+		Attempts:      row.Attempts,
+		LastError:     row.LastError.String,
+		NextAttemptAt: row.NextAttemptAt.UTC(),
+		DateCreated:   row.DateCreated.UTC(),
+	}
+
+	if row.DateSent.Valid {
+		bus.DateSent = row.DateSent.Time.UTC()
+	}
+
+	return bus, nil
+}
+
+func toBusEmails(rows []emailRow) ([]emailbus.Email, error) {
+	bus := make([]emailbus.Email, len(rows))
+
+	for i, row := range rows {
+		var err error
+		bus[i], err = toBusEmail(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
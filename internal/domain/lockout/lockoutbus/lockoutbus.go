@@ -0,0 +1,188 @@
+// Package lockoutbus provides business access to the failed login attempts.
+// Accounts and client addresses are locked out for an exponentially growing
+// time after too many failed attempts.
+package lockoutbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Set of error variables for lockout operations.
+var (
+	ErrNotFound = errors.New("attempt not found")
+	ErrLocked   = errors.New("too many failed login attempts")
+)
+
+// LockedError is returned when a login is attempted for a locked out account
+// or from a locked out address.
+type LockedError struct {
+	Until time.Time
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, locked until %s", ErrLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+// Is reports whether the target is ErrLocked.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Lock(ctx context.Context, key string) (Attempt, error)
+	Update(ctx context.Context, attempt Attempt) error
+	Delete(ctx context.Context, key string) error
+	QueryByKey(ctx context.Context, key string) (Attempt, error)
+}
+
+// Config represents the thresholds of the lockout. An account is locked
+// after AccountThreshold failures within Window, an address after
+// IPThreshold failures. The first lockout lasts BaseLockout and every
+// following one twice as long, up to MaxLockout.
+type Config struct {
+	AccountThreshold int
+	IPThreshold      int
+	Window           time.Duration
+	BaseLockout      time.Duration
+	MaxLockout       time.Duration
+}
+
+// Business manages the set of APIs for lockout access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+	cfg    Config
+}
+
+// NewBusiness constructs a lockout business API for use.
+func NewBusiness(log *logger.Logger, storer Storer, cfg Config) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+		cfg:    cfg,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+		cfg:    b.cfg,
+	}
+
+	return &bus, nil
+}
+
+// Check returns a LockedError when the account of the email or the address
+// ip is locked out.
+func (b *Business) Check(ctx context.Context, email string, ip string) error {
+	now := time.Now()
+
+	var until time.Time
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		attempt, err := b.storer.QueryByKey(ctx, key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return fmt.Errorf("query: key[%s]: %w", key, err)
+		}
+
+		if attempt.Locked(now) && attempt.LockedUntil.After(until) {
+			until = attempt.LockedUntil
+		}
+	}
+
+	if !until.IsZero() {
+		return &LockedError{Until: until}
+	}
+
+	return nil
+}
+
+// RecordFailure records a failed login for the account of the email made
+// from the address ip. The attempts are locked while they are updated so
+// concurrent logins on several instances are all counted.
+func (b *Business) RecordFailure(ctx context.Context, bgn sqldb.Beginner, email string, ip string) error {
+	tx, err := bgn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	busTx, err := b.NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	now := time.Now()
+
+	// Keys are always locked in the same order to avoid deadlocks.
+	keys := []struct {
+		key       string
+		threshold int
+	}{
+		{key: accountKey(email), threshold: b.cfg.AccountThreshold},
+		{key: ipKey(ip), threshold: b.cfg.IPThreshold},
+	}
+
+	for _, k := range keys {
+		attempt, err := busTx.storer.Lock(ctx, k.key)
+		if err != nil {
+			return fmt.Errorf("lock: key[%s]: %w", k.key, err)
+		}
+
+		lockedUntil := attempt.LockedUntil
+
+		attempt = fail(attempt, now, k.threshold, b.cfg)
+		if attempt.LockedUntil.After(lockedUntil) {
+			b.log.Info(ctx, "login lockout", "key", k.key, "lockouts", attempt.Lockouts, "until", attempt.LockedUntil)
+		}
+
+		if err := busTx.storer.Update(ctx, attempt); err != nil {
+			return fmt.Errorf("update: key[%s]: %w", k.key, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// Reset clears the failed attempts and the lockout of the account of the
+// email. It is called on a successful login and when an admin unlocks the
+// account. The attempts of the address are kept so logging into an account
+// of their own doesn't let a client try more passwords on others.
+func (b *Business) Reset(ctx context.Context, email string) error {
+	if err := b.storer.Delete(ctx, accountKey(email)); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,24 @@
+package lockoutbus
+
+import (
+	"time"
+)
+
+// Attempt represents the failed login attempts made for a key, an account or
+// a client address.
+type Attempt struct {
+	Key string
+	// Failures counts the failed attempts since WindowStart.
+	Failures    int
+	WindowStart time.Time
+	// Lockouts counts the lockouts in a row, every lockout lasts twice as
+	// long as the previous one.
+	Lockouts    int
+	LockedUntil time.Time
+	LastFailure time.Time
+}
+
+// Locked reports whether the key is locked out at the time now.
+func (a Attempt) Locked(now time.Time) bool {
+	return now.Before(a.LockedUntil)
+}
@@ -0,0 +1,51 @@
+package lockoutbus
+
+import (
+	"time"
+)
+
+// fail records a failed attempt made at the time now and returns the new
+// state of the attempt. Once threshold failures are made within the window
+// the key is locked out and the counter starts over.
+func fail(a Attempt, now time.Time, threshold int, cfg Config) Attempt {
+	// A key that has behaved for as long as the longest lockout starts over
+	// with the shortest lockout.
+	if !a.LastFailure.IsZero() && now.Sub(a.LastFailure) >= cfg.MaxLockout {
+		a.Lockouts = 0
+	}
+
+	if a.WindowStart.IsZero() || now.Sub(a.WindowStart) >= cfg.Window {
+		a.Failures = 0
+		a.WindowStart = now
+	}
+
+	a.Failures++
+	a.LastFailure = now
+
+	if a.Failures >= threshold {
+		a.Lockouts++
+		a.LockedUntil = now.Add(lockoutDuration(a.Lockouts, cfg))
+		a.Failures = 0
+		a.WindowStart = time.Time{}
+	}
+
+	return a
+}
+
+// lockoutDuration returns how long the nth lockout in a row lasts. It doubles
+// with every lockout and is capped at the maximum lockout.
+func lockoutDuration(n int, cfg Config) time.Duration {
+	if n < 1 {
+		return 0
+	}
+
+	d := cfg.BaseLockout
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= cfg.MaxLockout {
+			return cfg.MaxLockout
+		}
+	}
+
+	return min(d, cfg.MaxLockout)
+}
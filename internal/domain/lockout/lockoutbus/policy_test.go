@@ -0,0 +1,109 @@
+package lockoutbus
+
+import (
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	AccountThreshold: 3,
+	IPThreshold:      10,
+	Window:           15 * time.Minute,
+	BaseLockout:      time.Minute,
+	MaxLockout:       time.Hour,
+}
+
+func Test_LockoutDuration(t *testing.T) {
+	tests := []struct {
+		lockouts int
+		want     time.Duration
+	}{
+		{lockouts: 0, want: 0},
+		{lockouts: 1, want: time.Minute},
+		{lockouts: 2, want: 2 * time.Minute},
+		{lockouts: 3, want: 4 * time.Minute},
+		{lockouts: 6, want: 32 * time.Minute},
+		{lockouts: 7, want: time.Hour},
+		{lockouts: 1000, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.lockouts, testConfig); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", tt.lockouts, got, tt.want)
+		}
+	}
+}
+
+func Test_Fail(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("locks at threshold", func(t *testing.T) {
+		var a Attempt
+		for i := 0; i < testConfig.AccountThreshold-1; i++ {
+			a = fail(a, start.Add(time.Duration(i)*time.Second), testConfig.AccountThreshold, testConfig)
+			if a.Locked(start.Add(time.Duration(i) * time.Second)) {
+				t.Fatalf("Should not be locked after %d failures", i+1)
+			}
+		}
+
+		now := start.Add(time.Minute)
+		a = fail(a, now, testConfig.AccountThreshold, testConfig)
+		if !a.Locked(now) {
+			t.Fatalf("Should be locked after %d failures", testConfig.AccountThreshold)
+		}
+		if a.Lockouts != 1 || !a.LockedUntil.Equal(now.Add(time.Minute)) {
+			t.Fatalf("Should be locked once for a minute, got %d lockouts until %s", a.Lockouts, a.LockedUntil)
+		}
+		if a.Locked(now.Add(time.Minute)) {
+			t.Fatalf("Should be unlocked once the lockout is over")
+		}
+	})
+
+	t.Run("window expires", func(t *testing.T) {
+		var a Attempt
+		a = fail(a, start, testConfig.AccountThreshold, testConfig)
+		a = fail(a, start.Add(time.Minute), testConfig.AccountThreshold, testConfig)
+
+		now := start.Add(testConfig.Window)
+		a = fail(a, now, testConfig.AccountThreshold, testConfig)
+		if a.Locked(now) {
+			t.Fatalf("Should not count failures of an expired window")
+		}
+		if a.Failures != 1 || !a.WindowStart.Equal(now) {
+			t.Fatalf("Should start a new window, got %d failures since %s", a.Failures, a.WindowStart)
+		}
+	})
+
+	t.Run("lockouts grow exponentially", func(t *testing.T) {
+		var a Attempt
+		now := start
+		for lockout := 1; lockout <= 3; lockout++ {
+			for i := 0; i < testConfig.AccountThreshold; i++ {
+				a = fail(a, now, testConfig.AccountThreshold, testConfig)
+			}
+
+			want := lockoutDuration(lockout, testConfig)
+			if a.Lockouts != lockout || a.LockedUntil.Sub(now) != want {
+				t.Fatalf("Lockout %d should last %s, got %d lockouts for %s", lockout, want, a.Lockouts, a.LockedUntil.Sub(now))
+			}
+
+			now = a.LockedUntil
+		}
+	})
+
+	t.Run("lockouts are forgotten", func(t *testing.T) {
+		a := Attempt{
+			Lockouts:    4,
+			LastFailure: start,
+		}
+
+		now := start.Add(testConfig.MaxLockout)
+		for i := 0; i < testConfig.AccountThreshold; i++ {
+			a = fail(a, now, testConfig.AccountThreshold, testConfig)
+		}
+
+		if a.Lockouts != 1 || !a.LockedUntil.Equal(now.Add(testConfig.BaseLockout)) {
+			t.Fatalf("Should start over with the shortest lockout, got %d lockouts until %s", a.Lockouts, a.LockedUntil)
+		}
+	})
+}
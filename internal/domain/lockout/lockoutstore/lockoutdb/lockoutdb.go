@@ -0,0 +1,146 @@
+// Package lockoutdb contains failed login attempts related CRUD functionality.
+package lockoutdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (lockoutbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+// Lock returns the attempts of the key and locks them until the end of the
+// transaction. The row is created first when the key has no attempts yet.
+func (s *Store) Lock(ctx context.Context, key string) (lockoutbus.Attempt, error) {
+	data := struct {
+		Key string `db:"attempt_key"`
+	}{
+		Key: key,
+	}
+
+	const ins = `
+	INSERT INTO login_attempts
+		(attempt_key)
+	VALUES
+		(:attempt_key)
+	ON CONFLICT (attempt_key) DO NOTHING`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, ins, data); err != nil {
+		return lockoutbus.Attempt{}, fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	const q = `
+	SELECT
+		attempt_key, failures, window_start, lockouts, locked_until, last_failure
+	FROM
+		login_attempts
+	WHERE
+		attempt_key = :attempt_key
+	FOR UPDATE`
+
+	var row attemptRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		return lockoutbus.Attempt{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toBusAttempt(row), nil
+}
+
+func (s *Store) Update(ctx context.Context, attempt lockoutbus.Attempt) error {
+	const q = `
+	UPDATE
+		login_attempts
+	SET
+		"failures" = :failures,
+		"window_start" = :window_start,
+		"lockouts" = :lockouts,
+		"locked_until" = :locked_until,
+		"last_failure" = :last_failure
+	WHERE
+		attempt_key = :attempt_key`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBAttempt(attempt)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	data := struct {
+		Key string `db:"attempt_key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	DELETE FROM
+		login_attempts
+	WHERE
+		attempt_key = :attempt_key`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryByKey(ctx context.Context, key string) (lockoutbus.Attempt, error) {
+	data := struct {
+		Key string `db:"attempt_key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	SELECT
+		attempt_key, failures, window_start, lockouts, locked_until, last_failure
+	FROM
+		login_attempts
+	WHERE
+		attempt_key = :attempt_key`
+
+	var row attemptRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return lockoutbus.Attempt{}, fmt.Errorf("db: %w", lockoutbus.ErrNotFound)
+		}
+		return lockoutbus.Attempt{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusAttempt(row), nil
+}
@@ -0,0 +1,51 @@
+package lockoutdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutbus"
+)
+
+type attemptRow struct {
+	Key         string       `db:"attempt_key"`
+	Failures    int          `db:"failures"`
+	WindowStart sql.NullTime `db:"window_start"`
+	Lockouts    int          `db:"lockouts"`
+	LockedUntil sql.NullTime `db:"locked_until"`
+	LastFailure sql.NullTime `db:"last_failure"`
+}
+
+func toDBAttempt(bus lockoutbus.Attempt) attemptRow {
+	return attemptRow{
+		Key:         bus.Key,
+		Failures:    bus.Failures,
+		WindowStart: toNullTime(bus.WindowStart),
+		Lockouts:    bus.Lockouts,
+		LockedUntil: toNullTime(bus.LockedUntil),
+		LastFailure: toNullTime(bus.LastFailure),
+	}
+}
+
+func toBusAttempt(row attemptRow) lockoutbus.Attempt {
+	return lockoutbus.Attempt{
+		Key:         row.Key,
+		Failures:    row.Failures,
+		WindowStart: toTime(row.WindowStart),
+		Lockouts:    row.Lockouts,
+		LockedUntil: toTime(row.LockedUntil),
+		LastFailure: toTime(row.LastFailure),
+	}
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func toTime(nt sql.NullTime) time.Time {
+	if !nt.Valid {
+		return time.Time{}
+	}
+
+	return nt.Time.UTC()
+}
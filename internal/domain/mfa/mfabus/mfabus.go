@@ -0,0 +1,303 @@
+// Package mfabus provides business access to the second authentication factor
+// of users: time-based one-time passwords and recovery codes.
+package mfabus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/aesgcm"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"github.com/nhannguyenacademy/ecommerce/pkg/totp"
+)
+
+// Set of error variables for MFA operations.
+var (
+	ErrNotFound       = errors.New("mfa is not set up")
+	ErrAlreadyEnabled = errors.New("mfa is already enabled")
+	ErrNotEnabled     = errors.New("mfa is not enabled")
+	ErrInvalidCode    = errors.New("code is invalid")
+)
+
+// RecoveryCodeCount is the number of recovery codes generated at once.
+const RecoveryCodeCount = 10
+
+// skew is the number of time steps accepted before and after the current one.
+const skew = 1
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Save(ctx context.Context, mfa MFA) error
+	Delete(ctx context.Context, userID uuid.UUID) error
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) error
+	QueryByUserID(ctx context.Context, userID uuid.UUID) (MFA, error)
+	CreateRecoveryCodes(ctx context.Context, codes []RecoveryCode) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, now time.Time) error
+}
+
+// Business manages the set of APIs for MFA access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+	cipher *aesgcm.Cipher
+	issuer string
+}
+
+// NewBusiness constructs a MFA business API for use. The cipher encrypts the
+// secrets and the issuer is the name authenticator apps show for the codes.
+func NewBusiness(log *logger.Logger, storer Storer, cipher *aesgcm.Cipher, issuer string) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+		cipher: cipher,
+		issuer: issuer,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+		cipher: b.cipher,
+		issuer: b.issuer,
+	}
+
+	return &bus, nil
+}
+
+// Setup generates a new secret for the user. The second factor is only
+// enabled once the user proves their authenticator holds the secret.
+func (b *Business) Setup(ctx context.Context, userID uuid.UUID, account string) (Setup, error) {
+	mfa, err := b.storer.QueryByUserID(ctx, userID)
+	switch {
+	case err == nil:
+		if mfa.Enabled {
+			return Setup{}, fmt.Errorf("userID[%s]: %w", userID, ErrAlreadyEnabled)
+		}
+	case !errors.Is(err, ErrNotFound):
+		return Setup{}, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return Setup{}, fmt.Errorf("generate secret: %w", err)
+	}
+
+	encrypted, err := b.cipher.Encrypt(secret)
+	if err != nil {
+		return Setup{}, fmt.Errorf("encrypt secret: %w", err)
+	}
+
+	now := time.Now()
+
+	mfa = MFA{
+		UserID:          userID,
+		EncryptedSecret: encrypted,
+		DateCreated:     now,
+		DateUpdated:     now,
+	}
+
+	if err := b.storer.Save(ctx, mfa); err != nil {
+		return Setup{}, fmt.Errorf("save: userID[%s]: %w", userID, err)
+	}
+
+	setup := Setup{
+		Secret: secret,
+		URI:    totp.URI(b.issuer, account, secret),
+	}
+
+	return setup, nil
+}
+
+// Enable turns the second factor on once the code proves the authenticator of
+// the user holds the secret. It returns the recovery codes of the user, they
+// are only shown once.
+func (b *Business) Enable(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	mfa, err := b.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	if mfa.Enabled {
+		return nil, fmt.Errorf("userID[%s]: %w", userID, ErrAlreadyEnabled)
+	}
+
+	step, err := b.verifyTOTP(ctx, mfa, code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	mfa.LastStep = step
+	mfa.Enabled = true
+	mfa.DateEnabled = now
+	mfa.DateUpdated = now
+
+	if err := b.storer.Save(ctx, mfa); err != nil {
+		return nil, fmt.Errorf("save: userID[%s]: %w", userID, err)
+	}
+
+	codes, err := b.RegenerateRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns the second factor off and deletes the secret and the recovery
+// codes of the user.
+func (b *Business) Disable(ctx context.Context, userID uuid.UUID) error {
+	if err := b.storer.Delete(ctx, userID); err != nil {
+		return fmt.Errorf("delete: userID[%s]: %w", userID, err)
+	}
+
+	if err := b.storer.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// Enabled reports whether the user has the second factor turned on.
+func (b *Business) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := b.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	return mfa.Enabled, nil
+}
+
+// Verify checks the code of the user. The code is either a one-time password
+// of their authenticator or one of their recovery codes. Both can only be
+// used once. It reports whether a recovery code was used.
+func (b *Business) Verify(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	mfa, err := b.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, fmt.Errorf("userID[%s]: %w", userID, ErrNotEnabled)
+		}
+		return false, fmt.Errorf("query: userID[%s]: %w", userID, err)
+	}
+
+	if !mfa.Enabled {
+		return false, fmt.Errorf("userID[%s]: %w", userID, ErrNotEnabled)
+	}
+
+	if len(strings.TrimSpace(code)) == totp.Digits {
+		if _, err := b.verifyTOTP(ctx, mfa, code); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	if err := b.storer.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), time.Now()); err != nil {
+		return false, fmt.Errorf("use recovery code: userID[%s]: %w", userID, err)
+	}
+
+	return true, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user and returns
+// the new ones, they are only shown once.
+func (b *Business) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if err := b.storer.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("delete recovery codes: userID[%s]: %w", userID, err)
+	}
+
+	now := time.Now()
+
+	plain := make([]string, RecoveryCodeCount)
+	codes := make([]RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+
+		plain[i] = code
+		codes[i] = RecoveryCode{
+			ID:          uuid.New(),
+			UserID:      userID,
+			Hash:        hashRecoveryCode(code),
+			DateCreated: now,
+		}
+	}
+
+	if err := b.storer.CreateRecoveryCodes(ctx, codes); err != nil {
+		return nil, fmt.Errorf("create recovery codes: userID[%s]: %w", userID, err)
+	}
+
+	return plain, nil
+}
+
+// verifyTOTP checks the one-time password against the secret and marks its
+// time step as used. It returns the time step of the code.
+func (b *Business) verifyTOTP(ctx context.Context, mfa MFA, code string) (int64, error) {
+	secret, err := b.cipher.Decrypt(mfa.EncryptedSecret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypt secret: userID[%s]: %w", mfa.UserID, err)
+	}
+
+	step, ok, err := totp.Validate(secret, code, time.Now(), skew)
+	if err != nil {
+		return 0, fmt.Errorf("validate: userID[%s]: %w", mfa.UserID, err)
+	}
+
+	if !ok || step <= mfa.LastStep {
+		return 0, fmt.Errorf("userID[%s]: %w", mfa.UserID, ErrInvalidCode)
+	}
+
+	// The store only moves the step forward so two concurrent requests can't
+	// both use the same code.
+	if err := b.storer.UseStep(ctx, mfa.UserID, step); err != nil {
+		return 0, fmt.Errorf("use step: userID[%s]: %w", mfa.UserID, err)
+	}
+
+	return step, nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx.
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode hashes the code ignoring case, spaces and dashes.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
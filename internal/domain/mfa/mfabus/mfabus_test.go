@@ -0,0 +1,230 @@
+package mfabus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/aesgcm"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"github.com/nhannguyenacademy/ecommerce/pkg/totp"
+)
+
+func Test_VerifyReplay(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(t, store)
+
+	userID := uuid.New()
+	secret := enable(t, bus, userID)
+
+	// Enabling used the code of the current step, the next code is the one
+	// of the next step which the skew accepts.
+	step := totp.Step(time.Now()) + 1
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatalf("Should generate a code: %s", err)
+	}
+
+	recovery, err := bus.Verify(ctx, userID, code)
+	if err != nil {
+		t.Fatalf("Should verify the code: %s", err)
+	}
+	if recovery {
+		t.Errorf("Should not report a one-time password as a recovery code")
+	}
+
+	if store.mfas[userID].LastStep != step {
+		t.Errorf("Should record the step of the code: got %d, want %d", store.mfas[userID].LastStep, step)
+	}
+
+	if _, err := bus.Verify(ctx, userID, code); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Should reject a code used twice: got %v", err)
+	}
+
+	previous, err := totp.Code(secret, step-1)
+	if err != nil {
+		t.Fatalf("Should generate a code: %s", err)
+	}
+
+	if _, err := bus.Verify(ctx, userID, previous); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Should reject a code older than the last one used: got %v", err)
+	}
+}
+
+func Test_VerifyRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(t, store)
+
+	userID := uuid.New()
+	enable(t, bus, userID)
+
+	codes := store.plainCodes
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("Should generate %d recovery codes: got %d", RecoveryCodeCount, len(codes))
+	}
+
+	recovery, err := bus.Verify(ctx, userID, codes[0])
+	if err != nil {
+		t.Fatalf("Should verify the recovery code: %s", err)
+	}
+	if !recovery {
+		t.Errorf("Should report the use of a recovery code")
+	}
+
+	if _, err := bus.Verify(ctx, userID, codes[0]); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Should reject a recovery code used twice: got %v", err)
+	}
+
+	if _, err := bus.Verify(ctx, userID, " "+strings.ToUpper(codes[1])+" "); err != nil {
+		t.Errorf("Should ignore the case and spaces of a recovery code: %s", err)
+	}
+
+	if _, err := bus.Verify(ctx, userID, "aaaaa-bbbbb"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Should reject an unknown recovery code: got %v", err)
+	}
+}
+
+func Test_VerifyNotEnabled(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(t, store)
+
+	userID := uuid.New()
+
+	if _, err := bus.Verify(ctx, userID, "123456"); !errors.Is(err, ErrNotEnabled) {
+		t.Errorf("Should reject a user without mfa: got %v", err)
+	}
+
+	if _, err := bus.Setup(ctx, userID, "bill@example.com"); err != nil {
+		t.Fatalf("Should set up mfa: %s", err)
+	}
+
+	if _, err := bus.Verify(ctx, userID, "123456"); !errors.Is(err, ErrNotEnabled) {
+		t.Errorf("Should reject a user who didn't finish the setup: got %v", err)
+	}
+}
+
+// =============================================================================
+
+func newTestBusiness(t *testing.T, store *fakeStore) *Business {
+	t.Helper()
+
+	cipher, err := aesgcm.New("AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+	if err != nil {
+		t.Fatalf("Should construct the cipher: %s", err)
+	}
+
+	log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+	return NewBusiness(log, store, cipher, "TEST")
+}
+
+// enable sets up and enables mfa for the user and returns its secret.
+func enable(t *testing.T, bus *Business, userID uuid.UUID) string {
+	t.Helper()
+
+	ctx := context.Background()
+
+	setup, err := bus.Setup(ctx, userID, "bill@example.com")
+	if err != nil {
+		t.Fatalf("Should set up mfa: %s", err)
+	}
+
+	code, err := totp.Code(setup.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatalf("Should generate a code: %s", err)
+	}
+
+	plain, err := bus.Enable(ctx, userID, code)
+	if err != nil {
+		t.Fatalf("Should enable mfa: %s", err)
+	}
+
+	bus.storer.(*fakeStore).plainCodes = plain
+
+	return setup.Secret
+}
+
+// fakeStore keeps the mfa of users in memory with the semantics of the
+// database store.
+type fakeStore struct {
+	mfas       map[uuid.UUID]MFA
+	codes      map[string]RecoveryCode
+	plainCodes []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		mfas:  make(map[uuid.UUID]MFA),
+		codes: make(map[string]RecoveryCode),
+	}
+}
+
+func (s *fakeStore) NewWithTx(tx sqldb.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *fakeStore) Save(ctx context.Context, mfa MFA) error {
+	s.mfas[mfa.UserID] = mfa
+	return nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, userID uuid.UUID) error {
+	delete(s.mfas, userID)
+	return nil
+}
+
+func (s *fakeStore) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	mfa, exists := s.mfas[userID]
+	if !exists || mfa.LastStep >= step {
+		return ErrInvalidCode
+	}
+
+	mfa.LastStep = step
+	s.mfas[userID] = mfa
+
+	return nil
+}
+
+func (s *fakeStore) QueryByUserID(ctx context.Context, userID uuid.UUID) (MFA, error) {
+	mfa, exists := s.mfas[userID]
+	if !exists {
+		return MFA{}, ErrNotFound
+	}
+	return mfa, nil
+}
+
+func (s *fakeStore) CreateRecoveryCodes(ctx context.Context, codes []RecoveryCode) error {
+	for _, code := range codes {
+		s.codes[code.Hash] = code
+	}
+	return nil
+}
+
+func (s *fakeStore) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	for hash, code := range s.codes {
+		if code.UserID == userID {
+			delete(s.codes, hash)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, now time.Time) error {
+	code, exists := s.codes[hash]
+	if !exists || code.UserID != userID || !code.DateUsed.IsZero() {
+		return ErrInvalidCode
+	}
+
+	code.DateUsed = now
+	s.codes[hash] = code
+
+	return nil
+}
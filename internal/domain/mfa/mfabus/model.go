@@ -0,0 +1,37 @@
+package mfabus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFA represents the second factor of a user. The secret is stored
+// encrypted and is only decrypted to check a code.
+type MFA struct {
+	UserID          uuid.UUID
+	EncryptedSecret string
+	Enabled         bool
+	// LastStep is the time step of the last code accepted, a code can't be
+	// used twice.
+	LastStep    int64
+	DateEnabled time.Time
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// RecoveryCode represents a single-use code that replaces a one-time password
+// when the user has lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Hash        string
+	DateUsed    time.Time
+	DateCreated time.Time
+}
+
+// Setup is returned when a user starts enrolling an authenticator.
+type Setup struct {
+	Secret string
+	URI    string
+}
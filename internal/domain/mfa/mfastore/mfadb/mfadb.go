@@ -0,0 +1,213 @@
+// Package mfadb contains MFA related CRUD functionality.
+package mfadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfabus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (mfabus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Save(ctx context.Context, mfa mfabus.MFA) error {
+	const q = `
+	INSERT INTO user_mfa
+		(user_id, secret, enabled, last_step, date_enabled, date_created, date_updated)
+	VALUES
+		(:user_id, :secret, :enabled, :last_step, :date_enabled, :date_created, :date_updated)
+	ON CONFLICT (user_id) DO UPDATE SET
+		"secret" = EXCLUDED.secret,
+		"enabled" = EXCLUDED.enabled,
+		"last_step" = EXCLUDED.last_step,
+		"date_enabled" = EXCLUDED.date_enabled,
+		"date_updated" = EXCLUDED.date_updated`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBMFA(mfa)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_mfa
+	WHERE
+		user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	data := struct {
+		UserID string `db:"user_id"`
+		Step   int64  `db:"last_step"`
+	}{
+		UserID: userID.String(),
+		Step:   step,
+	}
+
+	const q = `
+	UPDATE
+		user_mfa
+	SET
+		"last_step" = :last_step
+	WHERE
+		user_id = :user_id AND last_step < :last_step
+	RETURNING
+		user_id`
+
+	var row struct {
+		UserID uuid.UUID `db:"user_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", mfabus.ErrInvalidCode)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) (mfabus.MFA, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	SELECT
+		user_id, secret, enabled, last_step, date_enabled, date_created, date_updated
+	FROM
+		user_mfa
+	WHERE
+		user_id = :user_id`
+
+	var row mfaRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return mfabus.MFA{}, fmt.Errorf("db: %w", mfabus.ErrNotFound)
+		}
+		return mfabus.MFA{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusMFA(row), nil
+}
+
+func (s *Store) CreateRecoveryCodes(ctx context.Context, codes []mfabus.RecoveryCode) error {
+	const q = `
+	INSERT INTO mfa_recovery_codes
+		(code_id, user_id, code_hash, date_used, date_created)
+	VALUES
+		(:code_id, :user_id, :code_hash, :date_used, :date_created)`
+
+	for _, code := range codes {
+		if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBRecoveryCode(code)); err != nil {
+			return fmt.Errorf("namedexeccontext: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		mfa_recovery_codes
+	WHERE
+		user_id = :user_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string, now time.Time) error {
+	data := struct {
+		UserID   string    `db:"user_id"`
+		Hash     string    `db:"code_hash"`
+		DateUsed time.Time `db:"date_used"`
+	}{
+		UserID:   userID.String(),
+		Hash:     hash,
+		DateUsed: now.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		mfa_recovery_codes
+	SET
+		"date_used" = :date_used
+	WHERE
+		user_id = :user_id AND code_hash = :code_hash AND date_used IS NULL
+	RETURNING
+		code_id`
+
+	var row struct {
+		ID uuid.UUID `db:"code_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", mfabus.ErrInvalidCode)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package mfadb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfabus"
+)
+
+type mfaRow struct {
+	UserID      uuid.UUID    `db:"user_id"`
+	Secret      string       `db:"secret"`
+	Enabled     bool         `db:"enabled"`
+	LastStep    int64        `db:"last_step"`
+	DateEnabled sql.NullTime `db:"date_enabled"`
+	DateCreated time.Time    `db:"date_created"`
+	DateUpdated time.Time    `db:"date_updated"`
+}
+
+func toDBMFA(bus mfabus.MFA) mfaRow {
+	return mfaRow{
+		UserID:      bus.UserID,
+		Secret:      bus.EncryptedSecret,
+		Enabled:     bus.Enabled,
+		LastStep:    bus.LastStep,
+		DateEnabled: sql.NullTime{Time: bus.DateEnabled.UTC(), Valid: !bus.DateEnabled.IsZero()},
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusMFA(row mfaRow) mfabus.MFA {
+	bus := mfabus.MFA{
+		UserID:          row.UserID,
+		EncryptedSecret: row.Secret,
+		Enabled:         row.Enabled,
+		LastStep:        row.LastStep,
+		DateCreated:     row.DateCreated.UTC(),
+		DateUpdated:     row.DateUpdated.UTC(),
+	}
+
+	if row.DateEnabled.Valid {
+		bus.DateEnabled = row.DateEnabled.Time.UTC()
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type recoveryCodeRow struct {
+	ID          uuid.UUID    `db:"code_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Hash        string       `db:"code_hash"`
+	DateUsed    sql.NullTime `db:"date_used"`
+	DateCreated time.Time    `db:"date_created"`
+}
+
+func toDBRecoveryCode(bus mfabus.RecoveryCode) recoveryCodeRow {
+	return recoveryCodeRow{
+		ID:          bus.ID,
+		UserID:      bus.UserID,
+		Hash:        bus.Hash,
+		DateUsed:    sql.NullTime{Time: bus.DateUsed.UTC(), Valid: !bus.DateUsed.IsZero()},
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
@@ -4,8 +4,10 @@ package orderapp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
@@ -27,6 +29,7 @@ type app struct {
 	orderBus   *orderbus.Business
 	productBus *productbus.Business
 	userBus    *userbus.Business
+	emailBus   *emailbus.Business
 }
 
 func New(
@@ -36,6 +39,7 @@ func New(
 	orderBus *orderbus.Business,
 	productBus *productbus.Business,
 	userBus *userbus.Business,
+	emailBus *emailbus.Business,
 ) *app {
 	return &app{
 		log:        log,
@@ -44,6 +48,7 @@ func New(
 		orderBus:   orderBus,
 		productBus: productBus,
 		userBus:    userBus,
+		emailBus:   emailBus,
 	}
 }
 
@@ -64,12 +69,19 @@ func (a *app) newWithTx(ctx context.Context) (*app, error) {
 		return nil, err
 	}
 
+	emailBusTx, err := a.emailBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
 	app := app{
 		log:        a.log,
 		auth:       a.auth,
+		dbBeginner: a.dbBeginner,
 		orderBus:   orderBusTx,
 		productBus: productBusTx,
 		userBus:    a.userBus,
+		emailBus:   emailBusTx,
 	}
 
 	return &app, nil
@@ -116,22 +128,13 @@ func (a *app) createHandler(c *gin.Context) {
 	// for mapping product data to order items
 	productsMap := make(map[uuid.UUID]productbus.Product)
 
-	// validate and update product quantity
+	// validate product quantity
 	for _, product := range products {
 		if product.Quantity < itemQuantityMap[product.ID] {
 			respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "insufficient quantity: %s", product.ID))
 			return
 		}
 		productsMap[product.ID] = product
-
-		newQuantity := product.Quantity - itemQuantityMap[product.ID]
-		_, err = a.productBus.Update(ctx, product, productbus.UpdateProduct{
-			Quantity: &newQuantity,
-		})
-		if err != nil {
-			respond.Error(c, a.log, errs.Newf(errs.Internal, "update product: id[%s]: %s", product.ID, err))
-			return
-		}
 	}
 
 	// create new order
@@ -147,6 +150,28 @@ func (a *app) createHandler(c *gin.Context) {
 		return
 	}
 
+	// take the ordered quantity out of stock
+	for _, product := range products {
+		_, err := a.productBus.AdjustStock(ctx, product, -itemQuantityMap[product.ID], productbus.StockChange{
+			Kind:        productbus.MovementKinds.Sale,
+			ActorID:     order.UserID,
+			ReferenceID: order.ID,
+		})
+		if err != nil {
+			if errors.Is(err, productbus.ErrInsufficientStock) {
+				respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "insufficient quantity: %s", product.ID))
+			} else {
+				respond.Error(c, a.log, errs.Newf(errs.Internal, "adjust stock: id[%s]: %s", product.ID, err))
+			}
+			return
+		}
+	}
+
+	if err := a.sendOrderConfirmation(ctx, order, newOrder.Items, emailbus.MatchLocale(c.GetHeader("Accept-Language"))); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "send order confirmation: orderID[%s]: %s", order.ID, err))
+		return
+	}
+
 	respond.Success(c, a.log, toAppOrder(order))
 }
 
@@ -273,6 +298,12 @@ func (a *app) queryByIDHandler(c *gin.Context) {
 func (a *app) updateStatusHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	orderID, err := uuid.Parse(c.Param("order_id"))
 	if err != nil {
 		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid orderID: %s", err))
@@ -303,13 +334,32 @@ func (a *app) updateStatusHandler(c *gin.Context) {
 
 	updatedOrder, err := a.orderBus.UpdateStatus(ctx, ord, status)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "update order status: orderID[%s]: %s", orderID, err))
+		switch {
+		case errors.Is(err, orderbus.ErrConflict):
+			respond.Error(c, a.log, errs.New(errs.Aborted, orderbus.ErrConflict))
+		case errors.Is(err, orderbus.ErrOrderAlreadyFinished),
+			errors.Is(err, orderbus.ErrOrderAlreadyCancelled),
+			errors.Is(err, orderbus.ErrOrderAlreadyDelivered):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "update order status: orderID[%s]: %s", orderID, err))
+		}
 		return
 	}
 
-	//if updatedOrder.Status.Equal(orderbus.Statuses.Cancelled) {
-	// todo: revert product quantity (here or move to bus?)
-	//}
+	if !ord.Status.Equal(orderbus.Statuses.Cancelled) && updatedOrder.Status.Equal(orderbus.Statuses.Cancelled) {
+		if err := a.restock(ctx, updatedOrder); err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "restock: orderID[%s]: %s", orderID, err))
+			return
+		}
+	}
+
+	if !ord.Status.Equal(updatedOrder.Status) {
+		if err := a.sendStatusChange(ctx, updatedOrder, emailbus.DefaultLocale); err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "send status change: orderID[%s]: %s", orderID, err))
+			return
+		}
+	}
 
 	respond.Success(c, a.log, toAppOrder(updatedOrder))
 }
@@ -317,6 +367,12 @@ func (a *app) updateStatusHandler(c *gin.Context) {
 func (a *app) cancelHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	orderID, err := uuid.Parse(c.Param("order_id"))
 	if err != nil {
 		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid orderID: %s", err))
@@ -329,19 +385,40 @@ func (a *app) cancelHandler(c *gin.Context) {
 		return
 	}
 
+	// Cancelling a cancelled order changes nothing, it was restocked when it
+	// was cancelled.
+	if ord.Status.Equal(orderbus.Statuses.Cancelled) {
+		respond.Success(c, a.log, toAppOrder(ord))
+		return
+	}
+
+	// The order was read before the transaction began, the update fails with
+	// a conflict when a concurrent request changed its status since, so the
+	// order is restocked once.
 	updatedOrder, err := a.orderBus.UpdateStatus(ctx, ord, orderbus.Statuses.Cancelled)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "cancel order: orderID[%s]: %s", orderID, err))
+		switch {
+		case errors.Is(err, orderbus.ErrConflict):
+			respond.Error(c, a.log, errs.New(errs.Aborted, orderbus.ErrConflict))
+		case errors.Is(err, orderbus.ErrOrderAlreadyFinished),
+			errors.Is(err, orderbus.ErrOrderAlreadyCancelled),
+			errors.Is(err, orderbus.ErrOrderAlreadyDelivered):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "cancel order: orderID[%s]: %s", orderID, err))
+		}
 		return
 	}
 
-	// todo: revert product quantity (here or move to bus?)
-	items, err := a.orderBus.QueryOrderItems(ctx, updatedOrder)
-	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "query order items: orderID[%s]: %s", orderID, err))
+	if err := a.restock(ctx, updatedOrder); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "restock: orderID[%s]: %s", orderID, err))
+		return
+	}
+
+	if err := a.sendStatusChange(ctx, updatedOrder, emailbus.MatchLocale(c.GetHeader("Accept-Language"))); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "send status change: orderID[%s]: %s", orderID, err))
 		return
 	}
-	_ = items
 
 	respond.Success(c, a.log, toAppOrder(updatedOrder))
 }
@@ -372,12 +449,107 @@ func (a *app) deleteHandler(c *gin.Context) {
 		return
 	}
 
+	// only a created order still holds reserved stock, the items of a delivered
+	// or finished order have left the warehouse and a cancelled one was restocked
+	if ord.Status.Equal(orderbus.Statuses.Created) {
+		if err := a.restock(ctx, ord); err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "restock: orderID[%s]: %s", orderID, err))
+			return
+		}
+	}
+
 	if err := a.orderBus.Delete(ctx, ord); err != nil {
 		respond.Error(c, a.log, errs.Newf(errs.Internal, "delete: orderID[%s]: %s", orderID, err))
 		return
 	}
 
-	// todo: revert product quantity (here or move to bus?)
-
 	respond.Success(c, a.log, nil)
 }
+
+// restock puts the quantity of every item of the order back in stock and
+// records the movements against the order.
+func (a *app) restock(ctx context.Context, order orderbus.Order) error {
+	items, err := a.orderBus.QueryOrderItems(ctx, order)
+	if err != nil {
+		return fmt.Errorf("query order items: %w", err)
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		product, err := a.productBus.QueryByID(ctx, item.ProductID)
+		if err != nil {
+			if errors.Is(err, productbus.ErrNotFound) {
+				continue
+			}
+			return fmt.Errorf("query product: id[%s]: %w", item.ProductID, err)
+		}
+
+		_, err = a.productBus.AdjustStock(ctx, product, item.Quantity, productbus.StockChange{
+			Kind:        productbus.MovementKinds.Cancellation,
+			ActorID:     actorID,
+			ReferenceID: order.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("adjust stock: id[%s]: %w", item.ProductID, err)
+		}
+	}
+
+	return nil
+}
+
+// sendOrderConfirmation queues the confirmation email of a new order.
+func (a *app) sendOrderConfirmation(ctx context.Context, order orderbus.Order, items []orderbus.NewOrderItem, locale string) error {
+	usr, err := a.userBus.QueryByID(ctx, order.UserID)
+	if err != nil {
+		return fmt.Errorf("query user: id[%s]: %w", order.UserID, err)
+	}
+
+	lines := make([]map[string]any, len(items))
+	for i, item := range items {
+		lines[i] = map[string]any{
+			"name":     item.ProductName,
+			"quantity": item.Quantity,
+			"price":    item.Price,
+		}
+	}
+
+	_, err = a.emailBus.Enqueue(ctx, emailbus.NewEmail{
+		To:       usr.Email,
+		Template: emailbus.TemplateOrderConfirmation,
+		Locale:   locale,
+		Data: map[string]any{
+			"name":     usr.Name.String(),
+			"order_id": order.ID.String(),
+			"items":    lines,
+			"amount":   order.Amount,
+		},
+	})
+
+	return err
+}
+
+// sendStatusChange queues the email telling the owner of the order about its
+// new status.
+func (a *app) sendStatusChange(ctx context.Context, order orderbus.Order, locale string) error {
+	usr, err := a.userBus.QueryByID(ctx, order.UserID)
+	if err != nil {
+		return fmt.Errorf("query user: id[%s]: %w", order.UserID, err)
+	}
+
+	_, err = a.emailBus.Enqueue(ctx, emailbus.NewEmail{
+		To:       usr.Email,
+		Template: emailbus.TemplateOrderStatus,
+		Locale:   locale,
+		Data: map[string]any{
+			"name":     usr.Name.String(),
+			"order_id": order.ID.String(),
+			"status":   order.Status.String(),
+		},
+	})
+
+	return err
+}
@@ -8,16 +8,19 @@ import (
 
 func (a *app) Routes(r gin.IRouter) {
 	authenticate := mid.Authenticate(a.log, a.auth)
-	roleAdmin := mid.Authorize(a.log, a.auth, auth.Rules.Admin)
-	orderOwner := mid.AuthorizeOrder(a.log, a.auth, a.orderBus, auth.Rules.Owner)
-	adminOrOrderOwner := mid.AuthorizeOrder(a.log, a.auth, a.orderBus, auth.Rules.AdminOrOwner)
+	requireUser := mid.RequireUser(a.log)
+	orderRead := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.OrderRead))
+	orderUpdateStatus := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.OrderUpdateStatus))
+	orderDelete := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.OrderDelete))
+	orderOwner := mid.AuthorizeOrder(a.log, a.auth, a.orderBus, auth.Owner())
+	ownerOrOrderRead := mid.AuthorizeOrder(a.log, a.auth, a.orderBus, auth.OwnerOr(auth.Permissions.OrderRead))
 	transaction := mid.BeginCommitRollback(a.log, a.dbBeginner)
 
-	r.POST("/orders", authenticate, transaction, a.createHandler)
-	r.PUT("/orders/:order_id/cancel", authenticate, orderOwner, a.cancelHandler)
-	r.GET("/orders/:order_id", authenticate, adminOrOrderOwner, a.queryByIDHandler)
-	r.GET("/:user_id/orders", authenticate, a.queryUserOrdersHandler)
-	r.GET("/orders", authenticate, roleAdmin, a.queryHandler)
-	r.PUT("/orders/:order_id", authenticate, roleAdmin, a.updateStatusHandler)
-	r.DELETE("/orders/:order_id", authenticate, roleAdmin, transaction, a.deleteHandler)
+	r.POST("/orders", authenticate, requireUser, transaction, a.createHandler)
+	r.PUT("/orders/:order_id/cancel", authenticate, orderOwner, transaction, a.cancelHandler)
+	r.GET("/orders/:order_id", authenticate, ownerOrOrderRead, a.queryByIDHandler)
+	r.GET("/:user_id/orders", authenticate, requireUser, a.queryUserOrdersHandler)
+	r.GET("/orders", authenticate, orderRead, a.queryHandler)
+	r.PUT("/orders/:order_id", authenticate, orderUpdateStatus, transaction, a.updateStatusHandler)
+	r.DELETE("/orders/:order_id", authenticate, orderDelete, transaction, a.deleteHandler)
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
@@ -17,6 +18,8 @@ var (
 	ErrNotFound              = errors.New("order not found")
 	ErrOrderAlreadyFinished  = errors.New("order already finished")
 	ErrOrderAlreadyCancelled = errors.New("order already cancelled")
+	ErrOrderAlreadyDelivered = errors.New("order already delivered")
+	ErrConflict              = errors.New("order was modified concurrently")
 )
 
 type Storer interface {
@@ -117,10 +120,24 @@ func (b *Business) UpdateStatus(ctx context.Context, order Order, status Status)
 		return order, fmt.Errorf("order %s: %w", order.ID, ErrOrderAlreadyCancelled)
 	}
 
+	// The items of a delivered order have left the warehouse, it can only be
+	// finished.
+	if order.Status.Equal(Statuses.Delivered) && !status.Equal(Statuses.Finished) {
+		return order, fmt.Errorf("order %s: %w", order.ID, ErrOrderAlreadyDelivered)
+	}
+
 	if err := b.storer.UpdateStatus(ctx, order, status); err != nil {
 		return Order{}, fmt.Errorf("update status: %w", err)
 	}
 
+	order.Status = status
+
+	if status.Equal(Statuses.Cancelled) {
+		sqldb.AfterCommit(ctx, func(context.Context) {
+			metrics.OrderCancelled()
+		})
+	}
+
 	return order, nil
 }
 
@@ -175,6 +192,11 @@ func (b *Business) Create(ctx context.Context, newOrder NewOrder) (Order, error)
 		return Order{}, fmt.Errorf("create order items: %w", err)
 	}
 
+	// An order rolled back with the rest of the request was never created.
+	sqldb.AfterCommit(ctx, func(context.Context) {
+		metrics.OrderCreated()
+	})
+
 	return order, nil
 }
 
@@ -0,0 +1,164 @@
+package orderbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+func Test_UpdateStatus(t *testing.T) {
+	created := Statuses.Created
+	delivered := Statuses.Delivered
+	finished := Statuses.Finished
+	cancelled := Statuses.Cancelled
+
+	tests := []struct {
+		from Status
+		to   Status
+		want error
+	}{
+		{from: created, to: created},
+		{from: created, to: delivered},
+		{from: created, to: finished},
+		{from: created, to: cancelled},
+		{from: delivered, to: delivered},
+		{from: delivered, to: finished},
+		{from: delivered, to: created, want: ErrOrderAlreadyDelivered},
+		{from: delivered, to: cancelled, want: ErrOrderAlreadyDelivered},
+		{from: finished, to: finished},
+		{from: finished, to: created, want: ErrOrderAlreadyFinished},
+		{from: finished, to: delivered, want: ErrOrderAlreadyFinished},
+		{from: finished, to: cancelled, want: ErrOrderAlreadyFinished},
+		{from: cancelled, to: cancelled},
+		{from: cancelled, to: created, want: ErrOrderAlreadyCancelled},
+		{from: cancelled, to: delivered, want: ErrOrderAlreadyCancelled},
+		{from: cancelled, to: finished, want: ErrOrderAlreadyCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from.String()+" to "+tt.to.String(), func(t *testing.T) {
+			store := newFakeStore()
+			bus := newTestBusiness(store)
+
+			order := Order{ID: uuid.New(), Status: tt.from}
+			store.orders[order.ID] = order
+
+			got, err := bus.UpdateStatus(context.Background(), order, tt.to)
+
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("Should reject the change with %v: got %v", tt.want, err)
+				}
+
+				if !store.orders[order.ID].Status.Equal(tt.from) {
+					t.Errorf("Should keep the status: got %s", store.orders[order.ID].Status)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Should change the status: %s", err)
+			}
+
+			if !got.Status.Equal(tt.to) || !store.orders[order.ID].Status.Equal(tt.to) {
+				t.Errorf("Should store the status %s: got %s", tt.to, store.orders[order.ID].Status)
+			}
+		})
+	}
+}
+
+func Test_UpdateStatusConflict(t *testing.T) {
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	order := Order{ID: uuid.New(), Status: Statuses.Created}
+	store.orders[order.ID] = order
+
+	if _, err := bus.UpdateStatus(context.Background(), order, Statuses.Cancelled); err != nil {
+		t.Fatalf("Should cancel the order: %s", err)
+	}
+
+	if _, err := bus.UpdateStatus(context.Background(), order, Statuses.Cancelled); !errors.Is(err, ErrConflict) {
+		t.Errorf("Should reject a change from a stale status: got %v", err)
+	}
+}
+
+// =============================================================================
+
+func newTestBusiness(store *fakeStore) *Business {
+	log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	return NewBusiness(log, store)
+}
+
+// fakeStore keeps the orders in memory with the semantics of the database
+// store.
+type fakeStore struct {
+	orders map[uuid.UUID]Order
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		orders: make(map[uuid.UUID]Order),
+	}
+}
+
+func (s *fakeStore) NewWithTx(tx sqldb.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, order Order) error {
+	s.orders[order.ID] = order
+	return nil
+}
+
+func (s *fakeStore) UpdateStatus(ctx context.Context, order Order, status Status) error {
+	stored, exists := s.orders[order.ID]
+	if !exists || !stored.Status.Equal(order.Status) {
+		return ErrConflict
+	}
+
+	stored.Status = status
+	s.orders[order.ID] = stored
+
+	return nil
+}
+
+func (s *fakeStore) Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]Order, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStore) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (s *fakeStore) QueryByID(ctx context.Context, orderID uuid.UUID) (Order, error) {
+	order, exists := s.orders[orderID]
+	if !exists {
+		return Order{}, ErrNotFound
+	}
+	return order, nil
+}
+
+func (s *fakeStore) Delete(ctx context.Context, order Order) error {
+	delete(s.orders, order.ID)
+	return nil
+}
+
+func (s *fakeStore) QueryOrderItems(ctx context.Context, order Order) ([]OrderItem, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) DeleteOrderItems(ctx context.Context, order Order) error {
+	return nil
+}
+
+func (s *fakeStore) CreateOrderItems(ctx context.Context, items []OrderItem) error {
+	return nil
+}
@@ -4,12 +4,14 @@ import "fmt"
 
 type statusSet struct {
 	Created   Status
+	Delivered Status
 	Finished  Status
 	Cancelled Status
 }
 
 var Statuses = statusSet{
 	Created:   newStatus("CREATED"),
+	Delivered: newStatus("DELIVERED"),
 	Finished:  newStatus("FINISHED"),
 	Cancelled: newStatus("CANCELLED"),
 }
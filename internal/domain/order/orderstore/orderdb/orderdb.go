@@ -172,10 +172,20 @@ func (s *Store) UpdateStatus(ctx context.Context, order orderbus.Order, status o
 	const q = `
 	UPDATE orders
 	SET status = :new_status
-	WHERE order_id = :order_id AND status = :status`
+	WHERE order_id = :order_id AND status = :status
+	RETURNING
+		order_id`
 
-	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
-		return fmt.Errorf("namedexeccontext: %w", err)
+	// No row means another request changed the status since the order was
+	// read.
+	var row struct {
+		OrderID uuid.UUID `db:"order_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", orderbus.ErrConflict)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
 	}
 
 	return nil
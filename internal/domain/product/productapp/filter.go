@@ -50,5 +50,13 @@ func parseFilter(qp queryParams) (productbus.QueryFilter, error) {
 		filter.EndPrice = &endPrice
 	}
 
+	if qp.MinRating != "" {
+		minRating, err := strconv.ParseFloat(qp.MinRating, 64)
+		if err != nil {
+			return productbus.QueryFilter{}, fmt.Errorf("parse min_rating: %w", err)
+		}
+		filter.MinRating = &minRating
+	}
+
 	return filter, nil
 }
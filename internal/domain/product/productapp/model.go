@@ -2,6 +2,7 @@ package productapp
 
 import (
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
 	"net/http"
 	"net/url"
@@ -21,6 +22,7 @@ type queryParams struct {
 	EndCreatedDate   string
 	StartPrice       string
 	EndPrice         string
+	MinRating        string
 }
 
 func parseQueryParams(r *http.Request) queryParams {
@@ -35,6 +37,7 @@ func parseQueryParams(r *http.Request) queryParams {
 		EndCreatedDate:   values.Get("end_created_date"),
 		StartPrice:       values.Get("start_price"),
 		EndPrice:         values.Get("end_price"),
+		MinRating:        values.Get("min_rating"),
 	}
 
 	return filter
@@ -44,26 +47,36 @@ func parseQueryParams(r *http.Request) queryParams {
 
 // product represents information about an individual product.
 type product struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	Price       int64  `json:"price"`
-	Quantity    int32  `json:"quantity"`
-	DateCreated string `json:"date_created"`
-	DateUpdated string `json:"date_updated"`
+	ID               string  `json:"id"`
+	SKU              string  `json:"sku"`
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	ImageURL         string  `json:"image_url"`
+	Price            int64   `json:"price"`
+	Quantity         int32   `json:"quantity"`
+	ReorderThreshold int32   `json:"reorder_threshold"`
+	RatingAverage    float64 `json:"rating_average"`
+	RatingCount      int32   `json:"rating_count"`
+	Version          int     `json:"version"`
+	DateCreated      string  `json:"date_created"`
+	DateUpdated      string  `json:"date_updated"`
 }
 
 func toAppProduct(bus productbus.Product) product {
 	return product{
-		ID:          bus.ID.String(),
-		Name:        bus.Name.String(),
-		Description: bus.Description,
-		ImageURL:    bus.ImageURL.String(),
-		Price:       bus.Price,
-		Quantity:    bus.Quantity,
-		DateCreated: bus.DateCreated.Format(time.RFC3339),
-		DateUpdated: bus.DateUpdated.Format(time.RFC3339),
+		ID:               bus.ID.String(),
+		SKU:              bus.SKU.String(),
+		Name:             bus.Name.String(),
+		Description:      bus.Description,
+		ImageURL:         bus.ImageURL.String(),
+		Price:            bus.Price,
+		Quantity:         bus.Quantity,
+		ReorderThreshold: bus.ReorderThreshold,
+		RatingAverage:    bus.Rating.Average,
+		RatingCount:      bus.Rating.Count,
+		Version:          bus.Version,
+		DateCreated:      bus.DateCreated.Format(time.RFC3339),
+		DateUpdated:      bus.DateUpdated.Format(time.RFC3339),
 	}
 }
 
@@ -79,11 +92,13 @@ func toAppProducts(products []productbus.Product) []product {
 // =============================================================================
 
 type newProductReq struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url" binding:"omitempty,url"`
-	Price       int64  `json:"price" binding:"required,gte=1"`
-	Quantity    int32  `json:"quantity" binding:"required,gte=1"`
+	SKU              string `json:"sku"`
+	Name             string `json:"name" binding:"required"`
+	Description      string `json:"description"`
+	ImageURL         string `json:"image_url" binding:"omitempty,url"`
+	Price            int64  `json:"price" binding:"required,gte=1"`
+	Quantity         int32  `json:"quantity" binding:"required,gte=1"`
+	ReorderThreshold int32  `json:"reorder_threshold" binding:"gte=0"`
 }
 
 func toBusNewProduct(app newProductReq) (productbus.NewProduct, error) {
@@ -97,12 +112,22 @@ func toBusNewProduct(app newProductReq) (productbus.NewProduct, error) {
 		return productbus.NewProduct{}, fmt.Errorf("parse: %w", err)
 	}
 
+	var sku productbus.SKU
+	if app.SKU != "" {
+		sku, err = productbus.ParseSKU(app.SKU)
+		if err != nil {
+			return productbus.NewProduct{}, fmt.Errorf("parse: %w", err)
+		}
+	}
+
 	bus := productbus.NewProduct{
-		Name:        name,
-		Description: app.Description,
-		ImageURL:    *imageURL,
-		Price:       app.Price,
-		Quantity:    app.Quantity,
+		SKU:              sku,
+		Name:             name,
+		Description:      app.Description,
+		ImageURL:         *imageURL,
+		Price:            app.Price,
+		Quantity:         app.Quantity,
+		ReorderThreshold: app.ReorderThreshold,
 	}
 
 	return bus, nil
@@ -111,14 +136,25 @@ func toBusNewProduct(app newProductReq) (productbus.NewProduct, error) {
 // =============================================================================
 
 type updateProductReq struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	ImageURL    *string `json:"image_url" binding:"omitempty,url"`
-	Price       *int64  `json:"price" binding:"omitempty,gte=1"`
-	Quantity    *int32  `json:"quantity" binding:"omitempty,gte=1"`
+	SKU              *string `json:"sku"`
+	Name             *string `json:"name"`
+	Description      *string `json:"description"`
+	ImageURL         *string `json:"image_url" binding:"omitempty,url"`
+	Price            *int64  `json:"price" binding:"omitempty,gte=1"`
+	Quantity         *int32  `json:"quantity" binding:"omitempty,gte=1"`
+	ReorderThreshold *int32  `json:"reorder_threshold" binding:"omitempty,gte=0"`
 }
 
 func toBusUpdateProduct(app updateProductReq) (productbus.UpdateProduct, error) {
+	var sku *productbus.SKU
+	if app.SKU != nil {
+		s, err := productbus.ParseSKU(*app.SKU)
+		if err != nil {
+			return productbus.UpdateProduct{}, fmt.Errorf("parse: %w", err)
+		}
+		sku = &s
+	}
+
 	var name *productbus.Name
 	if app.Name != nil {
 		nm, err := productbus.ParseName(*app.Name)
@@ -138,12 +174,161 @@ func toBusUpdateProduct(app updateProductReq) (productbus.UpdateProduct, error)
 	}
 
 	bus := productbus.UpdateProduct{
-		Name:        name,
-		Description: app.Description,
-		ImageURL:    imageURL,
-		Price:       app.Price,
-		Quantity:    app.Quantity,
+		SKU:              sku,
+		Name:             name,
+		Description:      app.Description,
+		ImageURL:         imageURL,
+		Price:            app.Price,
+		Quantity:         app.Quantity,
+		ReorderThreshold: app.ReorderThreshold,
 	}
 
 	return bus, nil
 }
+
+// =============================================================================
+
+// importResult represents the outcome of importing a single row.
+type importResult struct {
+	Line      int    `json:"line"`
+	ProductID string `json:"product_id,omitempty"`
+	Action    string `json:"action"`
+	Error     string `json:"error,omitempty"`
+}
+
+// importReport represents the outcome of a bulk import.
+type importReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Failed  int            `json:"failed"`
+	Results []importResult `json:"results"`
+}
+
+func toAppImportReport(bus productbus.ImportReport) importReport {
+	results := make([]importResult, len(bus.Results))
+	for i, res := range bus.Results {
+		results[i] = importResult{
+			Line:   res.Line,
+			Action: res.Action,
+		}
+		if res.ProductID != uuid.Nil {
+			results[i].ProductID = res.ProductID.String()
+		}
+		if res.Err != nil {
+			results[i].Error = res.Err.Error()
+		}
+	}
+
+	return importReport{
+		DryRun:  bus.DryRun,
+		Created: bus.Created,
+		Updated: bus.Updated,
+		Failed:  bus.Failed,
+		Results: results,
+	}
+}
+
+// =============================================================================
+
+// movement represents a single change of a product quantity.
+type movement struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	Kind        string `json:"kind"`
+	Delta       int32  `json:"delta"`
+	Quantity    int32  `json:"quantity"`
+	ActorID     string `json:"actor_id,omitempty"`
+	ReferenceID string `json:"reference_id,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	DateCreated string `json:"date_created"`
+}
+
+func toAppMovement(bus productbus.Movement) movement {
+	app := movement{
+		ID:          bus.ID.String(),
+		ProductID:   bus.ProductID.String(),
+		Kind:        bus.Kind.String(),
+		Delta:       bus.Delta,
+		Quantity:    bus.Quantity,
+		Reason:      bus.Reason,
+		DateCreated: bus.DateCreated.Format(time.RFC3339),
+	}
+
+	if bus.ActorID != uuid.Nil {
+		app.ActorID = bus.ActorID.String()
+	}
+
+	if bus.ReferenceID != uuid.Nil {
+		app.ReferenceID = bus.ReferenceID.String()
+	}
+
+	return app
+}
+
+func toAppMovements(movements []productbus.Movement) []movement {
+	app := make([]movement, len(movements))
+	for i, mvt := range movements {
+		app[i] = toAppMovement(mvt)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+// stockAdjustmentReq defines the data needed to manually adjust stock. Only
+// adjustments and returns can be recorded manually.
+type stockAdjustmentReq struct {
+	Delta       int32  `json:"delta" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+	Kind        string `json:"kind" binding:"omitempty,oneof=ADJUSTMENT RETURN"`
+	ReferenceID string `json:"reference_id" binding:"omitempty,uuid"`
+}
+
+func toBusStockAdjustment(app stockAdjustmentReq) (int32, productbus.StockChange, error) {
+	kind := productbus.MovementKinds.Adjustment
+	if app.Kind != "" {
+		k, err := productbus.ParseMovementKind(app.Kind)
+		if err != nil {
+			return 0, productbus.StockChange{}, fmt.Errorf("parse: %w", err)
+		}
+		kind = k
+	}
+
+	var referenceID uuid.UUID
+	if app.ReferenceID != "" {
+		id, err := uuid.Parse(app.ReferenceID)
+		if err != nil {
+			return 0, productbus.StockChange{}, fmt.Errorf("parse: %w", err)
+		}
+		referenceID = id
+	}
+
+	change := productbus.StockChange{
+		Kind:        kind,
+		ReferenceID: referenceID,
+		Reason:      app.Reason,
+	}
+
+	return app.Delta, change, nil
+}
+
+// =============================================================================
+
+// subscription represents a user waiting for a product to be back in stock.
+type subscription struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	UserID      string `json:"user_id"`
+	DateCreated string `json:"date_created"`
+}
+
+func toAppSubscription(bus productbus.Subscription) subscription {
+	return subscription{
+		ID:          bus.ID.String(),
+		ProductID:   bus.ProductID.String(),
+		UserID:      bus.UserID.String(),
+		DateCreated: bus.DateCreated.Format(time.RFC3339),
+	}
+}
@@ -2,40 +2,82 @@
 package productapp
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productio"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/etag"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/query"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"net/http"
+	"strconv"
 )
 
+// maxImportSize limits the size of an import request body.
+const maxImportSize = 32 << 20
+
 type app struct {
 	log        *logger.Logger
 	auth       *auth.Auth
+	dbBeginner sqldb.Beginner
 	productBus *productbus.Business
 }
 
 func New(
 	log *logger.Logger,
 	auth *auth.Auth,
+	dbBeginner sqldb.Beginner,
 	productBus *productbus.Business,
 ) *app {
 	return &app{
 		log:        log,
 		auth:       auth,
+		dbBeginner: dbBeginner,
 		productBus: productBus,
 	}
 }
 
+// newWithTx constructs a new app value using a store transaction that was created via middleware.
+func (a *app) newWithTx(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	productBusTx, err := a.productBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	app := app{
+		log:        a.log,
+		auth:       a.auth,
+		dbBeginner: a.dbBeginner,
+		productBus: productBusTx,
+	}
+
+	return &app, nil
+}
+
 func (a *app) createHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	var req newProductReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		respond.Error(c, a.log, err)
@@ -48,9 +90,24 @@ func (a *app) createHandler(c *gin.Context) {
 		return
 	}
 
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+	newProduct.StockChange = productbus.StockChange{
+		Kind:    productbus.MovementKinds.Adjustment,
+		ActorID: actorID,
+		Reason:  "initial stock",
+	}
+
 	prod, err := a.productBus.Create(ctx, newProduct)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "create: req[%+v]: %s", req, err))
+		if errors.Is(err, productbus.ErrUniqueSKU) {
+			respond.Error(c, a.log, errs.New(errs.Aborted, productbus.ErrUniqueSKU))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "create: req[%+v]: %s", req, err))
+		}
 		return
 	}
 
@@ -60,6 +117,12 @@ func (a *app) createHandler(c *gin.Context) {
 func (a *app) updateHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	var req updateProductReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		respond.Error(c, a.log, err)
@@ -72,6 +135,17 @@ func (a *app) updateHandler(c *gin.Context) {
 		return
 	}
 
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+	updateProduct.StockChange = productbus.StockChange{
+		Kind:    productbus.MovementKinds.Adjustment,
+		ActorID: actorID,
+		Reason:  "product update",
+	}
+
 	productID, err := uuid.Parse(c.Param("product_id"))
 	if err != nil {
 		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
@@ -88,12 +162,25 @@ func (a *app) updateHandler(c *gin.Context) {
 		return
 	}
 
+	if !etag.Match(c.GetHeader("If-Match"), prd.Version) {
+		respond.Error(c, a.log, errs.Newf(errs.PreconditionFailed, "update: productID[%s]: version %d does not match If-Match", productID, prd.Version))
+		return
+	}
+
 	updatedProduct, err := a.productBus.Update(ctx, prd, updateProduct)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "update: productID[%s] req[%+v]: %s", productID, req, err))
+		switch {
+		case errors.Is(err, productbus.ErrUniqueSKU):
+			respond.Error(c, a.log, errs.New(errs.Aborted, productbus.ErrUniqueSKU))
+		case errors.Is(err, productbus.ErrConflict):
+			respond.Error(c, a.log, errs.New(errs.Aborted, productbus.ErrConflict))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "update: productID[%s] req[%+v]: %s", productID, req, err))
+		}
 		return
 	}
 
+	c.Header("ETag", etag.Format(updatedProduct.Version))
 	respond.Success(c, a.log, toAppProduct(updatedProduct))
 }
 
@@ -172,9 +259,270 @@ func (a *app) queryByIDHandler(c *gin.Context) {
 
 	prd, err := a.productBus.QueryByID(ctx, productID)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "querybyid: %s", err))
+		if errors.Is(err, productbus.ErrNotFound) {
+			respond.Error(c, a.log, errs.Newf(errs.NotFound, "querybyid: %s", err))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "querybyid: %s", err))
+		}
 		return
 	}
 
+	c.Header("ETag", etag.Format(prd.Version))
 	respond.Success(c, a.log, toAppProduct(prd))
 }
+
+func (a *app) importHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	format, err := productio.ParseFormat(importFormat(c))
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	var dryRun bool
+	if v := c.Query("dry_run"); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "parse dry_run: %s", err))
+			return
+		}
+	}
+
+	rows, err := productio.Decode(format, http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "decode: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	report, err := a.productBus.Import(ctx, a.dbBeginner, rows, productbus.ImportOptions{DryRun: dryRun, ActorID: actorID})
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "import: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, toAppImportReport(report))
+}
+
+func (a *app) exportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	format, err := productio.ParseFormat(c.DefaultQuery("format", productio.FormatCSV))
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	// The encoder buffers what it writes, so it is created before the status
+	// line is sent and its failure can still be reported.
+	enc, err := productio.NewEncoder(format, c.Writer)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "new encoder: %s", err))
+		return
+	}
+
+	c.Header("Content-Type", productio.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=products.%s", format))
+	c.Status(http.StatusOK)
+
+	// The status line has already been sent once the first page is flushed,
+	// so a failure after that point can only be logged.
+	if _, err := productio.Export(ctx, a.productBus, filter, enc, c.Writer.Flush); err != nil {
+		a.log.Error(ctx, "export products", "err", err)
+		_ = c.Error(err)
+	}
+}
+
+func (a *app) stockAdjustmentHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req stockAdjustmentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	delta, change, err := toBusStockAdjustment(req)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	change.ActorID, err = mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	prd, err := a.productBus.QueryByID(ctx, productID)
+	if err != nil {
+		if errors.Is(err, productbus.ErrNotFound) {
+			respond.Error(c, a.log, errs.Newf(errs.NotFound, "stock adjustment: productID[%s]: %s", productID, err))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "stock adjustment: productID[%s]: %s", productID, err))
+		}
+		return
+	}
+
+	updatedProduct, err := a.productBus.AdjustStock(ctx, prd, delta, change)
+	if err != nil {
+		if errors.Is(err, productbus.ErrInsufficientStock) {
+			respond.Error(c, a.log, errs.Newf(errs.FailedPrecondition, "stock adjustment: productID[%s]: %s", productID, err))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "stock adjustment: productID[%s] req[%+v]: %s", productID, req, err))
+		}
+		return
+	}
+
+	respond.Success(c, a.log, toAppProduct(updatedProduct))
+}
+
+func (a *app) queryMovementsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	filter := productbus.MovementFilter{ProductID: &productID}
+	if kind := c.Query("kind"); kind != "" {
+		k, err := productbus.ParseMovementKind(kind)
+		if err != nil {
+			respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+			return
+		}
+		filter.Kind = &k
+	}
+
+	movements, err := a.productBus.QueryMovements(ctx, filter, page)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "query movements: %s", err))
+		return
+	}
+
+	total, err := a.productBus.CountMovements(ctx, filter)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "count movements: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, query.NewResult(toAppMovements(movements), total, page))
+}
+
+func (a *app) subscribeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	prd, err := a.productBus.QueryByID(ctx, productID)
+	if err != nil {
+		if errors.Is(err, productbus.ErrNotFound) {
+			respond.Error(c, a.log, errs.Newf(errs.NotFound, "subscribe: productID[%s]: %s", productID, err))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "subscribe: productID[%s]: %s", productID, err))
+		}
+		return
+	}
+
+	sub, err := a.productBus.Subscribe(ctx, prd, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, productbus.ErrInStock):
+			respond.Error(c, a.log, errs.Newf(errs.FailedPrecondition, "subscribe: productID[%s]: %s", productID, err))
+		case errors.Is(err, productbus.ErrSubscribed):
+			respond.Error(c, a.log, errs.Newf(errs.Aborted, "subscribe: productID[%s]: %s", productID, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "subscribe: productID[%s]: %s", productID, err))
+		}
+		return
+	}
+
+	respond.Success(c, a.log, toAppSubscription(sub))
+}
+
+func (a *app) unsubscribeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	prd, err := a.productBus.QueryByID(ctx, productID)
+	if err != nil {
+		if errors.Is(err, productbus.ErrNotFound) {
+			respond.Error(c, a.log, errs.Newf(errs.NotFound, "unsubscribe: productID[%s]: %s", productID, err))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "unsubscribe: productID[%s]: %s", productID, err))
+		}
+		return
+	}
+
+	if err := a.productBus.Unsubscribe(ctx, prd, userID); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "unsubscribe: productID[%s]: %s", productID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+// importFormat returns the format from the query string, falling back to the
+// request content type.
+func importFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	return c.ContentType()
+}
@@ -8,11 +8,22 @@ import (
 
 func (a *app) Routes(r gin.IRouter) {
 	authenticate := mid.Authenticate(a.log, a.auth)
-	roleAdmin := mid.Authorize(a.log, a.auth, auth.Rules.Admin)
+	requireUser := mid.RequireUser(a.log)
+	productWrite := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.ProductWrite))
+	productExport := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.ProductExport))
+	stockRead := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.StockRead))
+	stockWrite := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.StockWrite))
+	transaction := mid.BeginCommitRollback(a.log, a.dbBeginner)
 
 	r.GET("/products", a.queryHandler)
+	r.GET("/products/export", authenticate, productExport, a.exportHandler)
+	r.POST("/products/import", authenticate, productWrite, a.importHandler)
 	r.GET("/products/:product_id", a.queryByIDHandler)
-	r.POST("/products", authenticate, roleAdmin, a.createHandler)
-	r.PUT("/products/:product_id", authenticate, roleAdmin, a.updateHandler)
-	r.DELETE("/products/:product_id", authenticate, roleAdmin, a.deleteHandler)
+	r.POST("/products", authenticate, productWrite, transaction, a.createHandler)
+	r.PUT("/products/:product_id", authenticate, productWrite, transaction, a.updateHandler)
+	r.DELETE("/products/:product_id", authenticate, productWrite, a.deleteHandler)
+	r.POST("/products/:product_id/stock-adjustments", authenticate, stockWrite, transaction, a.stockAdjustmentHandler)
+	r.GET("/products/:product_id/stock-movements", authenticate, stockRead, a.queryMovementsHandler)
+	r.POST("/products/:product_id/subscriptions", authenticate, requireUser, a.subscribeHandler)
+	r.DELETE("/products/:product_id/subscriptions", authenticate, requireUser, a.unsubscribeHandler)
 }
@@ -12,4 +12,5 @@ var sortByFields = map[string]string{
 	"date_created": productbus.SortByDateCreated,
 	"price":        productbus.SortByPrice,
 	"quantity":     productbus.SortByQuantity,
+	"rating":       productbus.SortByRating,
 }
@@ -2,6 +2,8 @@ package productbus
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // QueryFilter holds the available fields a query can be filtered on.
@@ -11,4 +13,11 @@ type QueryFilter struct {
 	EndCreatedDate   *time.Time
 	StartPrice       *int64
 	EndPrice         *int64
+	MinRating        *float64
+}
+
+// MovementFilter holds the available fields a movement query can be filtered on.
+type MovementFilter struct {
+	ProductID *uuid.UUID
+	Kind      *MovementKind
 }
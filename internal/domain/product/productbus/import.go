@@ -0,0 +1,248 @@
+package productbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+// DefaultImportChunkSize is the number of rows written per transaction when
+// no chunk size is provided.
+const DefaultImportChunkSize = 100
+
+// Set of actions reported for an imported row.
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionFailed = "failed"
+)
+
+// ErrImportMissingField is returned when a row that creates a new product
+// does not provide all the required fields.
+var ErrImportMissingField = errors.New("missing required field")
+
+// ImportRow represents a single row of a bulk import. Nil fields are left
+// unchanged when the row updates an existing product. If Err is set, the row
+// failed to parse and is reported without being written.
+type ImportRow struct {
+	Line             int
+	ID               *uuid.UUID
+	SKU              *SKU
+	Name             *Name
+	Description      *string
+	ImageURL         *url.URL
+	Price            *int64
+	Quantity         *int32
+	ReorderThreshold *int32
+	Err              error
+}
+
+// ImportOptions controls how a bulk import is executed.
+type ImportOptions struct {
+	DryRun    bool
+	ChunkSize int
+	ActorID   uuid.UUID
+}
+
+// ImportResult represents the outcome of importing a single row.
+type ImportResult struct {
+	Line      int
+	ProductID uuid.UUID
+	Action    string
+	Err       error
+}
+
+// ImportReport represents the outcome of a bulk import.
+type ImportReport struct {
+	DryRun  bool
+	Created int
+	Updated int
+	Failed  int
+	Results []ImportResult
+}
+
+// Import upserts the rows by ID or SKU. Rows are written in chunks where each
+// chunk runs inside its own transaction. If any row in a chunk fails to be
+// written, the whole chunk is rolled back and every row of the chunk is
+// reported as failed. In dry run mode every chunk is rolled back.
+func (b *Business) Import(ctx context.Context, bgn sqldb.Beginner, rows []ImportRow, opts ImportOptions) (ImportReport, error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultImportChunkSize
+	}
+
+	report := ImportReport{
+		DryRun:  opts.DryRun,
+		Results: make([]ImportResult, 0, len(rows)),
+	}
+
+	var valid []ImportRow
+	for _, row := range rows {
+		if row.Err != nil {
+			report.Results = append(report.Results, ImportResult{Line: row.Line, Action: ImportActionFailed, Err: row.Err})
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	for start := 0; start < len(valid); start += chunkSize {
+		end := min(start+chunkSize, len(valid))
+
+		results, err := b.importChunk(ctx, bgn, valid[start:end], opts)
+		if err != nil {
+			return ImportReport{}, fmt.Errorf("import chunk: lines[%d-%d]: %w", valid[start].Line, valid[end-1].Line, err)
+		}
+
+		report.Results = append(report.Results, results...)
+	}
+
+	for _, res := range report.Results {
+		switch res.Action {
+		case ImportActionCreate:
+			report.Created++
+		case ImportActionUpdate:
+			report.Updated++
+		default:
+			report.Failed++
+		}
+	}
+
+	return report, nil
+}
+
+// importChunk writes the rows inside a single transaction. A returned error
+// means the transaction itself failed, row errors are reported in the results.
+func (b *Business) importChunk(ctx context.Context, bgn sqldb.Beginner, rows []ImportRow, opts ImportOptions) ([]ImportResult, error) {
+	ctx = sqldb.WithCommitHooks(ctx)
+
+	tx, err := bgn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	busTx, err := b.NewWithTx(tx)
+	if err != nil {
+		return nil, fmt.Errorf("new with tx: %w", err)
+	}
+
+	results := make([]ImportResult, len(rows))
+	for i, row := range rows {
+		prd, action, err := busTx.importRow(ctx, row, opts.ActorID)
+		if err != nil {
+			for j := range rows {
+				results[j] = ImportResult{Line: rows[j].Line, Action: ImportActionFailed, Err: fmt.Errorf("chunk rolled back: line %d failed", row.Line)}
+			}
+			results[i].Err = err
+			return results, nil
+		}
+
+		results[i] = ImportResult{Line: row.Line, ProductID: prd.ID, Action: action}
+	}
+
+	if opts.DryRun {
+		return results, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	sqldb.RunCommitHooks(ctx)
+
+	return results, nil
+}
+
+// importRow creates or updates the product a row refers to. A row is matched
+// by ID first and then by SKU.
+func (b *Business) importRow(ctx context.Context, row ImportRow, actorID uuid.UUID) (Product, string, error) {
+	change := StockChange{
+		Kind:    MovementKinds.Import,
+		ActorID: actorID,
+		Reason:  fmt.Sprintf("import line %d", row.Line),
+	}
+
+	prd, err := b.importLookup(ctx, row)
+	switch {
+	case err == nil:
+		updated, err := b.Update(ctx, prd, UpdateProduct{
+			SKU:              row.SKU,
+			Name:             row.Name,
+			Description:      row.Description,
+			ImageURL:         row.ImageURL,
+			Price:            row.Price,
+			Quantity:         row.Quantity,
+			ReorderThreshold: row.ReorderThreshold,
+			StockChange:      change,
+		})
+		if err != nil {
+			return Product{}, "", err
+		}
+		return updated, ImportActionUpdate, nil
+
+	case !errors.Is(err, ErrNotFound):
+		return Product{}, "", err
+	}
+
+	if row.Name == nil || row.Price == nil || row.Quantity == nil {
+		return Product{}, "", fmt.Errorf("name, price and quantity are required to create a product: %w", ErrImportMissingField)
+	}
+
+	now := time.Now()
+
+	product := Product{
+		ID:          uuid.New(),
+		Name:        *row.Name,
+		Price:       *row.Price,
+		Quantity:    *row.Quantity,
+		Version:     1,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if row.ID != nil {
+		product.ID = *row.ID
+	}
+
+	if row.SKU != nil {
+		product.SKU = *row.SKU
+	}
+
+	if row.Description != nil {
+		product.Description = *row.Description
+	}
+
+	if row.ReorderThreshold != nil {
+		product.ReorderThreshold = *row.ReorderThreshold
+	}
+
+	if row.ImageURL != nil {
+		product.ImageURL = *row.ImageURL
+	}
+
+	if err := b.storer.Create(ctx, product); err != nil {
+		return Product{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	if err := b.recordMovement(ctx, product, product.Quantity, change); err != nil {
+		return Product{}, "", fmt.Errorf("record movement: %w", err)
+	}
+
+	return product, ImportActionCreate, nil
+}
+
+func (b *Business) importLookup(ctx context.Context, row ImportRow) (Product, error) {
+	switch {
+	case row.ID != nil:
+		return b.QueryByID(ctx, *row.ID)
+	case row.SKU != nil:
+		return b.QueryBySKU(ctx, *row.SKU)
+	default:
+		return Product{}, ErrNotFound
+	}
+}
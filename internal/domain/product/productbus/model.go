@@ -11,34 +11,94 @@ import (
 
 // Product represents information about an individual product.
 type Product struct {
-	ID          uuid.UUID
-	Name        Name
-	Description string
-	ImageURL    url.URL
-	Price       int64
-	Quantity    int32
-	DateCreated time.Time
-	DateUpdated time.Time
+	ID               uuid.UUID
+	SKU              SKU
+	Name             Name
+	Description      string
+	ImageURL         url.URL
+	Price            int64
+	Quantity         int32
+	ReorderThreshold int32
+	Rating           Rating
+	Version          int
+	DateCreated      time.Time
+	DateUpdated      time.Time
+}
+
+// Rating represents the aggregated review ratings of a product.
+type Rating struct {
+	Average float64
+	Count   int32
 }
 
 // =============================================================================
 
 // NewProduct contains information needed to create a new product.
 type NewProduct struct {
-	Name        Name
-	Description string
-	ImageURL    url.URL
-	Price       int64
-	Quantity    int32
+	SKU              SKU
+	Name             Name
+	Description      string
+	ImageURL         url.URL
+	Price            int64
+	Quantity         int32
+	ReorderThreshold int32
+	StockChange      StockChange
 }
 
 // =============================================================================
 
 // UpdateProduct contains information needed to update a product.
 type UpdateProduct struct {
-	Name        *Name
-	Description *string
-	ImageURL    *url.URL
-	Price       *int64
-	Quantity    *int32
+	SKU              *SKU
+	Name             *Name
+	Description      *string
+	ImageURL         *url.URL
+	Price            *int64
+	Quantity         *int32
+	ReorderThreshold *int32
+	StockChange      StockChange
+}
+
+// =============================================================================
+
+// StockChange describes who changed the quantity of a product and why. When
+// the kind is not provided, the change is recorded as an adjustment.
+type StockChange struct {
+	Kind        MovementKind
+	ActorID     uuid.UUID
+	ReferenceID uuid.UUID
+	Reason      string
+}
+
+// Movement represents a single change of a product quantity.
+type Movement struct {
+	ID          uuid.UUID
+	ProductID   uuid.UUID
+	Kind        MovementKind
+	Delta       int32
+	Quantity    int32
+	ActorID     uuid.UUID
+	ReferenceID uuid.UUID
+	Reason      string
+	DateCreated time.Time
+}
+
+// Discrepancy represents a product whose quantity does not match the sum of
+// its stock movements.
+type Discrepancy struct {
+	ProductID     uuid.UUID
+	Quantity      int32
+	MovementTotal int64
+}
+
+// =============================================================================
+
+// Subscription represents a user waiting for an out of stock product to be
+// back in stock. A subscription is closed once the user has been notified.
+type Subscription struct {
+	ID           uuid.UUID
+	ProductID    uuid.UUID
+	UserID       uuid.UUID
+	DateCreated  time.Time
+	DateNotified time.Time
 }
@@ -0,0 +1,73 @@
+package productbus
+
+import "fmt"
+
+type movementKindSet struct {
+	Sale         MovementKind
+	Cancellation MovementKind
+	Adjustment   MovementKind
+	Import       MovementKind
+	Return       MovementKind
+}
+
+// MovementKinds represents the set of reasons a product quantity can change.
+var MovementKinds = movementKindSet{
+	Sale:         newMovementKind("SALE"),
+	Cancellation: newMovementKind("CANCELLATION"),
+	Adjustment:   newMovementKind("ADJUSTMENT"),
+	Import:       newMovementKind("IMPORT"),
+	Return:       newMovementKind("RETURN"),
+}
+
+// =============================================================================
+
+var movementKinds = make(map[string]MovementKind)
+
+// MovementKind represents the reason of a stock movement.
+type MovementKind struct {
+	name string
+}
+
+func newMovementKind(kind string) MovementKind {
+	k := MovementKind{kind}
+	movementKinds[kind] = k
+	return k
+}
+
+// String returns the name of the kind.
+func (k MovementKind) String() string {
+	return k.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (k MovementKind) Equal(k2 MovementKind) bool {
+	return k.name == k2.name
+}
+
+// IsZero reports whether the kind has not been set.
+func (k MovementKind) IsZero() bool {
+	return k.name == ""
+}
+
+// =============================================================================
+
+// ParseMovementKind parses the string value and returns a kind if one exists.
+func ParseMovementKind(value string) (MovementKind, error) {
+	kind, exists := movementKinds[value]
+	if !exists {
+		return MovementKind{}, fmt.Errorf("invalid movement kind %q", value)
+	}
+
+	return kind, nil
+}
+
+// MustParseMovementKind parses the string value and returns a kind if one
+// exists. If an error occurs the function panics.
+func MustParseMovementKind(value string) MovementKind {
+	kind, err := ParseMovementKind(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return kind
+}
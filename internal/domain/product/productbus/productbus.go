@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/notify"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
@@ -16,7 +17,12 @@ import (
 // Set of error variables for CRUD operations.
 
 var (
-	ErrNotFound = errors.New("product not found")
+	ErrNotFound          = errors.New("product not found")
+	ErrUniqueSKU         = errors.New("sku is not unique")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrInStock           = errors.New("product is in stock")
+	ErrSubscribed        = errors.New("already subscribed")
+	ErrConflict          = errors.New("product was modified concurrently")
 )
 
 // Storer interface declares the behavior this package needs to perists and retrieve data.
@@ -28,19 +34,35 @@ type Storer interface {
 	Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]Product, error)
 	Count(ctx context.Context, filter QueryFilter) (int, error)
 	QueryByID(ctx context.Context, productID uuid.UUID) (Product, error)
+	QueryByIDForUpdate(ctx context.Context, productID uuid.UUID) (Product, error)
+	QueryBySKU(ctx context.Context, sku SKU) (Product, error)
 	QueryByIDs(ctx context.Context, productIDs []uuid.UUID) ([]Product, error)
+
+	UpdateRating(ctx context.Context, product Product) error
+
+	AdjustQuantity(ctx context.Context, product Product, delta int32) (Product, error)
+	CreateMovement(ctx context.Context, movement Movement) error
+	QueryMovements(ctx context.Context, filter MovementFilter, page page.Page) ([]Movement, error)
+	CountMovements(ctx context.Context, filter MovementFilter) (int, error)
+	QueryDiscrepancies(ctx context.Context) ([]Discrepancy, error)
+
+	CreateSubscription(ctx context.Context, sub Subscription) error
+	DeleteSubscription(ctx context.Context, productID uuid.UUID, userID uuid.UUID) error
+	CloseSubscriptions(ctx context.Context, productID uuid.UUID, dateNotified time.Time) ([]Subscription, error)
 }
 
 type Business struct {
 	log    *logger.Logger
 	storer Storer
+	sink   notify.Sink
 }
 
 // NewBusiness constructs a business API for use.
-func NewBusiness(log *logger.Logger, storer Storer) *Business {
+func NewBusiness(log *logger.Logger, storer Storer, sink notify.Sink) *Business {
 	return &Business{
 		log:    log,
 		storer: storer,
+		sink:   sink,
 	}
 }
 
@@ -54,6 +76,7 @@ func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
 	bus := Business{
 		log:    b.log,
 		storer: storerTx,
+		sink:   b.sink,
 	}
 
 	return &bus, nil
@@ -65,24 +88,35 @@ func (b *Business) Create(ctx context.Context, newProduct NewProduct) (Product,
 	// todo: upload image to s3
 
 	product := Product{
-		ID:          uuid.New(),
-		Name:        newProduct.Name,
-		Description: newProduct.Description,
-		ImageURL:    newProduct.ImageURL,
-		Price:       newProduct.Price,
-		Quantity:    newProduct.Quantity,
-		DateCreated: now,
-		DateUpdated: now,
+		ID:               uuid.New(),
+		SKU:              newProduct.SKU,
+		Name:             newProduct.Name,
+		Description:      newProduct.Description,
+		ImageURL:         newProduct.ImageURL,
+		Price:            newProduct.Price,
+		Quantity:         newProduct.Quantity,
+		ReorderThreshold: newProduct.ReorderThreshold,
+		Version:          1,
+		DateCreated:      now,
+		DateUpdated:      now,
 	}
 
 	if err := b.storer.Create(ctx, product); err != nil {
 		return Product{}, fmt.Errorf("create: %w", err)
 	}
 
+	if err := b.recordMovement(ctx, product, product.Quantity, newProduct.StockChange); err != nil {
+		return Product{}, fmt.Errorf("record movement: %w", err)
+	}
+
 	return product, nil
 }
 
 func (b *Business) Update(ctx context.Context, product Product, updateProduct UpdateProduct) (Product, error) {
+	if updateProduct.SKU != nil {
+		product.SKU = *updateProduct.SKU
+	}
+
 	if updateProduct.Name != nil {
 		product.Name = *updateProduct.Name
 	}
@@ -99,7 +133,13 @@ func (b *Business) Update(ctx context.Context, product Product, updateProduct Up
 		product.Price = *updateProduct.Price
 	}
 
+	if updateProduct.ReorderThreshold != nil {
+		product.ReorderThreshold = *updateProduct.ReorderThreshold
+	}
+
+	var delta int32
 	if updateProduct.Quantity != nil {
+		delta = *updateProduct.Quantity - product.Quantity
 		product.Quantity = *updateProduct.Quantity
 	}
 
@@ -109,6 +149,27 @@ func (b *Business) Update(ctx context.Context, product Product, updateProduct Up
 		return Product{}, fmt.Errorf("update: %w", err)
 	}
 
+	product.Version++
+
+	if err := b.recordMovement(ctx, product, delta, updateProduct.StockChange); err != nil {
+		return Product{}, fmt.Errorf("record movement: %w", err)
+	}
+
+	if err := b.notifyStockChange(ctx, product, delta, updateProduct.StockChange); err != nil {
+		return Product{}, fmt.Errorf("notify stock change: %w", err)
+	}
+
+	return product, nil
+}
+
+// UpdateRating stores the aggregated review ratings of the product.
+func (b *Business) UpdateRating(ctx context.Context, product Product, rating Rating) (Product, error) {
+	product.Rating = rating
+
+	if err := b.storer.UpdateRating(ctx, product); err != nil {
+		return Product{}, fmt.Errorf("update rating: productID[%s]: %w", product.ID, err)
+	}
+
 	return product, nil
 }
 
@@ -150,3 +211,23 @@ func (b *Business) QueryByID(ctx context.Context, productID uuid.UUID) (Product,
 
 	return product, nil
 }
+
+// QueryByIDForUpdate gets the product and locks it until the transaction
+// ends, for changes that derive from its current state.
+func (b *Business) QueryByIDForUpdate(ctx context.Context, productID uuid.UUID) (Product, error) {
+	product, err := b.storer.QueryByIDForUpdate(ctx, productID)
+	if err != nil {
+		return Product{}, fmt.Errorf("query for update: productID[%s]: %w", productID, err)
+	}
+
+	return product, nil
+}
+
+func (b *Business) QueryBySKU(ctx context.Context, sku SKU) (Product, error) {
+	product, err := b.storer.QueryBySKU(ctx, sku)
+	if err != nil {
+		return Product{}, fmt.Errorf("query: sku[%s]: %w", sku, err)
+	}
+
+	return product, nil
+}
@@ -0,0 +1,46 @@
+package productbus
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SKU represents a stock keeping unit in the system.
+type SKU struct {
+	sku string
+}
+
+// String returns the value of the sku.
+func (s SKU) String() string {
+	return s.sku
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (s SKU) Equal(s2 SKU) bool {
+	return s.sku == s2.sku
+}
+
+// =============================================================================
+
+var skuRegEx = regexp.MustCompile("^[A-Z0-9][A-Z0-9_-]{2,63}$")
+
+// ParseSKU parses the string value and returns a sku if the value complies
+// with the rules for a sku.
+func ParseSKU(value string) (SKU, error) {
+	if !skuRegEx.MatchString(value) {
+		return SKU{}, fmt.Errorf("invalid sku %q", value)
+	}
+
+	return SKU{value}, nil
+}
+
+// MustParseSKU parses the string value and returns a sku if the value
+// complies with the rules for a sku. If an error occurs the function panics.
+func MustParseSKU(value string) SKU {
+	sku, err := ParseSKU(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return sku
+}
@@ -13,4 +13,5 @@ const (
 	SortByName        = "name"
 	SortByPrice       = "price"
 	SortByQuantity    = "quantity"
+	SortByRating      = "rating"
 )
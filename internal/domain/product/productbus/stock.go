@@ -0,0 +1,92 @@
+package productbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+)
+
+// AdjustStock changes the quantity of the product by delta and records the
+// movement. The change is applied relative to the stored quantity, so
+// concurrent adjustments don't overwrite each other. The quantity can never
+// become negative.
+func (b *Business) AdjustStock(ctx context.Context, product Product, delta int32, change StockChange) (Product, error) {
+	if delta == 0 {
+		return product, nil
+	}
+
+	updated, err := b.storer.AdjustQuantity(ctx, product, delta)
+	if err != nil {
+		return Product{}, fmt.Errorf("adjust quantity: productID[%s] delta[%d]: %w", product.ID, delta, err)
+	}
+
+	if err := b.recordMovement(ctx, updated, delta, change); err != nil {
+		return Product{}, fmt.Errorf("record movement: %w", err)
+	}
+
+	if err := b.notifyStockChange(ctx, updated, delta, change); err != nil {
+		return Product{}, fmt.Errorf("notify stock change: %w", err)
+	}
+
+	return updated, nil
+}
+
+// QueryMovements retrieves a page of stock movements, most recent first.
+func (b *Business) QueryMovements(ctx context.Context, filter MovementFilter, page page.Page) ([]Movement, error) {
+	movements, err := b.storer.QueryMovements(ctx, filter, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return movements, nil
+}
+
+// CountMovements returns the total number of stock movements for the filter.
+func (b *Business) CountMovements(ctx context.Context, filter MovementFilter) (int, error) {
+	return b.storer.CountMovements(ctx, filter)
+}
+
+// Reconcile returns every product whose quantity does not match the sum of
+// its recorded stock movements.
+func (b *Business) Reconcile(ctx context.Context) ([]Discrepancy, error) {
+	discrepancies, err := b.storer.QueryDiscrepancies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query discrepancies: %w", err)
+	}
+
+	return discrepancies, nil
+}
+
+// recordMovement stores a movement for a product that has already been
+// written with its resulting quantity.
+func (b *Business) recordMovement(ctx context.Context, product Product, delta int32, change StockChange) error {
+	if delta == 0 {
+		return nil
+	}
+
+	kind := change.Kind
+	if kind.IsZero() {
+		kind = MovementKinds.Adjustment
+	}
+
+	movement := Movement{
+		ID:          uuid.New(),
+		ProductID:   product.ID,
+		Kind:        kind,
+		Delta:       delta,
+		Quantity:    product.Quantity,
+		ActorID:     change.ActorID,
+		ReferenceID: change.ReferenceID,
+		Reason:      change.Reason,
+		DateCreated: time.Now(),
+	}
+
+	if err := b.storer.CreateMovement(ctx, movement); err != nil {
+		return fmt.Errorf("create movement: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package productbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/notify"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+// Set of notification topics sent by this package.
+const (
+	TopicLowStock    = "product.low_stock"
+	TopicBackInStock = "product.back_in_stock"
+)
+
+// Subscribe registers the user to be notified once the product is back in
+// stock. Only out of stock products can be subscribed to.
+func (b *Business) Subscribe(ctx context.Context, product Product, userID uuid.UUID) (Subscription, error) {
+	if product.Quantity > 0 {
+		return Subscription{}, fmt.Errorf("productID[%s]: %w", product.ID, ErrInStock)
+	}
+
+	sub := Subscription{
+		ID:          uuid.New(),
+		ProductID:   product.ID,
+		UserID:      userID,
+		DateCreated: time.Now(),
+	}
+
+	if err := b.storer.CreateSubscription(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("create subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Unsubscribe removes the open subscription of the user for the product.
+func (b *Business) Unsubscribe(ctx context.Context, product Product, userID uuid.UUID) error {
+	if err := b.storer.DeleteSubscription(ctx, product.ID, userID); err != nil {
+		return fmt.Errorf("delete subscription: %w", err)
+	}
+
+	return nil
+}
+
+// notifyStockChange sends the notifications triggered by a quantity change of
+// delta that resulted in the quantity of the product. Admins are alerted when
+// a sale pushes the quantity below the reorder threshold, subscribers are
+// notified when the quantity goes from zero to a positive value. Nothing is
+// sent before the transaction of the change is committed.
+func (b *Business) notifyStockChange(ctx context.Context, product Product, delta int32, change StockChange) error {
+	previous := product.Quantity - delta
+
+	if change.Kind.Equal(MovementKinds.Sale) && previous >= product.ReorderThreshold && product.Quantity < product.ReorderThreshold {
+		n := notify.Notification{
+			Topic:  TopicLowStock,
+			Admins: true,
+			Data: map[string]any{
+				"product_id":        product.ID.String(),
+				"name":              product.Name.String(),
+				"quantity":          product.Quantity,
+				"reorder_threshold": product.ReorderThreshold,
+			},
+		}
+
+		b.sendAfterCommit(ctx, n)
+	}
+
+	if previous != 0 || product.Quantity <= 0 {
+		return nil
+	}
+
+	subs, err := b.storer.CloseSubscriptions(ctx, product.ID, time.Now())
+	if err != nil {
+		return fmt.Errorf("close subscriptions: %w", err)
+	}
+
+	if len(subs) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, len(subs))
+	for i, sub := range subs {
+		userIDs[i] = sub.UserID
+	}
+
+	n := notify.Notification{
+		Topic:   TopicBackInStock,
+		UserIDs: userIDs,
+		Data: map[string]any{
+			"product_id": product.ID.String(),
+			"name":       product.Name.String(),
+			"quantity":   product.Quantity,
+		},
+	}
+
+	b.sendAfterCommit(ctx, n)
+
+	return nil
+}
+
+// sendAfterCommit delivers the notification once the current transaction, if
+// any, is committed. The change is already stored by then so a notification
+// that can't be delivered is only logged.
+func (b *Business) sendAfterCommit(ctx context.Context, n notify.Notification) {
+	sqldb.AfterCommit(ctx, func(ctx context.Context) {
+		if err := b.sink.Send(ctx, n); err != nil {
+			b.log.Error(ctx, "send notification", "topic", n.Topic, "err", err)
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package productio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+)
+
+// decodeCSV reads a CSV document where the first line is a header naming the
+// columns. Columns can be provided in any order and unknown columns are ignored.
+func decodeCSV(r io.Reader) ([]productbus.ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("missing header")
+		}
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+
+	var rows []productbus.ImportRow
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("read line: %w", err)
+			}
+			rows = append(rows, productbus.ImportRow{Line: parseErr.StartLine, Err: err})
+			continue
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		rec, err := csvRecord(index, fields)
+		if err != nil {
+			rows = append(rows, productbus.ImportRow{Line: line, Err: err})
+			continue
+		}
+
+		row, err := toImportRow(line, rec)
+		if err != nil {
+			rows = append(rows, productbus.ImportRow{Line: line, Err: err})
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func csvRecord(index map[string]int, fields []string) (record, error) {
+	value := func(col string) *string {
+		i, exists := index[col]
+		if !exists || i >= len(fields) {
+			return nil
+		}
+
+		v := strings.TrimSpace(fields[i])
+		if v == "" {
+			return nil
+		}
+
+		return &v
+	}
+
+	rec := record{
+		ID:          value("id"),
+		SKU:         value("sku"),
+		Name:        value("name"),
+		Description: value("description"),
+		ImageURL:    value("image_url"),
+	}
+
+	if v := value("price"); v != nil {
+		price, err := strconv.ParseInt(*v, 10, 64)
+		if err != nil {
+			return record{}, fmt.Errorf("parse price: %w", err)
+		}
+		rec.Price = &price
+	}
+
+	if v := value("quantity"); v != nil {
+		quantity, err := strconv.ParseInt(*v, 10, 32)
+		if err != nil {
+			return record{}, fmt.Errorf("parse quantity: %w", err)
+		}
+		q := int32(quantity)
+		rec.Quantity = &q
+	}
+
+	if v := value("reorder_threshold"); v != nil {
+		threshold, err := strconv.ParseInt(*v, 10, 32)
+		if err != nil {
+			return record{}, fmt.Errorf("parse reorder_threshold: %w", err)
+		}
+		t := int32(threshold)
+		rec.ReorderThreshold = &t
+	}
+
+	return rec, nil
+}
+
+// =============================================================================
+
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return nil, fmt.Errorf("write header: %w", err)
+	}
+
+	return &csvEncoder{w: cw}, nil
+}
+
+// Encode implements the Encoder interface.
+func (e *csvEncoder) Encode(prd productbus.Product) error {
+	rec := toRecord(prd)
+
+	var sku string
+	if rec.SKU != nil {
+		sku = *rec.SKU
+	}
+
+	return e.w.Write([]string{
+		*rec.ID,
+		sku,
+		*rec.Name,
+		*rec.Description,
+		*rec.ImageURL,
+		strconv.FormatInt(*rec.Price, 10),
+		strconv.FormatInt(int64(*rec.Quantity), 10),
+		strconv.FormatInt(int64(*rec.ReorderThreshold), 10),
+		rec.DateCreated,
+		rec.DateUpdated,
+	})
+}
+
+// Flush implements the Encoder interface.
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
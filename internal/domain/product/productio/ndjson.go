@@ -0,0 +1,74 @@
+package productio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+)
+
+// maxLineSize limits the size of a single NDJSON line.
+const maxLineSize = 1024 * 1024
+
+// decodeNDJSON reads one JSON object per line. Blank lines are skipped.
+func decodeNDJSON(r io.Reader) ([]productbus.ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var rows []productbus.ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			rows = append(rows, productbus.ImportRow{Line: line, Err: fmt.Errorf("unmarshal: %w", err)})
+			continue
+		}
+
+		row, err := toImportRow(line, rec)
+		if err != nil {
+			rows = append(rows, productbus.ImportRow{Line: line, Err: err})
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return rows, nil
+}
+
+// =============================================================================
+
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	bw := bufio.NewWriter(w)
+
+	return &ndjsonEncoder{
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+// Encode implements the Encoder interface.
+func (e *ndjsonEncoder) Encode(prd productbus.Product) error {
+	return e.enc.Encode(toRecord(prd))
+}
+
+// Flush implements the Encoder interface.
+func (e *ndjsonEncoder) Flush() error {
+	return e.w.Flush()
+}
@@ -0,0 +1,120 @@
+// Package productio provides support for reading and writing products in bulk
+// using CSV or newline delimited JSON.
+package productio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+)
+
+// Set of supported formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// exportRowsPerPage is the number of products read from the store for every
+// page of an export.
+const exportRowsPerPage = 100
+
+// exportSortBy keeps the export stable while products are being created.
+var exportSortBy = sort.NewBy(productbus.SortByDateCreated, sort.ASC)
+
+// Columns represents the set of fields, in order, used by both formats.
+var Columns = []string{"id", "sku", "name", "description", "image_url", "price", "quantity", "reorder_threshold", "date_created", "date_updated"}
+
+// ParseFormat validates the format and returns it in its canonical form.
+func ParseFormat(value string) (string, error) {
+	switch value {
+	case FormatCSV, "text/csv":
+		return FormatCSV, nil
+	case FormatNDJSON, "jsonl", "application/x-ndjson":
+		return FormatNDJSON, nil
+	default:
+		return "", fmt.Errorf("invalid format %q", value)
+	}
+}
+
+// ContentType returns the http content type for the format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Decode reads every row from r using the specified format. Rows that fail
+// validation are returned with their Err field set so they can be reported,
+// while a returned error means the input could not be read at all.
+func Decode(format string, r io.Reader) ([]productbus.ImportRow, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatNDJSON:
+		return decodeNDJSON(r)
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+}
+
+// Encoder writes products to an underlying writer.
+type Encoder interface {
+	Encode(prd productbus.Product) error
+	Flush() error
+}
+
+// NewEncoder constructs an encoder for the specified format.
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder(w)
+	case FormatNDJSON:
+		return newNDJSONEncoder(w), nil
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+}
+
+// Export streams every product matching the filter to the encoder, one page
+// at a time. The afterPage function, if provided, is called after every page
+// is encoded which allows the caller to flush the underlying writer.
+func Export(ctx context.Context, productBus *productbus.Business, filter productbus.QueryFilter, enc Encoder, afterPage func()) (int, error) {
+	var total int
+
+	for number := 1; ; number++ {
+		pg, err := page.Parse(strconv.Itoa(number), strconv.Itoa(exportRowsPerPage))
+		if err != nil {
+			return total, fmt.Errorf("page: %w", err)
+		}
+
+		prds, err := productBus.Query(ctx, filter, exportSortBy, pg)
+		if err != nil {
+			return total, fmt.Errorf("query: %w", err)
+		}
+
+		for _, prd := range prds {
+			if err := enc.Encode(prd); err != nil {
+				return total, fmt.Errorf("encode: productID[%s]: %w", prd.ID, err)
+			}
+		}
+		total += len(prds)
+
+		if err := enc.Flush(); err != nil {
+			return total, fmt.Errorf("flush: %w", err)
+		}
+
+		if afterPage != nil {
+			afterPage()
+		}
+
+		if len(prds) < exportRowsPerPage {
+			return total, nil
+		}
+	}
+}
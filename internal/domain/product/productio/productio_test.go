@@ -0,0 +1,131 @@
+package productio
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+)
+
+func testProducts() []productbus.Product {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	return []productbus.Product{
+		{
+			ID:               uuid.New(),
+			SKU:              productbus.MustParseSKU("SKU-001"),
+			Name:             productbus.MustParseName("Coffee"),
+			Description:      "Dark, \"bold\" roast\non two lines",
+			ImageURL:         url.URL{Scheme: "https", Host: "cdn.example.com", Path: "/coffee.png"},
+			Price:            1500,
+			Quantity:         20,
+			ReorderThreshold: 5,
+			DateCreated:      now,
+			DateUpdated:      now,
+		},
+		{
+			ID:          uuid.New(),
+			Name:        productbus.MustParseName("Tea"),
+			ImageURL:    url.URL{Scheme: "https", Host: "cdn.example.com", Path: "/tea.png"},
+			Price:       900,
+			DateCreated: now,
+			DateUpdated: now,
+		},
+	}
+}
+
+func Test_RoundTrip(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			prds := testProducts()
+
+			var buf bytes.Buffer
+			enc, err := NewEncoder(format, &buf)
+			if err != nil {
+				t.Fatalf("Should be able to create an encoder: %s", err)
+			}
+			for _, prd := range prds {
+				if err := enc.Encode(prd); err != nil {
+					t.Fatalf("Should be able to encode a product: %s", err)
+				}
+			}
+			if err := enc.Flush(); err != nil {
+				t.Fatalf("Should be able to flush: %s", err)
+			}
+
+			rows, err := Decode(format, &buf)
+			if err != nil {
+				t.Fatalf("Should be able to decode what was encoded: %s", err)
+			}
+			if len(rows) != len(prds) {
+				t.Fatalf("Should decode %d rows, got %d", len(prds), len(rows))
+			}
+
+			for i, row := range rows {
+				prd := prds[i]
+
+				if row.Err != nil {
+					t.Fatalf("Row %d should be valid: %s", i, row.Err)
+				}
+				if row.ID == nil || *row.ID != prd.ID {
+					t.Errorf("Row %d should keep the id %s, got %v", i, prd.ID, row.ID)
+				}
+				if row.Name == nil || row.Name.String() != prd.Name.String() {
+					t.Errorf("Row %d should keep the name %q, got %v", i, prd.Name, row.Name)
+				}
+				if got := row.SKU; (prd.SKU.String() == "") != (got == nil) || (got != nil && got.String() != prd.SKU.String()) {
+					t.Errorf("Row %d should keep the sku %q, got %v", i, prd.SKU, got)
+				}
+				// An empty CSV field reads as absent.
+				var description string
+				if row.Description != nil {
+					description = *row.Description
+				}
+				if description != prd.Description {
+					t.Errorf("Row %d should keep the description %q, got %q", i, prd.Description, description)
+				}
+				if row.ImageURL == nil || row.ImageURL.String() != prd.ImageURL.String() {
+					t.Errorf("Row %d should keep the image url %q, got %v", i, prd.ImageURL.String(), row.ImageURL)
+				}
+				if row.Price == nil || *row.Price != prd.Price {
+					t.Errorf("Row %d should keep the price %d, got %v", i, prd.Price, row.Price)
+				}
+				if row.Quantity == nil || *row.Quantity != prd.Quantity {
+					t.Errorf("Row %d should keep the quantity %d, got %v", i, prd.Quantity, row.Quantity)
+				}
+				if row.ReorderThreshold == nil || *row.ReorderThreshold != prd.ReorderThreshold {
+					t.Errorf("Row %d should keep the reorder threshold %d, got %v", i, prd.ReorderThreshold, row.ReorderThreshold)
+				}
+			}
+		})
+	}
+}
+
+func Test_DecodeInvalidRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		doc    string
+	}{
+		{name: "csv price", format: FormatCSV, doc: "name,price\nTea,abc\n"},
+		{name: "csv negative quantity", format: FormatCSV, doc: "name,price,quantity\nTea,10,-1\n"},
+		{name: "ndjson id", format: FormatNDJSON, doc: `{"id":"nope","name":"Tea"}` + "\n"},
+		{name: "ndjson syntax", format: FormatNDJSON, doc: `{"name":` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := Decode(tt.format, strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatalf("Should report invalid rows, not fail: %s", err)
+			}
+			if len(rows) != 1 || rows[0].Err == nil {
+				t.Fatalf("Should return the row with its error, got %+v", rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package productio
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+)
+
+// record represents the raw fields of a single row before validation. A nil
+// field means the column was absent or empty.
+type record struct {
+	ID               *string `json:"id"`
+	SKU              *string `json:"sku"`
+	Name             *string `json:"name"`
+	Description      *string `json:"description"`
+	ImageURL         *string `json:"image_url"`
+	Price            *int64  `json:"price"`
+	Quantity         *int32  `json:"quantity"`
+	ReorderThreshold *int32  `json:"reorder_threshold"`
+	DateCreated      string  `json:"date_created,omitempty"`
+	DateUpdated      string  `json:"date_updated,omitempty"`
+}
+
+func toRecord(prd productbus.Product) record {
+	var sku *string
+	if prd.SKU.String() != "" {
+		v := prd.SKU.String()
+		sku = &v
+	}
+
+	id := prd.ID.String()
+	name := prd.Name.String()
+	imageURL := prd.ImageURL.String()
+
+	return record{
+		ID:               &id,
+		SKU:              sku,
+		Name:             &name,
+		Description:      &prd.Description,
+		ImageURL:         &imageURL,
+		Price:            &prd.Price,
+		Quantity:         &prd.Quantity,
+		ReorderThreshold: &prd.ReorderThreshold,
+		DateCreated:      prd.DateCreated.Format(time.RFC3339),
+		DateUpdated:      prd.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+// toImportRow validates the record using the product business parsers.
+func toImportRow(line int, rec record) (productbus.ImportRow, error) {
+	rec.ID = nonEmpty(rec.ID)
+	rec.SKU = nonEmpty(rec.SKU)
+	rec.Name = nonEmpty(rec.Name)
+	rec.ImageURL = nonEmpty(rec.ImageURL)
+
+	row := productbus.ImportRow{
+		Line:             line,
+		Description:      rec.Description,
+		Price:            rec.Price,
+		Quantity:         rec.Quantity,
+		ReorderThreshold: rec.ReorderThreshold,
+	}
+
+	if rec.ID != nil {
+		id, err := uuid.Parse(*rec.ID)
+		if err != nil {
+			return productbus.ImportRow{}, fmt.Errorf("parse id: %w", err)
+		}
+		row.ID = &id
+	}
+
+	if rec.SKU != nil {
+		sku, err := productbus.ParseSKU(*rec.SKU)
+		if err != nil {
+			return productbus.ImportRow{}, fmt.Errorf("parse sku: %w", err)
+		}
+		row.SKU = &sku
+	}
+
+	if rec.Name != nil {
+		name, err := productbus.ParseName(*rec.Name)
+		if err != nil {
+			return productbus.ImportRow{}, fmt.Errorf("parse name: %w", err)
+		}
+		row.Name = &name
+	}
+
+	if rec.ImageURL != nil {
+		imageURL, err := url.ParseRequestURI(*rec.ImageURL)
+		if err != nil {
+			return productbus.ImportRow{}, fmt.Errorf("parse image_url: %w", err)
+		}
+		row.ImageURL = imageURL
+	}
+
+	if rec.Price != nil && *rec.Price < 1 {
+		return productbus.ImportRow{}, fmt.Errorf("invalid price %d: must be greater than 0", *rec.Price)
+	}
+
+	if rec.Quantity != nil && *rec.Quantity < 0 {
+		return productbus.ImportRow{}, fmt.Errorf("invalid quantity %d: must not be negative", *rec.Quantity)
+	}
+
+	if rec.ReorderThreshold != nil && *rec.ReorderThreshold < 0 {
+		return productbus.ImportRow{}, fmt.Errorf("invalid reorder_threshold %d: must not be negative", *rec.ReorderThreshold)
+	}
+
+	return row, nil
+}
+
+func nonEmpty(v *string) *string {
+	if v == nil || *v == "" {
+		return nil
+	}
+
+	return v
+}
@@ -35,6 +35,30 @@ func applyFilter(filter productbus.QueryFilter, data map[string]any, buf *bytes.
 		wc = append(wc, "price <= :end_price")
 	}
 
+	if filter.MinRating != nil {
+		data["min_rating"] = filter.MinRating
+		wc = append(wc, "rating_average >= :min_rating")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
+
+func applyMovementFilter(filter productbus.MovementFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ProductID != nil {
+		data["product_id"] = filter.ProductID
+		wc = append(wc, "product_id = :product_id")
+	}
+
+	if filter.Kind != nil {
+		data["kind"] = filter.Kind.String()
+		wc = append(wc, "kind = :kind")
+	}
+
 	if len(wc) > 0 {
 		buf.WriteString(" WHERE ")
 		buf.WriteString(strings.Join(wc, " AND "))
@@ -11,26 +11,36 @@ import (
 )
 
 type productRow struct {
-	ID          uuid.UUID      `db:"product_id"`
-	Name        string         `db:"name"`
-	Description sql.NullString `db:"description"`
-	ImageURL    sql.NullString `db:"image_url"`
-	Price       int64          `db:"price"`
-	Quantity    int32          `db:"quantity"`
-	DateCreated time.Time      `db:"date_created"`
-	DateUpdated time.Time      `db:"date_updated"`
+	ID               uuid.UUID      `db:"product_id"`
+	SKU              sql.NullString `db:"sku"`
+	Name             string         `db:"name"`
+	Description      sql.NullString `db:"description"`
+	ImageURL         sql.NullString `db:"image_url"`
+	Price            int64          `db:"price"`
+	Quantity         int32          `db:"quantity"`
+	ReorderThreshold int32          `db:"reorder_threshold"`
+	RatingAverage    float64        `db:"rating_average"`
+	RatingCount      int32          `db:"rating_count"`
+	Version          int            `db:"version"`
+	DateCreated      time.Time      `db:"date_created"`
+	DateUpdated      time.Time      `db:"date_updated"`
 }
 
 func toDBProduct(bus productbus.Product) productRow {
 	return productRow{
-		ID:          bus.ID,
-		Name:        bus.Name.String(),
-		Description: sql.NullString{String: bus.Description, Valid: bus.Description != ""},
-		ImageURL:    sql.NullString{String: bus.ImageURL.String(), Valid: bus.ImageURL.String() != ""},
-		Price:       bus.Price,
-		Quantity:    bus.Quantity,
-		DateCreated: bus.DateCreated.UTC(),
-		DateUpdated: bus.DateUpdated.UTC(),
+		ID:               bus.ID,
+		SKU:              sql.NullString{String: bus.SKU.String(), Valid: bus.SKU.String() != ""},
+		Name:             bus.Name.String(),
+		Description:      sql.NullString{String: bus.Description, Valid: bus.Description != ""},
+		ImageURL:         sql.NullString{String: bus.ImageURL.String(), Valid: bus.ImageURL.String() != ""},
+		Price:            bus.Price,
+		Quantity:         bus.Quantity,
+		ReorderThreshold: bus.ReorderThreshold,
+		RatingAverage:    bus.Rating.Average,
+		RatingCount:      bus.Rating.Count,
+		Version:          bus.Version,
+		DateCreated:      bus.DateCreated.UTC(),
+		DateUpdated:      bus.DateUpdated.UTC(),
 	}
 }
 
@@ -40,6 +50,14 @@ func toBusProduct(row productRow) (productbus.Product, error) {
 		return productbus.Product{}, fmt.Errorf("parse name: %w", err)
 	}
 
+	var sku productbus.SKU
+	if row.SKU.Valid {
+		sku, err = productbus.ParseSKU(row.SKU.String)
+		if err != nil {
+			return productbus.Product{}, fmt.Errorf("parse sku: %w", err)
+		}
+	}
+
 	var imageURL url.URL
 	if row.ImageURL.Valid {
 		imageURLPtr, err := url.Parse(row.ImageURL.String)
@@ -50,12 +68,19 @@ func toBusProduct(row productRow) (productbus.Product, error) {
 	}
 
 	bus := productbus.Product{
-		ID:          row.ID,
-		Name:        name,
-		Description: row.Description.String,
-		ImageURL:    imageURL,
-		Price:       row.Price,
-		Quantity:    row.Quantity,
+		ID:               row.ID,
+		SKU:              sku,
+		Name:             name,
+		Description:      row.Description.String,
+		ImageURL:         imageURL,
+		Price:            row.Price,
+		Quantity:         row.Quantity,
+		ReorderThreshold: row.ReorderThreshold,
+		Rating: productbus.Rating{
+			Average: row.RatingAverage,
+			Count:   row.RatingCount,
+		},
+		Version:     row.Version,
 		DateCreated: row.DateCreated.UTC(),
 		DateUpdated: row.DateUpdated.UTC(),
 	}
@@ -76,3 +101,124 @@ func toBusProducts(rows []productRow) ([]productbus.Product, error) {
 
 	return bus, nil
 }
+
+// =============================================================================
+
+type movementRow struct {
+	ID          uuid.UUID      `db:"stock_movement_id"`
+	ProductID   uuid.UUID      `db:"product_id"`
+	Kind        string         `db:"kind"`
+	Delta       int32          `db:"delta"`
+	Quantity    int32          `db:"quantity"`
+	ActorID     uuid.NullUUID  `db:"actor_id"`
+	ReferenceID uuid.NullUUID  `db:"reference_id"`
+	Reason      sql.NullString `db:"reason"`
+	DateCreated time.Time      `db:"date_created"`
+}
+
+func toDBMovement(bus productbus.Movement) movementRow {
+	return movementRow{
+		ID:          bus.ID,
+		ProductID:   bus.ProductID,
+		Kind:        bus.Kind.String(),
+		Delta:       bus.Delta,
+		Quantity:    bus.Quantity,
+		ActorID:     uuid.NullUUID{UUID: bus.ActorID, Valid: bus.ActorID != uuid.Nil},
+		ReferenceID: uuid.NullUUID{UUID: bus.ReferenceID, Valid: bus.ReferenceID != uuid.Nil},
+		Reason:      sql.NullString{String: bus.Reason, Valid: bus.Reason != ""},
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusMovement(row movementRow) (productbus.Movement, error) {
+	kind, err := productbus.ParseMovementKind(row.Kind)
+	if err != nil {
+		return productbus.Movement{}, fmt.Errorf("parse kind: %w", err)
+	}
+
+	bus := productbus.Movement{
+		ID:          row.ID,
+		ProductID:   row.ProductID,
+		Kind:        kind,
+		Delta:       row.Delta,
+		Quantity:    row.Quantity,
+		ActorID:     row.ActorID.UUID,
+		ReferenceID: row.ReferenceID.UUID,
+		Reason:      row.Reason.String,
+		DateCreated: row.DateCreated.UTC(),
+	}
+
+	return bus, nil
+}
+
+func toBusMovements(rows []movementRow) ([]productbus.Movement, error) {
+	bus := make([]productbus.Movement, len(rows))
+
+	for i, row := range rows {
+		var err error
+		bus[i], err = toBusMovement(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type discrepancyRow struct {
+	ProductID     uuid.UUID `db:"product_id"`
+	Quantity      int32     `db:"quantity"`
+	MovementTotal int64     `db:"movement_total"`
+}
+
+func toBusDiscrepancies(rows []discrepancyRow) []productbus.Discrepancy {
+	bus := make([]productbus.Discrepancy, len(rows))
+
+	for i, row := range rows {
+		bus[i] = productbus.Discrepancy{
+			ProductID:     row.ProductID,
+			Quantity:      row.Quantity,
+			MovementTotal: row.MovementTotal,
+		}
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type subscriptionRow struct {
+	ID           uuid.UUID    `db:"subscription_id"`
+	ProductID    uuid.UUID    `db:"product_id"`
+	UserID       uuid.UUID    `db:"user_id"`
+	DateCreated  time.Time    `db:"date_created"`
+	DateNotified sql.NullTime `db:"date_notified"`
+}
+
+func toDBSubscription(bus productbus.Subscription) subscriptionRow {
+	return subscriptionRow{
+		ID:           bus.ID,
+		ProductID:    bus.ProductID,
+		UserID:       bus.UserID,
+		DateCreated:  bus.DateCreated.UTC(),
+		DateNotified: sql.NullTime{Time: bus.DateNotified.UTC(), Valid: !bus.DateNotified.IsZero()},
+	}
+}
+
+func toBusSubscriptions(rows []subscriptionRow) []productbus.Subscription {
+	bus := make([]productbus.Subscription, len(rows))
+
+	for i, row := range rows {
+		bus[i] = productbus.Subscription{
+			ID:           row.ID,
+			ProductID:    row.ProductID,
+			UserID:       row.UserID,
+			DateCreated:  row.DateCreated.UTC(),
+			DateNotified: row.DateNotified.Time.UTC(),
+		}
+	}
+
+	return bus
+}
@@ -48,11 +48,14 @@ func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (productbus.Storer, error)
 func (s *Store) Create(ctx context.Context, product productbus.Product) error {
 	const q = `
 	INSERT INTO products
-		(product_id, name, description, image_url, price, quantity, date_created, date_updated)
+		(product_id, sku, name, description, image_url, price, quantity, reorder_threshold, version, date_created, date_updated)
 	VALUES
-		(:product_id, :name, :description, :image_url, :price, :quantity, :date_created, :date_updated)`
+		(:product_id, :sku, :name, :description, :image_url, :price, :quantity, :reorder_threshold, :version, :date_created, :date_updated)`
 
 	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBProduct(product)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", productbus.ErrUniqueSKU)
+		}
 		return fmt.Errorf("namedexeccontext: %w", err)
 	}
 
@@ -64,12 +67,43 @@ func (s *Store) Update(ctx context.Context, product productbus.Product) error {
 	UPDATE
 		products
 	SET 
+		"sku" = :sku,
 		"name" = :name,
 		"description" = :description,
 		"image_url" = :image_url,
 		"price" = :price,
 		"quantity" = :quantity,
+		"reorder_threshold" = :reorder_threshold,
+		"version" = version + 1,
 		"date_updated" = :date_updated
+	WHERE
+		product_id = :product_id AND version = :version
+	RETURNING
+		version`
+
+	var row struct {
+		Version int `db:"version"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBProduct(product), &row); err != nil {
+		switch {
+		case errors.Is(err, sqldb.ErrDBNotFound):
+			return fmt.Errorf("namedquerystruct: %w", productbus.ErrConflict)
+		case errors.Is(err, sqldb.ErrDBDuplicatedEntry):
+			return fmt.Errorf("namedquerystruct: %w", productbus.ErrUniqueSKU)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateRating(ctx context.Context, product productbus.Product) error {
+	const q = `
+	UPDATE
+		products
+	SET
+		"rating_average" = :rating_average,
+		"rating_count" = :rating_count
 	WHERE
 		product_id = :product_id`
 
@@ -83,7 +117,7 @@ func (s *Store) Update(ctx context.Context, product productbus.Product) error {
 func (s *Store) QueryByIDs(ctx context.Context, productIDs []uuid.UUID) ([]productbus.Product, error) {
 	const q = `
 	SELECT
-        product_id, name, description, image_url, price, quantity, date_created, date_updated
+        product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated
 	FROM
 		products
 	WHERE 
@@ -122,7 +156,7 @@ func (s *Store) Query(ctx context.Context, filter productbus.QueryFilter, sortBy
 
 	const q = `
 	SELECT
-		product_id, name, description, image_url, price, quantity, date_created, date_updated
+		product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated
 	FROM
 		products`
 
@@ -154,7 +188,7 @@ func (s *Store) QueryByID(ctx context.Context, prdID uuid.UUID) (productbus.Prod
 
 	const q = `
 	SELECT
-        product_id, name, description, image_url, price, quantity, date_created, date_updated
+        product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated
 	FROM
 		products
 	WHERE 
@@ -171,6 +205,61 @@ func (s *Store) QueryByID(ctx context.Context, prdID uuid.UUID) (productbus.Prod
 	return toBusProduct(row)
 }
 
+// QueryByIDForUpdate gets the product and locks it until the transaction
+// ends.
+func (s *Store) QueryByIDForUpdate(ctx context.Context, prdID uuid.UUID) (productbus.Product, error) {
+	data := struct {
+		ID string `db:"product_id"`
+	}{
+		ID: prdID.String(),
+	}
+
+	const q = `
+	SELECT
+        product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated
+	FROM
+		products
+	WHERE 
+		product_id = :product_id
+	FOR UPDATE`
+
+	var row productRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return productbus.Product{}, fmt.Errorf("db: %w", productbus.ErrNotFound)
+		}
+		return productbus.Product{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusProduct(row)
+}
+
+func (s *Store) QueryBySKU(ctx context.Context, sku productbus.SKU) (productbus.Product, error) {
+	data := struct {
+		SKU string `db:"sku"`
+	}{
+		SKU: sku.String(),
+	}
+
+	const q = `
+	SELECT
+        product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated
+	FROM
+		products
+	WHERE 
+		sku = :sku`
+
+	var row productRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return productbus.Product{}, fmt.Errorf("db: %w", productbus.ErrNotFound)
+		}
+		return productbus.Product{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusProduct(row)
+}
+
 func (s *Store) Count(ctx context.Context, filter productbus.QueryFilter) (int, error) {
 	data := map[string]any{}
 
@@ -11,6 +11,7 @@ var sortByFields = map[string]string{
 	productbus.SortByName:        "name",
 	productbus.SortByPrice:       "price",
 	productbus.SortByQuantity:    "quantity",
+	productbus.SortByRating:      "rating_average",
 }
 
 func orderByClause(sortBy sort.By) (string, error) {
@@ -0,0 +1,133 @@
+package productdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+// AdjustQuantity adds delta to the stored quantity unless the result would be
+// negative, and returns the product with its resulting quantity.
+func (s *Store) AdjustQuantity(ctx context.Context, product productbus.Product, delta int32) (productbus.Product, error) {
+	data := struct {
+		ID          uuid.UUID `db:"product_id"`
+		Delta       int32     `db:"delta"`
+		DateUpdated time.Time `db:"date_updated"`
+	}{
+		ID:          product.ID,
+		Delta:       delta,
+		DateUpdated: time.Now().UTC(),
+	}
+
+	const q = `
+	UPDATE
+		products
+	SET
+		"quantity" = quantity + :delta,
+		"version" = version + 1,
+		"date_updated" = :date_updated
+	WHERE
+		product_id = :product_id AND quantity + :delta >= 0
+	RETURNING
+		product_id, sku, name, description, image_url, price, quantity, reorder_threshold, rating_average, rating_count, version, date_created, date_updated`
+
+	var row productRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return productbus.Product{}, fmt.Errorf("db: %w", productbus.ErrInsufficientStock)
+		}
+		return productbus.Product{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusProduct(row)
+}
+
+func (s *Store) CreateMovement(ctx context.Context, movement productbus.Movement) error {
+	const q = `
+	INSERT INTO stock_movements
+		(stock_movement_id, product_id, kind, delta, quantity, actor_id, reference_id, reason, date_created)
+	VALUES
+		(:stock_movement_id, :product_id, :kind, :delta, :quantity, :actor_id, :reference_id, :reason, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBMovement(movement)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryMovements(ctx context.Context, filter productbus.MovementFilter, page page.Page) ([]productbus.Movement, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		stock_movement_id, product_id, kind, delta, quantity, actor_id, reference_id, reason, date_created
+	FROM
+		stock_movements`
+
+	buf := bytes.NewBufferString(q)
+	applyMovementFilter(filter, data, buf)
+
+	buf.WriteString(" ORDER BY date_created DESC")
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var rows []movementRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusMovements(rows)
+}
+
+func (s *Store) CountMovements(ctx context.Context, filter productbus.MovementFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		stock_movements`
+
+	buf := bytes.NewBufferString(q)
+	applyMovementFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+func (s *Store) QueryDiscrepancies(ctx context.Context) ([]productbus.Discrepancy, error) {
+	const q = `
+	SELECT
+		p.product_id, p.quantity, COALESCE(SUM(m.delta), 0) AS movement_total
+	FROM
+		products p
+	LEFT JOIN
+		stock_movements m ON m.product_id = p.product_id
+	GROUP BY
+		p.product_id, p.quantity
+	HAVING
+		p.quantity <> COALESCE(SUM(m.delta), 0)`
+
+	var rows []discrepancyRow
+	if err := sqldb.QuerySlice(ctx, s.log, s.db, q, &rows); err != nil {
+		return nil, fmt.Errorf("queryslice: %w", err)
+	}
+
+	return toBusDiscrepancies(rows), nil
+}
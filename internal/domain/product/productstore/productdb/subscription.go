@@ -0,0 +1,80 @@
+package productdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+func (s *Store) CreateSubscription(ctx context.Context, sub productbus.Subscription) error {
+	const q = `
+	INSERT INTO stock_subscriptions
+		(subscription_id, product_id, user_id, date_created, date_notified)
+	VALUES
+		(:subscription_id, :product_id, :user_id, :date_created, :date_notified)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBSubscription(sub)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", productbus.ErrSubscribed)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteSubscription(ctx context.Context, productID uuid.UUID, userID uuid.UUID) error {
+	data := struct {
+		ProductID uuid.UUID `db:"product_id"`
+		UserID    uuid.UUID `db:"user_id"`
+	}{
+		ProductID: productID,
+		UserID:    userID,
+	}
+
+	const q = `
+	DELETE FROM
+		stock_subscriptions
+	WHERE
+		product_id = :product_id AND user_id = :user_id AND date_notified IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// CloseSubscriptions marks every open subscription of the product as notified
+// and returns them. A subscription can only be closed once.
+func (s *Store) CloseSubscriptions(ctx context.Context, productID uuid.UUID, dateNotified time.Time) ([]productbus.Subscription, error) {
+	data := struct {
+		ProductID    uuid.UUID `db:"product_id"`
+		DateNotified time.Time `db:"date_notified"`
+	}{
+		ProductID:    productID,
+		DateNotified: dateNotified.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		stock_subscriptions
+	SET
+		"date_notified" = :date_notified
+	WHERE
+		product_id = :product_id AND date_notified IS NULL
+	RETURNING
+		subscription_id, product_id, user_id, date_created, date_notified`
+
+	var rows []subscriptionRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, q, data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusSubscriptions(rows), nil
+}
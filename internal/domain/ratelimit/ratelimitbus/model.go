@@ -0,0 +1,37 @@
+package ratelimitbus
+
+import (
+	"time"
+)
+
+// Bucket represents the tokens a client has left for a policy, a request
+// takes a token and they come back as time passes.
+type Bucket struct {
+	Key         string
+	Tokens      float64
+	DateUpdated time.Time
+}
+
+// Policy represents the requests allowed to a client. Limit requests can be
+// made at once and the tokens come back evenly over Period.
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// Unlimited reports whether the policy lets every request through.
+func (p Policy) Unlimited() bool {
+	return p.Limit <= 0 || p.Period <= 0
+}
+
+// Result represents the outcome of taking a token. Reset is how long it
+// takes the bucket to be full again, RetryAfter how long a denied client has
+// to wait for a token.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
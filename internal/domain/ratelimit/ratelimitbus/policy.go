@@ -0,0 +1,80 @@
+package ratelimitbus
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePolicy parses a policy written like 100/1m, a hundred requests a
+// minute. A policy of 0 is unlimited.
+func ParsePolicy(name string, s string) (Policy, error) {
+	s = strings.TrimSpace(s)
+	if s == "0" {
+		return Policy{Name: name}, nil
+	}
+
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("policy %q: should look like 100/1m", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return Policy{}, fmt.Errorf("policy %q: invalid limit %q", s, limit)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("policy %q: invalid period %q", s, period)
+	}
+
+	return Policy{Name: name, Limit: n, Period: d}, nil
+}
+
+// take takes a token from the bucket at the time now and returns the new
+// state of the bucket. The tokens that came back since the last update are
+// added first, a new bucket is full.
+func take(b Bucket, now time.Time, p Policy) (Bucket, Result) {
+	var (
+		limit = float64(p.Limit)
+		rate  = limit / p.Period.Seconds()
+	)
+
+	switch {
+	case b.DateUpdated.IsZero():
+		b.Tokens = limit
+
+	// The clocks of the instances sharing a bucket can be a little apart,
+	// time never goes back for a bucket.
+	case now.After(b.DateUpdated):
+		b.Tokens = min(limit, b.Tokens+now.Sub(b.DateUpdated).Seconds()*rate)
+
+	default:
+		now = b.DateUpdated
+	}
+
+	b.DateUpdated = now
+
+	res := Result{
+		Limit: p.Limit,
+	}
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.Tokens) / rate)
+	}
+
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.Reset = seconds((limit - b.Tokens) / rate)
+
+	return b, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,115 @@
+package ratelimitbus
+
+import (
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Name:   "test",
+	Limit:  3,
+	Period: time.Minute,
+}
+
+func Test_Take(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("burst then denied", func(t *testing.T) {
+		var (
+			b   Bucket
+			res Result
+		)
+		for i := 0; i < testPolicy.Limit; i++ {
+			b, res = take(b, start, testPolicy)
+			if !res.Allowed {
+				t.Fatalf("Should allow request %d of the burst", i+1)
+			}
+			if want := testPolicy.Limit - i - 1; res.Remaining != want {
+				t.Fatalf("Should have %d remaining after request %d, got %d", want, i+1, res.Remaining)
+			}
+		}
+
+		b, res = take(b, start, testPolicy)
+		if res.Allowed {
+			t.Fatalf("Should deny a request once the burst is spent")
+		}
+		if res.RetryAfter != 20*time.Second {
+			t.Fatalf("Should retry once a token is back in 20s, got %s", res.RetryAfter)
+		}
+		if res.Reset != time.Minute {
+			t.Fatalf("Should be full again in a minute, got %s", res.Reset)
+		}
+		if b.Tokens != 0 {
+			t.Fatalf("Should not take a token on a denied request, got %f tokens", b.Tokens)
+		}
+	})
+
+	t.Run("tokens come back", func(t *testing.T) {
+		b := Bucket{Tokens: 0, DateUpdated: start}
+
+		b, res := take(b, start.Add(10*time.Second), testPolicy)
+		if res.Allowed {
+			t.Fatalf("Should deny a request before a token is back")
+		}
+		if res.RetryAfter != 10*time.Second {
+			t.Fatalf("Should retry in 10s, got %s", res.RetryAfter)
+		}
+
+		_, res = take(b, start.Add(20*time.Second), testPolicy)
+		if !res.Allowed {
+			t.Fatalf("Should allow a request once a token is back")
+		}
+	})
+
+	t.Run("capped at limit", func(t *testing.T) {
+		b := Bucket{Tokens: 0, DateUpdated: start}
+
+		b, res := take(b, start.Add(time.Hour), testPolicy)
+		if !res.Allowed || res.Remaining != testPolicy.Limit-1 {
+			t.Fatalf("Should never hold more than the limit, got %d remaining", res.Remaining)
+		}
+		if b.Tokens > float64(testPolicy.Limit) {
+			t.Fatalf("Should never hold more than the limit, got %f tokens", b.Tokens)
+		}
+	})
+
+	t.Run("clock behind", func(t *testing.T) {
+		b := Bucket{Tokens: 0.5, DateUpdated: start}
+
+		b, res := take(b, start.Add(-time.Minute), testPolicy)
+		if res.Allowed {
+			t.Fatalf("Should not give tokens back when the clock is behind")
+		}
+		if !b.DateUpdated.Equal(start) {
+			t.Fatalf("Should keep the time of the bucket, got %s", b.DateUpdated)
+		}
+	})
+}
+
+func Test_ParsePolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{in: "100/1m", want: Policy{Name: "p", Limit: 100, Period: time.Minute}},
+		{in: " 5/1h ", want: Policy{Name: "p", Limit: 5, Period: time.Hour}},
+		{in: "0", want: Policy{Name: "p"}},
+		{in: "100", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/minute", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePolicy("p", tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolicy(%q) error = %v, want error %t", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
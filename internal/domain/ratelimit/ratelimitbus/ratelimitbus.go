@@ -0,0 +1,69 @@
+// Package ratelimitbus provides business access to the rate limits. Clients
+// get a token bucket for every policy, a request takes a token and the
+// tokens come back evenly over time.
+package ratelimitbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	// Update applies fn to the bucket of the key and saves what it returns,
+	// nobody else changes the bucket in between. A key without a bucket is
+	// given an empty one.
+	Update(ctx context.Context, key string, fn func(Bucket) Bucket) error
+	// Prune deletes the buckets not updated since before.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// Business manages the set of APIs for rate limit access.
+type Business struct {
+	log    *logger.Logger
+	storer Storer
+}
+
+// NewBusiness constructs a rate limit business API for use.
+func NewBusiness(log *logger.Logger, storer Storer) *Business {
+	return &Business{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// Take takes a token from the bucket of the client for the policy, the
+// request of the client is allowed when there was one.
+func (b *Business) Take(ctx context.Context, p Policy, client string) (Result, error) {
+	if p.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+
+	now := time.Now()
+	key := p.Name + "|" + client
+
+	var res Result
+	err := b.storer.Update(ctx, key, func(bkt Bucket) Bucket {
+		bkt, res = take(bkt, now, p)
+		return bkt
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("update: key[%s]: %w", key, err)
+	}
+
+	return res, nil
+}
+
+// Prune deletes the buckets idle for longer than idle. It should be at least
+// the longest period of the policies, the buckets are full again by then and
+// the same as no bucket.
+func (b *Business) Prune(ctx context.Context, idle time.Duration) error {
+	if err := b.storer.Prune(ctx, time.Now().Add(-idle)); err != nil {
+		return fmt.Errorf("prune: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package ratelimitdb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitbus"
+)
+
+type bucketRow struct {
+	Key         string       `db:"bucket_key"`
+	Tokens      float64      `db:"tokens"`
+	DateUpdated sql.NullTime `db:"date_updated"`
+}
+
+func toDBBucket(bus ratelimitbus.Bucket) bucketRow {
+	return bucketRow{
+		Key:         bus.Key,
+		Tokens:      bus.Tokens,
+		DateUpdated: toNullTime(bus.DateUpdated),
+	}
+}
+
+func toBusBucket(row bucketRow) ratelimitbus.Bucket {
+	return ratelimitbus.Bucket{
+		Key:         row.Key,
+		Tokens:      row.Tokens,
+		DateUpdated: toTime(row.DateUpdated),
+	}
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+func toTime(nt sql.NullTime) time.Time {
+	if !nt.Valid {
+		return time.Time{}
+	}
+
+	return nt.Time.UTC()
+}
@@ -0,0 +1,111 @@
+// Package ratelimitdb contains rate limit buckets related CRUD functionality,
+// the buckets are shared by every instance of the service.
+package ratelimitdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  *sqlx.DB
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Update applies fn to the bucket of the key. The row is locked until it is
+// saved so concurrent requests on several instances are all counted.
+func (s *Store) Update(ctx context.Context, key string, fn func(ratelimitbus.Bucket) ratelimitbus.Bucket) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	data := struct {
+		Key string `db:"bucket_key"`
+	}{
+		Key: key,
+	}
+
+	const ins = `
+	INSERT INTO rate_limits
+		(bucket_key)
+	VALUES
+		(:bucket_key)
+	ON CONFLICT (bucket_key) DO NOTHING`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, tx, ins, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	const q = `
+	SELECT
+		bucket_key, tokens, date_updated
+	FROM
+		rate_limits
+	WHERE
+		bucket_key = :bucket_key
+	FOR UPDATE`
+
+	var row bucketRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, tx, q, data, &row); err != nil {
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	bkt := fn(toBusBucket(row))
+
+	const upd = `
+	UPDATE
+		rate_limits
+	SET
+		"tokens" = :tokens,
+		"date_updated" = :date_updated
+	WHERE
+		bucket_key = :bucket_key`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, tx, upd, toDBBucket(bkt)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
+
+// Prune deletes the buckets not updated since before.
+func (s *Store) Prune(ctx context.Context, before time.Time) error {
+	data := struct {
+		Before time.Time `db:"before"`
+	}{
+		Before: before.UTC(),
+	}
+
+	const q = `
+	DELETE FROM
+		rate_limits
+	WHERE
+		date_updated < :before OR date_updated IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
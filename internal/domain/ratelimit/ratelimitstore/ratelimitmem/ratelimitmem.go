@@ -0,0 +1,56 @@
+// Package ratelimitmem keeps the rate limit buckets in memory, every instance
+// of the service counts the requests it handles on its own.
+package ratelimitmem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitbus"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for memory access.
+type Store struct {
+	log     *logger.Logger
+	mu      sync.Mutex
+	buckets map[string]ratelimitbus.Bucket
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger) *Store {
+	return &Store{
+		log:     log,
+		buckets: make(map[string]ratelimitbus.Bucket),
+	}
+}
+
+// Update applies fn to the bucket of the key.
+func (s *Store) Update(ctx context.Context, key string, fn func(ratelimitbus.Bucket) ratelimitbus.Bucket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bkt, exists := s.buckets[key]
+	if !exists {
+		bkt = ratelimitbus.Bucket{Key: key}
+	}
+
+	s.buckets[key] = fn(bkt)
+
+	return nil
+}
+
+// Prune deletes the buckets not updated since before.
+func (s *Store) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, bkt := range s.buckets {
+		if bkt.DateUpdated.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
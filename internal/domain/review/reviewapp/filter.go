@@ -0,0 +1,51 @@
+package reviewapp
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+)
+
+func parseFilter(qp queryParams) (reviewbus.QueryFilter, error) {
+	var filter reviewbus.QueryFilter
+
+	if qp.ProductID != "" {
+		id, err := uuid.Parse(qp.ProductID)
+		if err != nil {
+			return reviewbus.QueryFilter{}, fmt.Errorf("parse product_id: %w", err)
+		}
+		filter.ProductID = &id
+	}
+
+	if qp.UserID != "" {
+		id, err := uuid.Parse(qp.UserID)
+		if err != nil {
+			return reviewbus.QueryFilter{}, fmt.Errorf("parse user_id: %w", err)
+		}
+		filter.UserID = &id
+	}
+
+	if qp.Status != "" {
+		status, err := reviewbus.ParseStatus(qp.Status)
+		if err != nil {
+			return reviewbus.QueryFilter{}, fmt.Errorf("parse status: %w", err)
+		}
+		filter.Status = &status
+	}
+
+	if qp.MinRating != "" {
+		v, err := strconv.Atoi(qp.MinRating)
+		if err != nil {
+			return reviewbus.QueryFilter{}, fmt.Errorf("parse min_rating: %w", err)
+		}
+		rating, err := reviewbus.ParseRating(v)
+		if err != nil {
+			return reviewbus.QueryFilter{}, fmt.Errorf("parse min_rating: %w", err)
+		}
+		filter.MinRating = &rating
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,127 @@
+package reviewapp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+)
+
+// =============================================================================
+// Query params
+
+// queryParams represents the set of possible query strings.
+type queryParams struct {
+	Page      string
+	Rows      string
+	SortBy    string
+	ProductID string
+	UserID    string
+	Status    string
+	MinRating string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:      values.Get("page"),
+		Rows:      values.Get("row"),
+		SortBy:    values.Get("sort_by"),
+		ProductID: values.Get("product_id"),
+		UserID:    values.Get("user_id"),
+		Status:    values.Get("status"),
+		MinRating: values.Get("min_rating"),
+	}
+
+	return filter
+}
+
+// =============================================================================
+
+// review represents a rating and comment a user left on a product.
+type review struct {
+	ID          string `json:"id"`
+	ProductID   string `json:"product_id"`
+	UserID      string `json:"user_id"`
+	Rating      int    `json:"rating"`
+	Comment     string `json:"comment"`
+	Status      string `json:"status"`
+	DateCreated string `json:"date_created"`
+	DateUpdated string `json:"date_updated"`
+}
+
+func toAppReview(bus reviewbus.Review) review {
+	return review{
+		ID:          bus.ID.String(),
+		ProductID:   bus.ProductID.String(),
+		UserID:      bus.UserID.String(),
+		Rating:      bus.Rating.Int(),
+		Comment:     bus.Comment,
+		Status:      bus.Status.String(),
+		DateCreated: bus.DateCreated.Format(time.RFC3339),
+		DateUpdated: bus.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppReviews(reviews []reviewbus.Review) []review {
+	app := make([]review, len(reviews))
+	for i, rvw := range reviews {
+		app[i] = toAppReview(rvw)
+	}
+
+	return app
+}
+
+// =============================================================================
+
+type newReviewReq struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+func toBusNewReview(app newReviewReq) (reviewbus.NewReview, error) {
+	rating, err := reviewbus.ParseRating(app.Rating)
+	if err != nil {
+		return reviewbus.NewReview{}, fmt.Errorf("parse: %w", err)
+	}
+
+	bus := reviewbus.NewReview{
+		Rating:  rating,
+		Comment: app.Comment,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type updateReviewReq struct {
+	Rating  *int    `json:"rating" binding:"omitempty,min=1,max=5"`
+	Comment *string `json:"comment" binding:"omitempty,max=2000"`
+}
+
+func toBusUpdateReview(app updateReviewReq) (reviewbus.UpdateReview, error) {
+	var rating *reviewbus.Rating
+	if app.Rating != nil {
+		r, err := reviewbus.ParseRating(*app.Rating)
+		if err != nil {
+			return reviewbus.UpdateReview{}, fmt.Errorf("parse: %w", err)
+		}
+		rating = &r
+	}
+
+	bus := reviewbus.UpdateReview{
+		Rating:  rating,
+		Comment: app.Comment,
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type moderateReviewReq struct {
+	Status string `json:"status" binding:"required,oneof=APPROVED HIDDEN"`
+}
@@ -0,0 +1,309 @@
+// Package reviewapp maintains the app layer.
+package reviewapp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/query"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+type app struct {
+	log        *logger.Logger
+	auth       *auth.Auth
+	dbBeginner sqldb.Beginner
+	reviewBus  *reviewbus.Business
+	productBus *productbus.Business
+}
+
+func New(
+	log *logger.Logger,
+	auth *auth.Auth,
+	dbBeginner sqldb.Beginner,
+	reviewBus *reviewbus.Business,
+	productBus *productbus.Business,
+) *app {
+	return &app{
+		log:        log,
+		auth:       auth,
+		dbBeginner: dbBeginner,
+		reviewBus:  reviewBus,
+		productBus: productBus,
+	}
+}
+
+// newWithTx constructs a new app value using a store transaction that was created via middleware.
+func (a *app) newWithTx(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewBusTx, err := a.reviewBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	productBusTx, err := a.productBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	app := app{
+		log:        a.log,
+		auth:       a.auth,
+		dbBeginner: a.dbBeginner,
+		reviewBus:  reviewBusTx,
+		productBus: productBusTx,
+	}
+
+	return &app, nil
+}
+
+func (a *app) createHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req newReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	newReview, err := toBusNewReview(req)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	userID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if _, err := a.productBus.QueryByID(ctx, productID); err != nil {
+		if errors.Is(err, productbus.ErrNotFound) {
+			respond.Error(c, a.log, errs.Newf(errs.NotFound, "product productID[%s] not found", productID))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "query product: productID[%s]: %s", productID, err))
+		}
+		return
+	}
+
+	newReview.ProductID = productID
+	newReview.UserID = userID
+
+	rvw, err := a.reviewBus.Create(ctx, newReview)
+	if err != nil {
+		switch {
+		case errors.Is(err, reviewbus.ErrNotVerifiedBuyer):
+			respond.Error(c, a.log, errs.New(errs.PermissionDenied, err))
+		case errors.Is(err, reviewbus.ErrAlreadyReviewed):
+			respond.Error(c, a.log, errs.New(errs.Aborted, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "create: req[%+v]: %s", req, err))
+		}
+		return
+	}
+
+	respond.Success(c, a.log, toAppReview(rvw))
+}
+
+func (a *app) queryProductReviewsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid productID: %s", err))
+		return
+	}
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	// only approved reviews are public
+	approved := reviewbus.Statuses.Approved
+	filter.ProductID = &productID
+	filter.Status = &approved
+	filter.UserID = nil
+
+	sortBy, err := sort.Parse(sortByFields, qp.SortBy, defaultSortBy)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	reviews, err := a.reviewBus.Query(ctx, filter, sortBy, page)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "query: %s", err))
+		return
+	}
+
+	total, err := a.reviewBus.Count(ctx, filter)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "count: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, query.NewResult(toAppReviews(reviews), total, page))
+}
+
+func (a *app) queryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	sortBy, err := sort.Parse(sortByFields, qp.SortBy, defaultSortBy)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	reviews, err := a.reviewBus.Query(ctx, filter, sortBy, page)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "query: %s", err))
+		return
+	}
+
+	total, err := a.reviewBus.Count(ctx, filter)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "count: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, query.NewResult(toAppReviews(reviews), total, page))
+}
+
+func (a *app) updateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req updateReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	updateReview, err := toBusUpdateReview(req)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	rvw, err := mid.GetReview(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	updatedReview, err := a.reviewBus.Update(ctx, rvw, updateReview)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "update: reviewID[%s] req[%+v]: %s", rvw.ID, req, err))
+		return
+	}
+
+	respond.Success(c, a.log, toAppReview(updatedReview))
+}
+
+func (a *app) moderateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req moderateReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	status, err := reviewbus.ParseStatus(req.Status)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.InvalidArgument, "invalid status: %s", err))
+		return
+	}
+
+	rvw, err := mid.GetReview(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	updatedReview, err := a.reviewBus.Moderate(ctx, rvw, status)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "moderate: reviewID[%s]: %s", rvw.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, toAppReview(updatedReview))
+}
+
+func (a *app) deleteHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	rvw, err := mid.GetReview(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if err := a.reviewBus.Delete(ctx, rvw); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "delete: reviewID[%s]: %s", rvw.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
@@ -0,0 +1,24 @@
+package reviewapp
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+)
+
+func (a *app) Routes(r gin.IRouter) {
+	authenticate := mid.Authenticate(a.log, a.auth)
+	requireUser := mid.RequireUser(a.log)
+	reviewRead := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.ReviewRead))
+	reviewOwner := mid.AuthorizeReview(a.log, a.auth, a.reviewBus, auth.Owner())
+	ownerOrReviewDelete := mid.AuthorizeReview(a.log, a.auth, a.reviewBus, auth.OwnerOr(auth.Permissions.ReviewDelete))
+	reviewModerate := mid.AuthorizeReview(a.log, a.auth, a.reviewBus, auth.Require(auth.Permissions.ReviewModerate))
+	transaction := mid.BeginCommitRollback(a.log, a.dbBeginner)
+
+	r.POST("/products/:product_id/reviews", authenticate, requireUser, a.createHandler)
+	r.GET("/products/:product_id/reviews", a.queryProductReviewsHandler)
+	r.GET("/reviews", authenticate, reviewRead, a.queryHandler)
+	r.PUT("/reviews/:review_id", authenticate, reviewOwner, transaction, a.updateHandler)
+	r.PUT("/reviews/:review_id/status", authenticate, reviewModerate, transaction, a.moderateHandler)
+	r.DELETE("/reviews/:review_id", authenticate, ownerOrReviewDelete, transaction, a.deleteHandler)
+}
@@ -0,0 +1,13 @@
+package reviewapp
+
+import (
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+)
+
+var defaultSortBy = sort.NewBy("date_created", sort.DESC)
+
+var sortByFields = map[string]string{
+	"date_created": reviewbus.SortByDateCreated,
+	"rating":       reviewbus.SortByRating,
+}
@@ -0,0 +1,13 @@
+package reviewbus
+
+import (
+	"github.com/google/uuid"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+type QueryFilter struct {
+	ProductID *uuid.UUID
+	UserID    *uuid.UUID
+	Status    *Status
+	MinRating *Rating
+}
@@ -0,0 +1,39 @@
+package reviewbus
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Review represents a rating and comment a user left on a product.
+type Review struct {
+	ID          uuid.UUID
+	ProductID   uuid.UUID
+	UserID      uuid.UUID
+	Rating      Rating
+	Comment     string
+	Status      Status
+	DateCreated time.Time
+	DateUpdated time.Time
+}
+
+// NewReview contains information needed to create a new review.
+type NewReview struct {
+	ProductID uuid.UUID
+	UserID    uuid.UUID
+	Rating    Rating
+	Comment   string
+}
+
+// UpdateReview contains information needed to update a review.
+type UpdateReview struct {
+	Rating  *Rating
+	Comment *string
+}
+
+// Summary represents the aggregate of the approved reviews of a product.
+type Summary struct {
+	Average float64
+	Count   int32
+}
@@ -0,0 +1,47 @@
+package reviewbus
+
+import "fmt"
+
+// Set of limits for a rating.
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Rating represents the score given to a product in a review.
+type Rating struct {
+	value int
+}
+
+// Int returns the value of the rating.
+func (r Rating) Int() int {
+	return r.value
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Rating) Equal(r2 Rating) bool {
+	return r.value == r2.value
+}
+
+// =============================================================================
+
+// ParseRating parses the value and returns a rating if the value is within
+// the allowed range.
+func ParseRating(value int) (Rating, error) {
+	if value < MinRating || value > MaxRating {
+		return Rating{}, fmt.Errorf("invalid rating %d: must be between %d and %d", value, MinRating, MaxRating)
+	}
+
+	return Rating{value}, nil
+}
+
+// MustParseRating parses the value and returns a rating if the value is
+// within the allowed range. If an error occurs the function panics.
+func MustParseRating(value int) Rating {
+	rating, err := ParseRating(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return rating
+}
@@ -0,0 +1,218 @@
+// Package reviewbus provides business access to review domain.
+package reviewbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound         = errors.New("review not found")
+	ErrAlreadyReviewed  = errors.New("product already reviewed by user")
+	ErrNotVerifiedBuyer = errors.New("user has not bought the product")
+)
+
+// Storer interface declares the behavior this package needs to perists and retrieve data.
+type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
+	Create(ctx context.Context, review Review) error
+	Update(ctx context.Context, review Review) error
+	Delete(ctx context.Context, review Review) error
+	Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]Review, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, reviewID uuid.UUID) (Review, error)
+	HasPurchased(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error)
+	QuerySummary(ctx context.Context, productID uuid.UUID) (Summary, error)
+}
+
+// Business manages the set of APIs for review access.
+type Business struct {
+	log        *logger.Logger
+	storer     Storer
+	productBus *productbus.Business
+}
+
+// NewBusiness constructs a business API for use.
+func NewBusiness(log *logger.Logger, storer Storer, productBus *productbus.Business) *Business {
+	return &Business{
+		log:        log,
+		storer:     storer,
+		productBus: productBus,
+	}
+}
+
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	productBusTx, err := b.productBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:        b.log,
+		storer:     storerTx,
+		productBus: productBusTx,
+	}
+
+	return &bus, nil
+}
+
+// Create adds a new review waiting for moderation. Only users with a
+// delivered or finished order containing the product can review it.
+func (b *Business) Create(ctx context.Context, newReview NewReview) (Review, error) {
+	purchased, err := b.storer.HasPurchased(ctx, newReview.UserID, newReview.ProductID)
+	if err != nil {
+		return Review{}, fmt.Errorf("has purchased: %w", err)
+	}
+
+	if !purchased {
+		return Review{}, fmt.Errorf("userID[%s] productID[%s]: %w", newReview.UserID, newReview.ProductID, ErrNotVerifiedBuyer)
+	}
+
+	now := time.Now()
+
+	review := Review{
+		ID:          uuid.New(),
+		ProductID:   newReview.ProductID,
+		UserID:      newReview.UserID,
+		Rating:      newReview.Rating,
+		Comment:     newReview.Comment,
+		Status:      Statuses.Pending,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	if err := b.storer.Create(ctx, review); err != nil {
+		return Review{}, fmt.Errorf("create: %w", err)
+	}
+
+	return review, nil
+}
+
+// Update modifies the review. An edited review goes back to moderation.
+func (b *Business) Update(ctx context.Context, review Review, updateReview UpdateReview) (Review, error) {
+	if updateReview.Rating != nil {
+		review.Rating = *updateReview.Rating
+	}
+
+	if updateReview.Comment != nil {
+		review.Comment = *updateReview.Comment
+	}
+
+	wasApproved := review.Status.Equal(Statuses.Approved)
+
+	review.Status = Statuses.Pending
+	review.DateUpdated = time.Now()
+
+	if err := b.storer.Update(ctx, review); err != nil {
+		return Review{}, fmt.Errorf("update: %w", err)
+	}
+
+	if wasApproved {
+		if err := b.refreshRating(ctx, review.ProductID); err != nil {
+			return Review{}, fmt.Errorf("refresh rating: %w", err)
+		}
+	}
+
+	return review, nil
+}
+
+// Moderate changes the moderation status of the review. Only approved
+// reviews count towards the product rating.
+func (b *Business) Moderate(ctx context.Context, review Review, status Status) (Review, error) {
+	if review.Status.Equal(status) {
+		return review, nil
+	}
+
+	review.Status = status
+	review.DateUpdated = time.Now()
+
+	if err := b.storer.Update(ctx, review); err != nil {
+		return Review{}, fmt.Errorf("update: %w", err)
+	}
+
+	if err := b.refreshRating(ctx, review.ProductID); err != nil {
+		return Review{}, fmt.Errorf("refresh rating: %w", err)
+	}
+
+	return review, nil
+}
+
+// Delete removes the review.
+func (b *Business) Delete(ctx context.Context, review Review) error {
+	if err := b.storer.Delete(ctx, review); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	if review.Status.Equal(Statuses.Approved) {
+		if err := b.refreshRating(ctx, review.ProductID); err != nil {
+			return fmt.Errorf("refresh rating: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (b *Business) Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]Review, error) {
+	reviews, err := b.storer.Query(ctx, filter, sortBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return reviews, nil
+}
+
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return b.storer.Count(ctx, filter)
+}
+
+func (b *Business) QueryByID(ctx context.Context, reviewID uuid.UUID) (Review, error) {
+	review, err := b.storer.QueryByID(ctx, reviewID)
+	if err != nil {
+		return Review{}, fmt.Errorf("query: reviewID[%s]: %w", reviewID, err)
+	}
+
+	return review, nil
+}
+
+// refreshRating recomputes the aggregated rating of the product from its
+// approved reviews. The product is locked first, so a concurrent change to
+// another review of the product waits for this transaction and computes the
+// rating with this review committed.
+func (b *Business) refreshRating(ctx context.Context, productID uuid.UUID) error {
+	product, err := b.productBus.QueryByIDForUpdate(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("query product: %w", err)
+	}
+
+	summary, err := b.storer.QuerySummary(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("query summary: %w", err)
+	}
+
+	rating := productbus.Rating{
+		Average: summary.Average,
+		Count:   summary.Count,
+	}
+
+	if _, err := b.productBus.UpdateRating(ctx, product, rating); err != nil {
+		return fmt.Errorf("update rating: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package reviewbus
+
+import "fmt"
+
+type statusSet struct {
+	Pending  Status
+	Approved Status
+	Hidden   Status
+}
+
+// Statuses represents the set of moderation states a review can be in.
+var Statuses = statusSet{
+	Pending:  newStatus("PENDING"),
+	Approved: newStatus("APPROVED"),
+	Hidden:   newStatus("HIDDEN"),
+}
+
+// =============================================================================
+
+var statuses = make(map[string]Status)
+
+// Status represents the moderation state of a review.
+type Status struct {
+	name string
+}
+
+func newStatus(status string) Status {
+	s := Status{status}
+	statuses[status] = s
+	return s
+}
+
+// String returns the name of the status.
+func (s Status) String() string {
+	return s.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (s Status) Equal(s2 Status) bool {
+	return s.name == s2.name
+}
+
+// =============================================================================
+
+// ParseStatus parses the string value and returns a status if one exists.
+func ParseStatus(value string) (Status, error) {
+	status, exists := statuses[value]
+	if !exists {
+		return Status{}, fmt.Errorf("invalid status %q", value)
+	}
+
+	return status, nil
+}
+
+// MustParseStatus parses the string value and returns a status if one exists.
+// If an error occurs the function panics.
+func MustParseStatus(value string) Status {
+	status, err := ParseStatus(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return status
+}
@@ -0,0 +1,37 @@
+package reviewdb
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+)
+
+func applyFilter(filter reviewbus.QueryFilter, data map[string]any, buf *bytes.Buffer) {
+	var wc []string
+
+	if filter.ProductID != nil {
+		data["product_id"] = filter.ProductID.String()
+		wc = append(wc, "product_id = :product_id")
+	}
+
+	if filter.UserID != nil {
+		data["user_id"] = filter.UserID.String()
+		wc = append(wc, "user_id = :user_id")
+	}
+
+	if filter.Status != nil {
+		data["status"] = filter.Status.String()
+		wc = append(wc, "status = :status")
+	}
+
+	if filter.MinRating != nil {
+		data["min_rating"] = filter.MinRating.Int()
+		wc = append(wc, "rating >= :min_rating")
+	}
+
+	if len(wc) > 0 {
+		buf.WriteString(" WHERE ")
+		buf.WriteString(strings.Join(wc, " AND "))
+	}
+}
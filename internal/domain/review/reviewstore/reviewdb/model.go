@@ -0,0 +1,87 @@
+package reviewdb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+)
+
+type reviewRow struct {
+	ID          uuid.UUID      `db:"review_id"`
+	ProductID   uuid.UUID      `db:"product_id"`
+	UserID      uuid.UUID      `db:"user_id"`
+	Rating      int            `db:"rating"`
+	Comment     sql.NullString `db:"comment"`
+	Status      string         `db:"status"`
+	DateCreated time.Time      `db:"date_created"`
+	DateUpdated time.Time      `db:"date_updated"`
+}
+
+func toDBReview(bus reviewbus.Review) reviewRow {
+	return reviewRow{
+		ID:          bus.ID,
+		ProductID:   bus.ProductID,
+		UserID:      bus.UserID,
+		Rating:      bus.Rating.Int(),
+		Comment:     sql.NullString{String: bus.Comment, Valid: bus.Comment != ""},
+		Status:      bus.Status.String(),
+		DateCreated: bus.DateCreated.UTC(),
+		DateUpdated: bus.DateUpdated.UTC(),
+	}
+}
+
+func toBusReview(row reviewRow) (reviewbus.Review, error) {
+	rating, err := reviewbus.ParseRating(row.Rating)
+	if err != nil {
+		return reviewbus.Review{}, fmt.Errorf("parse rating: %w", err)
+	}
+
+	status, err := reviewbus.ParseStatus(row.Status)
+	if err != nil {
+		return reviewbus.Review{}, fmt.Errorf("parse status: %w", err)
+	}
+
+	bus := reviewbus.Review{
+		ID:          row.ID,
+		ProductID:   row.ProductID,
+		UserID:      row.UserID,
+		Rating:      rating,
+		Comment:     row.Comment.String,
+		Status:      status,
+		DateCreated: row.DateCreated.UTC(),
+		DateUpdated: row.DateUpdated.UTC(),
+	}
+
+	return bus, nil
+}
+
+func toBusReviews(rows []reviewRow) ([]reviewbus.Review, error) {
+	bus := make([]reviewbus.Review, len(rows))
+
+	for i, row := range rows {
+		var err error
+		bus[i], err = toBusReview(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type summaryRow struct {
+	Average float64 `db:"average"`
+	Count   int32   `db:"count"`
+}
+
+func toBusSummary(row summaryRow) reviewbus.Summary {
+	return reviewbus.Summary{
+		Average: row.Average,
+		Count:   row.Count,
+	}
+}
@@ -0,0 +1,244 @@
+// Package reviewdb contains review related CRUD functionality.
+package reviewdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Store manages the set of APIs for database access.
+type Store struct {
+	log *logger.Logger
+	db  sqlx.ExtContext
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (reviewbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
+func (s *Store) Create(ctx context.Context, review reviewbus.Review) error {
+	const q = `
+	INSERT INTO reviews
+		(review_id, product_id, user_id, rating, comment, status, date_created, date_updated)
+	VALUES
+		(:review_id, :product_id, :user_id, :rating, :comment, :status, :date_created, :date_updated)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBReview(review)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", reviewbus.ErrAlreadyReviewed)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Update(ctx context.Context, review reviewbus.Review) error {
+	const q = `
+	UPDATE
+		reviews
+	SET
+		"rating" = :rating,
+		"comment" = :comment,
+		"status" = :status,
+		"date_updated" = :date_updated
+	WHERE
+		review_id = :review_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBReview(review)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, review reviewbus.Review) error {
+	const q = `
+	DELETE FROM
+		reviews
+	WHERE
+		review_id = :review_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBReview(review)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Query(ctx context.Context, filter reviewbus.QueryFilter, sortBy sort.By, page page.Page) ([]reviewbus.Review, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+		review_id, product_id, user_id, rating, comment, status, date_created, date_updated
+	FROM
+		reviews`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(sortBy)
+	if err != nil {
+		return nil, err
+	}
+
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var rows []reviewRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusReviews(rows)
+}
+
+func (s *Store) Count(ctx context.Context, filter reviewbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		reviews`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+func (s *Store) QueryByID(ctx context.Context, reviewID uuid.UUID) (reviewbus.Review, error) {
+	data := struct {
+		ID string `db:"review_id"`
+	}{
+		ID: reviewID.String(),
+	}
+
+	const q = `
+	SELECT
+		review_id, product_id, user_id, rating, comment, status, date_created, date_updated
+	FROM
+		reviews
+	WHERE
+		review_id = :review_id`
+
+	var row reviewRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return reviewbus.Review{}, fmt.Errorf("db: %w", reviewbus.ErrNotFound)
+		}
+		return reviewbus.Review{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusReview(row)
+}
+
+// HasPurchased reports whether the user has a delivered or finished order
+// containing the product.
+func (s *Store) HasPurchased(ctx context.Context, userID uuid.UUID, productID uuid.UUID) (bool, error) {
+	data := struct {
+		UserID    string `db:"user_id"`
+		ProductID string `db:"product_id"`
+		Delivered string `db:"delivered"`
+		Finished  string `db:"finished"`
+	}{
+		UserID:    userID.String(),
+		ProductID: productID.String(),
+		Delivered: orderbus.Statuses.Delivered.String(),
+		Finished:  orderbus.Statuses.Finished.String(),
+	}
+
+	const q = `
+	SELECT
+		EXISTS (
+			SELECT
+				1
+			FROM
+				orders o
+			JOIN
+				order_items oi ON oi.order_id = o.order_id
+			WHERE
+				o.user_id = :user_id AND
+				oi.product_id = :product_id AND
+				o.status IN (:delivered, :finished)
+		) AS purchased`
+
+	var result struct {
+		Purchased bool `db:"purchased"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		return false, fmt.Errorf("db: %w", err)
+	}
+
+	return result.Purchased, nil
+}
+
+// QuerySummary computes the average rating and the number of approved
+// reviews of the product.
+func (s *Store) QuerySummary(ctx context.Context, productID uuid.UUID) (reviewbus.Summary, error) {
+	data := struct {
+		ProductID string `db:"product_id"`
+		Status    string `db:"status"`
+	}{
+		ProductID: productID.String(),
+		Status:    reviewbus.Statuses.Approved.String(),
+	}
+
+	const q = `
+	SELECT
+		COALESCE(ROUND(AVG(rating), 2), 0)::FLOAT8 AS average, count(1) AS count
+	FROM
+		reviews
+	WHERE
+		product_id = :product_id AND status = :status`
+
+	var row summaryRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		return reviewbus.Summary{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusSummary(row), nil
+}
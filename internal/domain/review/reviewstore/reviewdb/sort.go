@@ -0,0 +1,22 @@
+package reviewdb
+
+import (
+	"fmt"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+)
+
+var sortByFields = map[string]string{
+	reviewbus.SortByDateCreated: "date_created",
+	reviewbus.SortByRating:      "rating",
+}
+
+func orderByClause(sortBy sort.By) (string, error) {
+	by, exists := sortByFields[sortBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", sortBy.Field)
+	}
+
+	return " ORDER BY " + by + " " + sortBy.Direction, nil
+}
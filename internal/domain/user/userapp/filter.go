@@ -0,0 +1,50 @@
+package userapp
+
+import (
+	"fmt"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"strconv"
+	"time"
+)
+
+func parseFilter(qp queryParams) (userbus.QueryFilter, error) {
+	var filter userbus.QueryFilter
+
+	if qp.Email != "" {
+		filter.Email = &qp.Email
+	}
+
+	if qp.Role != "" {
+		role, err := userbus.ParseRole(qp.Role)
+		if err != nil {
+			return userbus.QueryFilter{}, fmt.Errorf("parse role: %w", err)
+		}
+		filter.Role = &role
+	}
+
+	if qp.Enabled != "" {
+		enabled, err := strconv.ParseBool(qp.Enabled)
+		if err != nil {
+			return userbus.QueryFilter{}, fmt.Errorf("parse enabled: %w", err)
+		}
+		filter.Enabled = &enabled
+	}
+
+	if qp.StartCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.StartCreatedDate)
+		if err != nil {
+			return userbus.QueryFilter{}, fmt.Errorf("parse start_created_date: %w", err)
+		}
+		filter.StartCreatedDate = &t
+	}
+
+	if qp.EndCreatedDate != "" {
+		t, err := time.Parse(time.RFC3339, qp.EndCreatedDate)
+		if err != nil {
+			return userbus.QueryFilter{}, fmt.Errorf("parse end_created_date: %w", err)
+		}
+		filter.EndCreatedDate = &t
+	}
+
+	return filter, nil
+}
@@ -2,39 +2,99 @@ package userapp
 
 import (
 	"fmt"
-	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfabus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"net/http"
 	"net/mail"
 	"time"
 )
 
+// queryParams represents the set of possible query strings.
+type queryParams struct {
+	Page             string
+	Rows             string
+	SortBy           string
+	Email            string
+	Role             string
+	Enabled          string
+	StartCreatedDate string
+	EndCreatedDate   string
+}
+
+func parseQueryParams(r *http.Request) queryParams {
+	values := r.URL.Query()
+
+	filter := queryParams{
+		Page:             values.Get("page"),
+		Rows:             values.Get("row"),
+		SortBy:           values.Get("sort_by"),
+		Email:            values.Get("email"),
+		Role:             values.Get("role"),
+		Enabled:          values.Get("enabled"),
+		StartCreatedDate: values.Get("start_created_date"),
+		EndCreatedDate:   values.Get("end_created_date"),
+	}
+
+	return filter
+}
+
 // =============================================================================
 
 type user struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Email       string   `json:"email"`
-	Roles       []string `json:"roles"`
-	DateCreated string   `json:"date_created"`
-	DateUpdated string   `json:"date_updated"`
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Email          string   `json:"email"`
+	Roles          []string `json:"roles"`
+	Enabled        bool     `json:"enabled"`
+	EmailConfirmed bool     `json:"email_confirmed"`
+	Version        int      `json:"version"`
+	DateCreated    string   `json:"date_created"`
+	DateUpdated    string   `json:"date_updated"`
 }
 
 func toAppUser(bus userbus.User) user {
 	return user{
-		ID:          bus.ID.String(),
-		Name:        bus.Name.String(),
-		Email:       bus.Email.Address,
-		Roles:       userbus.ParseRolesToString(bus.Roles),
-		DateCreated: bus.DateCreated.Format(time.RFC3339),
-		DateUpdated: bus.DateUpdated.Format(time.RFC3339),
+		ID:             bus.ID.String(),
+		Name:           bus.Name.String(),
+		Email:          bus.Email.Address,
+		Roles:          userbus.ParseRolesToString(bus.Roles),
+		Enabled:        bus.Enabled,
+		EmailConfirmed: bus.EmailConfirmed,
+		Version:        bus.Version,
+		DateCreated:    bus.DateCreated.Format(time.RFC3339),
+		DateUpdated:    bus.DateUpdated.Format(time.RFC3339),
+	}
+}
+
+func toAppUsers(users []userbus.User) []user {
+	app := make([]user, len(users))
+	for i, usr := range users {
+		app[i] = toAppUser(usr)
 	}
+
+	return app
 }
 
 // =============================================================================
 
 type authenUser struct {
-	UserID string `json:"user_id"`
-	Token  string `json:"token"`
+	UserID           string `json:"user_id"`
+	Token            string `json:"token"`
+	ExpiresAt        string `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	MFASetupRequired bool   `json:"mfa_setup_required,omitempty"`
+}
+
+// mfaChallenge is returned by the login of a user with a second factor, the
+// challenge token is exchanged for a session with a code.
+type mfaChallenge struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresAt      string `json:"expires_at"`
+}
+
+type refreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // =============================================================================
@@ -58,11 +118,10 @@ func toBusRegisterUser(app registerReq) (userbus.NewUser, error) {
 	}
 
 	bus := userbus.NewUser{
-		Name:              name,
-		Email:             *addr,
-		Password:          app.Password,
-		Roles:             []userbus.Role{userbus.Roles.User},
-		EmailConfirmToken: uuid.NewString(),
+		Name:     name,
+		Email:    *addr,
+		Password: app.Password,
+		Roles:    []userbus.Role{userbus.Roles.User},
 	}
 
 	return bus, nil
@@ -70,6 +129,35 @@ func toBusRegisterUser(app registerReq) (userbus.NewUser, error) {
 
 // =============================================================================
 
+// confirmEmailReq defines the data needed to confirm the email of a user.
+type confirmEmailReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// resendConfirmationReq defines the data needed to send a new email
+// confirmation token.
+type resendConfirmationReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// =============================================================================
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type forgotPasswordResp struct {
+	Message string `json:"message"`
+}
+
+type resetPasswordReq struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"eqfield=Password"`
+}
+
+// =============================================================================
+
 // loginUser defines the data needed to login a user.
 type loginUser struct {
 	Email    string `json:"email" binding:"required,email"`
@@ -102,3 +190,47 @@ func toBusUpdateUser(app updateUserReq) (userbus.UpdateUser, error) {
 
 	return bus, nil
 }
+
+// =============================================================================
+
+// updateEnabledReq defines the data needed to enable or disable a user.
+type updateEnabledReq struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// updateRolesReq defines the data needed to change the roles of a user.
+type updateRolesReq struct {
+	Roles []string `json:"roles" binding:"required,min=1"`
+}
+
+// =============================================================================
+
+type mfaLoginReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// mfaCodeReq holds a one-time password or a recovery code.
+type mfaCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type mfaDisableReq struct {
+	Code string `json:"code"`
+}
+
+type mfaSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+func toAppMFASetup(bus mfabus.Setup) mfaSetup {
+	return mfaSetup{
+		Secret: bus.Secret,
+		URI:    bus.URI,
+	}
+}
+
+type recoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,192 @@
+package userapp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/clients/oidcclient"
+	"net/http"
+	"net/mail"
+	"time"
+)
+
+// oidcStateCookie is the cookie holding the state of a login in progress at
+// an identity provider.
+const oidcStateCookie = "oidc_state"
+
+// oidcStateTTL is how long the user has to log in at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState is what we need to remember between sending the user to the
+// provider and their return.
+type oidcState struct {
+	Provider  string    `json:"provider"`
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcAuthorizeHandler sends the user to the identity provider to log in.
+func (a *app) oidcAuthorizeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	name := c.Param("provider")
+	provider, exists := a.cfg.OIDCProviders[name]
+	if !exists {
+		respond.Error(c, a.log, errs.Newf(errs.NotFound, "unknown provider %q", name))
+		return
+	}
+
+	st := oidcState{
+		Provider:  name,
+		ExpiresAt: time.Now().Add(oidcStateTTL).UTC(),
+	}
+
+	var err error
+	if st.State, err = oidcclient.GenerateState(); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "generate state: %s", err))
+		return
+	}
+	if st.Nonce, err = oidcclient.GenerateState(); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "generate nonce: %s", err))
+		return
+	}
+	if st.Verifier, err = oidcclient.GenerateVerifier(); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "generate verifier: %s", err))
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Unavailable, "provider[%s]: %s", name, err))
+		return
+	}
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "marshal state: %s", err))
+		return
+	}
+
+	cookie, err := a.cfg.OIDCStateCipher.Encrypt(string(data))
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "encrypt state: %s", err))
+		return
+	}
+
+	// The provider sends the user back with a top level navigation, a lax
+	// cookie is sent along with it.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, int(oidcStateTTL.Seconds()), "/", "", a.cfg.OIDCSecureCookie, true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallbackHandler completes the login once the identity provider sends
+// the user back with an authorization code.
+func (a *app) oidcCallbackHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	name := c.Param("provider")
+	provider, exists := a.cfg.OIDCProviders[name]
+	if !exists {
+		respond.Error(c, a.log, errs.Newf(errs.NotFound, "unknown provider %q", name))
+		return
+	}
+
+	st, err := a.oidcState(c)
+
+	// The state is single use whatever the outcome.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/", "", a.cfg.OIDCSecureCookie, true)
+
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Unauthenticated, err))
+		return
+	}
+
+	if st.Provider != name || subtle.ConstantTimeCompare([]byte(st.State), []byte(c.Query("state"))) != 1 {
+		respond.Error(c, a.log, errs.New(errs.Unauthenticated, errors.New("state does not match")))
+		return
+	}
+
+	if reason := c.Query("error"); reason != "" {
+		respond.Error(c, a.log, errs.Newf(errs.Unauthenticated, "provider[%s]: %s: %s", name, reason, c.Query("error_description")))
+		return
+	}
+
+	idt, err := provider.Exchange(ctx, c.Query("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Unauthenticated, "provider[%s]: %s", name, err))
+		return
+	}
+
+	ext := userbus.ExternalIdentity{
+		Provider:      name,
+		Subject:       idt.Subject,
+		EmailVerified: idt.EmailVerified,
+		Name:          idt.Name,
+	}
+
+	if idt.Email != "" {
+		addr, err := mail.ParseAddress(idt.Email)
+		if err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Unauthenticated, "provider[%s]: parse email: %s", name, err))
+			return
+		}
+		ext.Email = *addr
+	}
+
+	usr, err := a.userBus.LoginWithIdentity(ctx, ext)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrEmailNotVerified):
+			respond.Error(c, a.log, errs.New(errs.Unauthenticated, userbus.ErrEmailNotVerified))
+		case errors.Is(err, userbus.ErrIdentityUnlinked):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrIdentityUnlinked))
+		case errors.Is(err, userbus.ErrIdentityNotUnique):
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrIdentityNotUnique))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "login with identity: provider[%s]: %s", name, err))
+		}
+		return
+	}
+
+	a.completeLogin(c, usr)
+}
+
+// oidcState decrypts the state kept in the cookie.
+func (a *app) oidcState(c *gin.Context) (oidcState, error) {
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		return oidcState{}, errors.New("login state is missing")
+	}
+
+	data, err := a.cfg.OIDCStateCipher.Decrypt(cookie)
+	if err != nil {
+		return oidcState{}, fmt.Errorf("decrypt login state: %w", err)
+	}
+
+	var st oidcState
+	if err := json.Unmarshal([]byte(data), &st); err != nil {
+		return oidcState{}, fmt.Errorf("unmarshal login state: %w", err)
+	}
+
+	if time.Now().After(st.ExpiresAt) {
+		return oidcState{}, errors.New("login state has expired")
+	}
+
+	return st, nil
+}
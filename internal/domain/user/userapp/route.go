@@ -8,11 +8,36 @@ import (
 
 func (a *app) Routes(r gin.IRouter) {
 	authenticate := mid.Authenticate(a.log, a.auth)
-	owner := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.Rules.Owner)
+	owner := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.Owner())
+	ownerOrUserRead := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.OwnerOr(auth.Permissions.UserRead))
+	ownerOrUserUpdate := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.OwnerOr(auth.Permissions.UserUpdate))
+	userUpdate := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.Require(auth.Permissions.UserUpdate))
+	userRoles := mid.AuthorizeUser(a.log, a.auth, a.userBus, auth.Require(auth.Permissions.UserRoles))
+	userRead := mid.Authorize(a.log, a.auth, auth.Require(auth.Permissions.UserRead))
+	userChange := mid.AuthorizeUserChange(a.log, a.auth)
+	transaction := mid.BeginCommitRollback(a.log, a.dbBeginner)
 
-	r.POST("/users/register", a.registerHandler)
+	r.POST("/users/register", transaction, a.registerHandler)
 	r.POST("/users/login", a.loginHandler)
-	r.GET("/users/confirm-email/:confirm_token", a.confirmEmailHandler)
+	r.POST("/users/login/mfa", a.mfaLoginHandler)
+	r.GET("/users/oidc/:provider/authorize", a.oidcAuthorizeHandler)
+	r.GET("/users/oidc/:provider/callback", transaction, a.oidcCallbackHandler)
+	r.POST("/users/token/refresh", a.refreshHandler)
+	r.POST("/users/logout", a.logoutHandler)
+	r.POST("/users/confirm-email", transaction, a.confirmEmailHandler)
+	r.POST("/users/confirm-email/resend", transaction, a.resendConfirmationHandler)
+	r.POST("/users/password/forgot", transaction, a.forgotPasswordHandler)
+	r.POST("/users/password/reset", transaction, a.resetPasswordHandler)
 	r.PUT("/users/:user_id", authenticate, owner, a.updateHandler)
-	r.GET("/users/:user_id", authenticate, owner, a.queryByIDHandler)
+	r.GET("/users", authenticate, userRead, a.queryHandler)
+	r.GET("/users/:user_id", authenticate, ownerOrUserRead, a.queryByIDHandler)
+	r.PUT("/users/:user_id/enabled", authenticate, userUpdate, userChange, transaction, a.updateEnabledHandler)
+	r.PUT("/users/:user_id/roles", authenticate, userRoles, userChange, transaction, a.updateRolesHandler)
+	r.POST("/users/:user_id/confirm-email", authenticate, userUpdate, userChange, transaction, a.forceConfirmEmailHandler)
+	r.POST("/users/:user_id/unlock", authenticate, userUpdate, userChange, transaction, a.unlockHandler)
+	r.POST("/users/:user_id/mfa/setup", authenticate, owner, transaction, a.mfaSetupHandler)
+	r.POST("/users/:user_id/mfa/enable", authenticate, owner, transaction, a.mfaEnableHandler)
+	r.POST("/users/:user_id/mfa/disable", authenticate, ownerOrUserUpdate, userChange, transaction, a.mfaDisableHandler)
+	r.POST("/users/:user_id/mfa/recovery-codes", authenticate, owner, transaction, a.mfaRecoveryCodesHandler)
+	r.POST("/users/:user_id/sessions/revoke", authenticate, ownerOrUserUpdate, userChange, transaction, a.revokeSessionsHandler)
 }
@@ -0,0 +1,14 @@
+package userapp
+
+import (
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+)
+
+var defaultSortBy = sort.NewBy("date_created", sort.DESC)
+
+var sortByFields = map[string]string{
+	"date_created": userbus.SortByDateCreated,
+	"email":        userbus.SortByEmail,
+	"name":         userbus.SortByName,
+}
@@ -2,43 +2,145 @@
 package userapp
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/email/emailbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/mfa/mfabus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/etag"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/query"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/clients/oidcclient"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/aesgcm"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"math"
 	"net/mail"
+	"strconv"
 	"time"
 )
 
+// Config represents the settings of the logins and of the tokens issued to
+// users.
+type Config struct {
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// RequireAdminMFA withholds the ADMIN role from the tokens of admins who
+	// haven't enabled a second factor.
+	RequireAdminMFA bool
+	// OIDCProviders are the identity providers users can log in with, by
+	// name.
+	OIDCProviders map[string]*oidcclient.Client
+	// OIDCStateCipher encrypts the state of the logins in progress, kept by
+	// the browser in a cookie.
+	OIDCStateCipher  *aesgcm.Cipher
+	OIDCSecureCookie bool
+}
+
 type app struct {
-	log       *logger.Logger
-	auth      *auth.Auth
-	activeKID string
-	userBus   *userbus.Business
+	log        *logger.Logger
+	auth       *auth.Auth
+	cfg        Config
+	dbBeginner sqldb.Beginner
+	userBus    *userbus.Business
+	emailBus   *emailbus.Business
+	auditBus   *auditbus.Business
+	lockoutBus *lockoutbus.Business
+	mfaBus     *mfabus.Business
 }
 
 func New(
 	log *logger.Logger,
 	auth *auth.Auth,
-	activeKID string,
+	cfg Config,
+	dbBeginner sqldb.Beginner,
 	userBus *userbus.Business,
+	emailBus *emailbus.Business,
+	auditBus *auditbus.Business,
+	lockoutBus *lockoutbus.Business,
+	mfaBus *mfabus.Business,
 ) *app {
 	return &app{
-		log:       log,
-		auth:      auth,
-		activeKID: activeKID,
-		userBus:   userBus,
+		log:        log,
+		auth:       auth,
+		cfg:        cfg,
+		dbBeginner: dbBeginner,
+		userBus:    userBus,
+		emailBus:   emailBus,
+		auditBus:   auditBus,
+		lockoutBus: lockoutBus,
+		mfaBus:     mfaBus,
+	}
+}
+
+// newWithTx constructs a new app value using a store transaction that was created via middleware.
+func (a *app) newWithTx(ctx context.Context) (*app, error) {
+	tx, err := mid.GetTran(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userBusTx, err := a.userBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	emailBusTx, err := a.emailBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	auditBusTx, err := a.auditBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	lockoutBusTx, err := a.lockoutBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	mfaBusTx, err := a.mfaBus.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	app := app{
+		log:        a.log,
+		auth:       a.auth,
+		cfg:        a.cfg,
+		dbBeginner: a.dbBeginner,
+		userBus:    userBusTx,
+		emailBus:   emailBusTx,
+		auditBus:   auditBusTx,
+		lockoutBus: lockoutBusTx,
+		mfaBus:     mfaBusTx,
 	}
+
+	return &app, nil
 }
 
 func (a *app) registerHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	var req registerReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		respond.Error(c, a.log, err)
@@ -61,8 +163,12 @@ func (a *app) registerHandler(c *gin.Context) {
 		return
 	}
 
-	// todo: send email confirmation, using queue
-	// todo: redesign email confirmation flow, db
+	if err := a.sendConfirmation(ctx, usr, emailbus.MatchLocale(c.GetHeader("Accept-Language"))); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "send confirmation: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
 }
 
 func (a *app) loginHandler(c *gin.Context) {
@@ -80,9 +186,18 @@ func (a *app) loginHandler(c *gin.Context) {
 		return
 	}
 
+	if err := a.lockoutBus.Check(ctx, addr.Address, c.ClientIP()); err != nil {
+		a.lockedOut(c, err)
+		return
+	}
+
 	usr, err := a.userBus.Authenticate(ctx, *addr, req.Password)
 	if err != nil {
 		if errors.Is(err, userbus.ErrAuthenticationFailure) {
+			metrics.LoginFailed("password")
+			if err := a.lockoutBus.RecordFailure(ctx, a.dbBeginner, addr.Address, c.ClientIP()); err != nil {
+				a.log.Error(ctx, "login: record failure", "err", err)
+			}
 			respond.Error(c, a.log, errs.New(errs.Unauthenticated, err))
 		} else {
 			respond.Error(c, a.log, errs.New(errs.Internal, err))
@@ -90,46 +205,230 @@ func (a *app) loginHandler(c *gin.Context) {
 		return
 	}
 
-	if !usr.Enabled || usr.EmailConfirmToken != "" {
+	a.completeLogin(c, usr)
+}
+
+// mfaLoginHandler completes a login started with the password by checking a
+// code of the second factor, either a one-time password or a recovery code.
+func (a *app) mfaLoginHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req mfaLoginReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := a.userBus.QueryMFAChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrInvalidToken),
+			errors.Is(err, userbus.ErrTokenExpired),
+			errors.Is(err, userbus.ErrTokenUsed):
+			respond.Error(c, a.log, errs.New(errs.Unauthenticated, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "query mfa challenge: %s", err))
+		}
+		return
+	}
+
+	if err := a.lockoutBus.Check(ctx, usr.Email.Address, c.ClientIP()); err != nil {
+		a.lockedOut(c, err)
+		return
+	}
+
+	recoveryUsed, err := a.mfaBus.Verify(ctx, usr.ID, req.Code)
+	if err != nil {
+		if errors.Is(err, mfabus.ErrInvalidCode) {
+			metrics.LoginFailed("mfa")
+			if err := a.lockoutBus.RecordFailure(ctx, a.dbBeginner, usr.Email.Address, c.ClientIP()); err != nil {
+				a.log.Error(ctx, "mfa login: record failure", "err", err)
+			}
+			respond.Error(c, a.log, errs.New(errs.Unauthenticated, mfabus.ErrInvalidCode))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "verify mfa: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	if _, err := a.userBus.ConsumeToken(ctx, req.ChallengeToken, userbus.Purposes.MFAChallenge); err != nil {
+		if errors.Is(err, userbus.ErrTokenUsed) {
+			respond.Error(c, a.log, errs.New(errs.Unauthenticated, userbus.ErrTokenUsed))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "consume mfa challenge: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	if err := a.lockoutBus.Reset(ctx, usr.Email.Address); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "reset lockout: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if recoveryUsed {
+		if err := a.audit(c, usr.ID, usr.ID, auditbus.ActionRecoveryCodeUsed, nil); err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+			return
+		}
+	}
+
+	if !usr.Enabled || !usr.EmailConfirmed {
 		respond.Error(c, a.log, errs.New(errs.Unauthenticated, errors.New("invalid user")))
 		return
 	}
 
-	claims := auth.Claims{
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   usr.ID.String(),
-			Issuer:    a.auth.Issuer(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(365 * 24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		},
-		Roles: userbus.ParseRolesToString(usr.Roles),
+	refreshToken, err := a.userBus.IssueRefreshToken(ctx, usr, a.cfg.RefreshTokenTTL)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "issue refresh token: userID[%s]: %s", usr.ID, err))
+		return
 	}
-	token, err := a.auth.GenerateToken(a.activeKID, claims)
+
+	resp, err := a.authenUser(ctx, usr, refreshToken)
 	if err != nil {
 		respond.Error(c, a.log, errs.New(errs.Internal, err))
 		return
 	}
 
-	respond.Success(c, a.log, authenUser{
-		UserID: usr.ID.String(),
-		Token:  token,
-	})
+	respond.Success(c, a.log, resp)
+}
+
+// refreshHandler exchanges a refresh token for a new access token and a new
+// refresh token.
+func (a *app) refreshHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req refreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, refreshToken, err := a.userBus.RotateRefreshToken(ctx, req.RefreshToken, a.cfg.RefreshTokenTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrInvalidToken),
+			errors.Is(err, userbus.ErrTokenExpired),
+			errors.Is(err, userbus.ErrTokenReused),
+			errors.Is(err, userbus.ErrTokenRevoked):
+			respond.Error(c, a.log, errs.New(errs.Unauthenticated, err))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "rotate refresh token: %s", err))
+		}
+		return
+	}
+
+	if !usr.Enabled || !usr.EmailConfirmed {
+		respond.Error(c, a.log, errs.New(errs.Unauthenticated, errors.New("invalid user")))
+		return
+	}
+
+	resp, err := a.authenUser(ctx, usr, refreshToken)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	respond.Success(c, a.log, resp)
+}
+
+// logoutHandler revokes the refresh token of the session. The access token
+// stays valid until it expires.
+func (a *app) logoutHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req refreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	if err := a.userBus.RevokeRefreshToken(ctx, req.RefreshToken); err != nil {
+		if !errors.Is(err, userbus.ErrInvalidToken) {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "revoke refresh token: %s", err))
+			return
+		}
+	}
+
+	respond.Success(c, a.log, nil)
 }
 
 func (a *app) confirmEmailHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	confirmToken := c.Param("confirm_token")
-	if confirmToken == "" {
-		respond.Error(c, a.log, errs.New(errs.InvalidArgument, errors.New("missing confirm_token")))
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req confirmEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	if _, err := a.userBus.ConfirmEmail(ctx, req.Token); err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrInvalidToken):
+			respond.Error(c, a.log, errs.New(errs.InvalidArgument, userbus.ErrInvalidToken))
+		case errors.Is(err, userbus.ErrTokenExpired):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrTokenExpired))
+		case errors.Is(err, userbus.ErrTokenUsed):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrTokenUsed))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "confirmEmail: %s", err))
+		}
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+// resendConfirmationHandler sends a new confirmation email. Unknown, already
+// confirmed or throttled emails get the same response so the endpoint can't
+// be used to find out which emails are registered.
+func (a *app) resendConfirmationHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req resendConfirmationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	addr, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
 	}
 
-	err := a.userBus.ConfirmEmail(ctx, confirmToken)
+	usr, err := a.userBus.QueryByEmail(ctx, *addr)
 	if err != nil {
 		if errors.Is(err, userbus.ErrNotFound) {
-			respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+			respond.Success(c, a.log, nil)
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "query: email[%s]: %s", addr.Address, err))
+		}
+		return
+	}
+
+	if usr.EmailConfirmed {
+		respond.Success(c, a.log, nil)
+		return
+	}
+
+	if err := a.sendConfirmation(ctx, usr, emailbus.MatchLocale(c.GetHeader("Accept-Language"))); err != nil {
+		if errors.Is(err, userbus.ErrTokenThrottled) {
+			a.log.Info(ctx, "resend confirmation: throttled", "userID", usr.ID)
+			respond.Success(c, a.log, nil)
 		} else {
-			respond.Error(c, a.log, errs.Newf(errs.Internal, "confirmEmail: confirmToken[%s]: %s", confirmToken, err))
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "send confirmation: userID[%s]: %s", usr.ID, err))
 		}
 		return
 	}
@@ -137,44 +436,860 @@ func (a *app) confirmEmailHandler(c *gin.Context) {
 	respond.Success(c, a.log, nil)
 }
 
-func (a *app) updateHandler(c *gin.Context) {
+// forgotPasswordHandler sends a password reset email. The response is the same
+// whether or not the email is registered so the endpoint can't be used to
+// find out which emails are registered.
+func (a *app) forgotPasswordHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	var req updateUserReq
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req forgotPasswordReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		respond.Error(c, a.log, err)
 		return
 	}
 
-	usr, err := mid.GetUser(ctx)
+	resp := forgotPasswordResp{
+		Message: "if the email is registered, a password reset link has been sent",
+	}
+
+	addr, err := mail.ParseAddress(req.Email)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
 		return
 	}
 
-	updateUser, err := toBusUpdateUser(req)
+	usr, err := a.userBus.QueryByEmail(ctx, *addr)
 	if err != nil {
-		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		if errors.Is(err, userbus.ErrNotFound) {
+			respond.Success(c, a.log, resp)
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "query: email[%s]: %s", addr.Address, err))
+		}
 		return
 	}
 
-	updatedUser, err := a.userBus.Update(ctx, usr, updateUser)
+	if !usr.Enabled {
+		respond.Success(c, a.log, resp)
+		return
+	}
+
+	token, err := a.userBus.RequestPasswordReset(ctx, usr)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "update: userID[%s] req[%+v]: %s", usr.ID, req, err))
+		if errors.Is(err, userbus.ErrTokenThrottled) {
+			a.log.Info(ctx, "forgot password: throttled", "userID", usr.ID)
+			respond.Success(c, a.log, resp)
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "request password reset: userID[%s]: %s", usr.ID, err))
+		}
 		return
 	}
 
-	respond.Success(c, a.log, toAppUser(updatedUser))
+	_, err = a.emailBus.Enqueue(ctx, emailbus.NewEmail{
+		To:       usr.Email,
+		Template: emailbus.TemplatePasswordReset,
+		Locale:   emailbus.MatchLocale(c.GetHeader("Accept-Language")),
+		Data: map[string]any{
+			"name":  usr.Name.String(),
+			"token": token,
+		},
+	})
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "enqueue: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if err := a.audit(c, usr.ID, usr.ID, auditbus.ActionPasswordResetRequested, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, resp)
 }
 
-func (a *app) queryByIDHandler(c *gin.Context) {
+func (a *app) resetPasswordHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req resetPasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := a.userBus.ResetPassword(ctx, req.Token, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrInvalidToken):
+			respond.Error(c, a.log, errs.New(errs.InvalidArgument, userbus.ErrInvalidToken))
+		case errors.Is(err, userbus.ErrTokenExpired):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrTokenExpired))
+		case errors.Is(err, userbus.ErrTokenUsed):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrTokenUsed))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "reset password: %s", err))
+		}
+		return
+	}
+
+	_, err = a.emailBus.Enqueue(ctx, emailbus.NewEmail{
+		To:       usr.Email,
+		Template: emailbus.TemplatePasswordChanged,
+		Locale:   emailbus.MatchLocale(c.GetHeader("Accept-Language")),
+		Data: map[string]any{
+			"name": usr.Name.String(),
+		},
+	})
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "enqueue: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if err := a.audit(c, usr.ID, usr.ID, auditbus.ActionPasswordReset, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+// revokeSessionsHandler logs the user out everywhere.
+func (a *app) revokeSessionsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
 	usr, err := mid.GetUser(ctx)
 	if err != nil {
-		respond.Error(c, a.log, errs.Newf(errs.Internal, "querybyid: %s", err))
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
 		return
 	}
 
-	respond.Success(c, a.log, toAppUser(usr))
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if _, err := a.userBus.RevokeSessions(ctx, usr); err != nil {
+		if errors.Is(err, userbus.ErrConflict) {
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrConflict))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "revoke sessions: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	if err := a.audit(c, usr.ID, actorID, auditbus.ActionSessionsRevoked, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+func (a *app) updateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req updateUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	updateUser, err := toBusUpdateUser(req)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	if !etag.Match(c.GetHeader("If-Match"), usr.Version) {
+		respond.Error(c, a.log, errs.Newf(errs.PreconditionFailed, "update: userID[%s]: version %d does not match If-Match", usr.ID, usr.Version))
+		return
+	}
+
+	updatedUser, err := a.userBus.Update(ctx, usr, updateUser)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrUniqueEmail):
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrUniqueEmail))
+		case errors.Is(err, userbus.ErrConflict):
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrConflict))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "update: userID[%s] req[%+v]: %s", usr.ID, req, err))
+		}
+		return
+	}
+
+	c.Header("ETag", etag.Format(updatedUser.Version))
+	respond.Success(c, a.log, toAppUser(updatedUser))
+}
+
+func (a *app) queryByIDHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "querybyid: %s", err))
+		return
+	}
+
+	c.Header("ETag", etag.Format(usr.Version))
+	respond.Success(c, a.log, toAppUser(usr))
+}
+
+func (a *app) queryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	qp := parseQueryParams(c.Request)
+
+	page, err := page.Parse(qp.Page, qp.Rows)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	filter, err := parseFilter(qp)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	sortBy, err := sort.Parse(sortByFields, qp.SortBy, defaultSortBy)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	users, err := a.userBus.Query(ctx, filter, sortBy, page)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "query: %s", err))
+		return
+	}
+
+	total, err := a.userBus.Count(ctx, filter)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "count: %s", err))
+		return
+	}
+
+	respond.Success(c, a.log, query.NewResult(toAppUsers(users), total, page))
+}
+
+// updateEnabledHandler enables or disables the account of a user. Users
+// can't disable their own account.
+func (a *app) updateEnabledHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req updateEnabledReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if err := userbus.CheckSelfUpdate(actorID, usr, userbus.UpdateUser{Enabled: req.Enabled}, a.canManageRoles); err != nil {
+		respond.Error(c, a.log, errs.New(errs.FailedPrecondition, err))
+		return
+	}
+
+	if !etag.Match(c.GetHeader("If-Match"), usr.Version) {
+		respond.Error(c, a.log, errs.Newf(errs.PreconditionFailed, "update enabled: userID[%s]: version %d does not match If-Match", usr.ID, usr.Version))
+		return
+	}
+
+	updatedUser, err := a.userBus.Update(ctx, usr, userbus.UpdateUser{Enabled: req.Enabled})
+	if err != nil {
+		if errors.Is(err, userbus.ErrConflict) {
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrConflict))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "update enabled: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	action := auditbus.ActionUserDisabled
+	if updatedUser.Enabled {
+		action = auditbus.ActionUserEnabled
+	}
+
+	if err := a.audit(c, usr.ID, actorID, action, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	c.Header("ETag", etag.Format(updatedUser.Version))
+	respond.Success(c, a.log, toAppUser(updatedUser))
+}
+
+// updateRolesHandler replaces the roles of a user. Users can't take away their
+// own permission to manage roles.
+func (a *app) updateRolesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req updateRolesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	roles, err := userbus.ParseRoles(req.Roles)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.InvalidArgument, err))
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if err := userbus.CheckSelfUpdate(actorID, usr, userbus.UpdateUser{Roles: roles}, a.canManageRoles); err != nil {
+		respond.Error(c, a.log, errs.New(errs.FailedPrecondition, err))
+		return
+	}
+
+	actorRoles, err := userbus.ParseRoles(mid.GetClaims(ctx).Roles)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	// Granting or taking away a role with permissions the actor doesn't hold
+	// would let them act beyond their own permissions.
+	for _, role := range changedRoles(usr.Roles, roles) {
+		if !a.auth.Grants(actorRoles, role) {
+			respond.Error(c, a.log, errs.Newf(errs.PermissionDenied, "role %s has permissions you don't hold", role))
+			return
+		}
+	}
+
+	if !etag.Match(c.GetHeader("If-Match"), usr.Version) {
+		respond.Error(c, a.log, errs.Newf(errs.PreconditionFailed, "update roles: userID[%s]: version %d does not match If-Match", usr.ID, usr.Version))
+		return
+	}
+
+	updatedUser, err := a.userBus.Update(ctx, usr, userbus.UpdateUser{Roles: roles})
+	if err != nil {
+		if errors.Is(err, userbus.ErrConflict) {
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrConflict))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "update roles: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	details := map[string]any{
+		"from": userbus.ParseRolesToString(usr.Roles),
+		"to":   userbus.ParseRolesToString(updatedUser.Roles),
+	}
+
+	if err := a.audit(c, usr.ID, actorID, auditbus.ActionRolesChanged, details); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	c.Header("ETag", etag.Format(updatedUser.Version))
+	respond.Success(c, a.log, toAppUser(updatedUser))
+}
+
+// canManageRoles reports whether the roles grant the permission to change the
+// roles of users.
+func (a *app) canManageRoles(roles []userbus.Role) bool {
+	return a.auth.HasPermission(roles, auth.Permissions.UserRoles)
+}
+
+// forceConfirmEmailHandler marks the email of a user as confirmed without a
+// confirmation token.
+func (a *app) forceConfirmEmailHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	updatedUser, err := a.userBus.ForceConfirmEmail(ctx, usr)
+	if err != nil {
+		switch {
+		case errors.Is(err, userbus.ErrEmailConfirmed):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, userbus.ErrEmailConfirmed))
+		case errors.Is(err, userbus.ErrConflict):
+			respond.Error(c, a.log, errs.New(errs.Aborted, userbus.ErrConflict))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "confirm email: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	if err := a.audit(c, usr.ID, actorID, auditbus.ActionEmailConfirmed, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	c.Header("ETag", etag.Format(updatedUser.Version))
+	respond.Success(c, a.log, toAppUser(updatedUser))
+}
+
+// unlockHandler clears the failed logins and the lockout of the account of a
+// user.
+func (a *app) unlockHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if err := a.lockoutBus.Reset(ctx, usr.Email.Address); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "unlock: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if err := a.audit(c, usr.ID, actorID, auditbus.ActionUserUnlocked, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+// mfaSetupHandler generates a new secret for the authenticator of the user.
+func (a *app) mfaSetupHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	setup, err := a.mfaBus.Setup(ctx, usr.ID, usr.Email.Address)
+	if err != nil {
+		if errors.Is(err, mfabus.ErrAlreadyEnabled) {
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, mfabus.ErrAlreadyEnabled))
+		} else {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa setup: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	respond.Success(c, a.log, toAppMFASetup(setup))
+}
+
+// mfaEnableHandler turns the second factor on once the user proves their
+// authenticator holds the secret. The recovery codes are only returned here.
+func (a *app) mfaEnableHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	codes, err := a.mfaBus.Enable(ctx, usr.ID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, mfabus.ErrNotFound):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, mfabus.ErrNotFound))
+		case errors.Is(err, mfabus.ErrAlreadyEnabled):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, mfabus.ErrAlreadyEnabled))
+		case errors.Is(err, mfabus.ErrInvalidCode):
+			respond.Error(c, a.log, errs.New(errs.InvalidArgument, mfabus.ErrInvalidCode))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa enable: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	if err := a.audit(c, usr.ID, usr.ID, auditbus.ActionMFAEnabled, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, recoveryCodes{RecoveryCodes: codes})
+}
+
+// mfaDisableHandler turns the second factor off. Users must give a code of
+// their second factor, admins can turn it off for a user who lost it.
+func (a *app) mfaDisableHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req mfaDisableReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	actorID, err := mid.GetUserID(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	if actorID == usr.ID {
+		if _, err := a.mfaBus.Verify(ctx, usr.ID, req.Code); err != nil {
+			switch {
+			case errors.Is(err, mfabus.ErrNotEnabled):
+				respond.Error(c, a.log, errs.New(errs.FailedPrecondition, mfabus.ErrNotEnabled))
+			case errors.Is(err, mfabus.ErrInvalidCode):
+				respond.Error(c, a.log, errs.New(errs.InvalidArgument, mfabus.ErrInvalidCode))
+			default:
+				respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa verify: userID[%s]: %s", usr.ID, err))
+			}
+			return
+		}
+	}
+
+	if err := a.mfaBus.Disable(ctx, usr.ID); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa disable: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if err := a.audit(c, usr.ID, actorID, auditbus.ActionMFADisabled, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, nil)
+}
+
+// mfaRecoveryCodesHandler replaces the recovery codes of the user.
+func (a *app) mfaRecoveryCodesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	a, err := a.newWithTx(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	var req mfaCodeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respond.Error(c, a.log, err)
+		return
+	}
+
+	usr, err := mid.GetUser(ctx)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "user missing in context: %s", err))
+		return
+	}
+
+	if _, err := a.mfaBus.Verify(ctx, usr.ID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, mfabus.ErrNotEnabled):
+			respond.Error(c, a.log, errs.New(errs.FailedPrecondition, mfabus.ErrNotEnabled))
+		case errors.Is(err, mfabus.ErrInvalidCode):
+			respond.Error(c, a.log, errs.New(errs.InvalidArgument, mfabus.ErrInvalidCode))
+		default:
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa verify: userID[%s]: %s", usr.ID, err))
+		}
+		return
+	}
+
+	codes, err := a.mfaBus.RegenerateRecoveryCodes(ctx, usr.ID)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "regenerate recovery codes: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	if err := a.audit(c, usr.ID, usr.ID, auditbus.ActionRecoveryCodesRenewed, nil); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "audit: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	respond.Success(c, a.log, recoveryCodes{RecoveryCodes: codes})
+}
+
+// sendConfirmation issues a new email confirmation token and queues the email
+// carrying it.
+func (a *app) sendConfirmation(ctx context.Context, usr userbus.User, locale string) error {
+	token, err := a.userBus.RequestEmailConfirmation(ctx, usr)
+	if err != nil {
+		return fmt.Errorf("request email confirmation: %w", err)
+	}
+
+	_, err = a.emailBus.Enqueue(ctx, emailbus.NewEmail{
+		To:       usr.Email,
+		Template: emailbus.TemplateConfirmEmail,
+		Locale:   locale,
+		Data: map[string]any{
+			"name":  usr.Name.String(),
+			"token": token,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// audit records an action done by the actor on the account of the user.
+func (a *app) audit(c *gin.Context, userID uuid.UUID, actorID uuid.UUID, action string, details map[string]any) error {
+	_, err := a.auditBus.Record(c.Request.Context(), auditbus.NewAudit{
+		UserID:    userID,
+		ActorID:   actorID,
+		Action:    action,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Details:   details,
+	})
+
+	return err
+}
+
+// completeLogin finishes the login of an authenticated user the same way
+// whatever proved their identity. Users with a second factor get a challenge
+// instead of a session.
+func (a *app) completeLogin(c *gin.Context, usr userbus.User) {
+	ctx := c.Request.Context()
+
+	if !usr.Enabled || !usr.EmailConfirmed {
+		respond.Error(c, a.log, errs.New(errs.Unauthenticated, errors.New("invalid user")))
+		return
+	}
+
+	mfaEnabled, err := a.mfaBus.Enabled(ctx, usr.ID)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "mfa enabled: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	// The failed attempts are only cleared once the second factor is checked
+	// too, otherwise knowing the password would allow unlimited guesses of
+	// the codes.
+	if mfaEnabled {
+		challenge, err := a.userBus.IssueMFAChallenge(ctx, usr)
+		if err != nil {
+			respond.Error(c, a.log, errs.Newf(errs.Internal, "issue mfa challenge: userID[%s]: %s", usr.ID, err))
+			return
+		}
+
+		resp := mfaChallenge{
+			MFARequired:    true,
+			ChallengeToken: challenge,
+			ExpiresAt:      time.Now().Add(userbus.MFAChallengeTTL).UTC().Format(time.RFC3339),
+		}
+
+		respond.Success(c, a.log, resp)
+		return
+	}
+
+	if err := a.lockoutBus.Reset(ctx, usr.Email.Address); err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "reset lockout: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	refreshToken, err := a.userBus.IssueRefreshToken(ctx, usr, a.cfg.RefreshTokenTTL)
+	if err != nil {
+		respond.Error(c, a.log, errs.Newf(errs.Internal, "issue refresh token: userID[%s]: %s", usr.ID, err))
+		return
+	}
+
+	resp, err := a.authenUser(ctx, usr, refreshToken)
+	if err != nil {
+		respond.Error(c, a.log, errs.New(errs.Internal, err))
+		return
+	}
+
+	respond.Success(c, a.log, resp)
+}
+
+// authenUser signs a new access token for the user and returns it with the
+// refresh token of the session. When the second factor is required for
+// admins, an admin who hasn't enabled it gets a token without the ADMIN role
+// until they do.
+func (a *app) authenUser(ctx context.Context, usr userbus.User, refreshToken string) (authenUser, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(a.cfg.AccessTokenTTL)
+
+	var mfaSetupRequired bool
+	roles := usr.Roles
+	if a.cfg.RequireAdminMFA && userbus.RolesList(roles).Contains(userbus.Roles.Admin) {
+		enabled, err := a.mfaBus.Enabled(ctx, usr.ID)
+		if err != nil {
+			return authenUser{}, fmt.Errorf("mfa enabled: %w", err)
+		}
+
+		if !enabled {
+			mfaSetupRequired = true
+			roles = withoutRole(roles, userbus.Roles.Admin)
+		}
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   usr.ID.String(),
+			Issuer:    a.auth.Issuer(),
+			Audience:  a.auth.Audiences(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		Roles:        userbus.ParseRolesToString(roles),
+		TokenVersion: usr.TokenVersion,
+	}
+
+	token, err := a.auth.GenerateToken(a.auth.ActiveKID(), claims)
+	if err != nil {
+		return authenUser{}, fmt.Errorf("generate token: %w", err)
+	}
+
+	resp := authenUser{
+		UserID:           usr.ID.String(),
+		Token:            token,
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		RefreshToken:     refreshToken,
+		MFASetupRequired: mfaSetupRequired,
+	}
+
+	return resp, nil
+}
+
+// lockedOut responds to a login refused by the lockout.
+func (a *app) lockedOut(c *gin.Context, err error) {
+	var lockedErr *lockoutbus.LockedError
+	if errors.As(err, &lockedErr) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(lockedErr.Until).Seconds()))))
+		respond.Error(c, a.log, errs.New(errs.AccountLocked, lockoutbus.ErrLocked))
+		return
+	}
+
+	respond.Error(c, a.log, errs.Newf(errs.Internal, "check lockout: %s", err))
+}
+
+// changedRoles returns the roles found in only one of from and to.
+func changedRoles(from []userbus.Role, to []userbus.Role) []userbus.Role {
+	var changed []userbus.Role
+	for _, r := range from {
+		if !userbus.RolesList(to).Contains(r) {
+			changed = append(changed, r)
+		}
+	}
+	for _, r := range to {
+		if !userbus.RolesList(from).Contains(r) {
+			changed = append(changed, r)
+		}
+	}
+
+	return changed
+}
+
+func withoutRole(roles []userbus.Role, role userbus.Role) []userbus.Role {
+	var result []userbus.Role
+	for _, r := range roles {
+		if !r.Equal(role) {
+			result = append(result, r)
+		}
+	}
+
+	return result
 }
@@ -0,0 +1,14 @@
+package userbus
+
+import (
+	"time"
+)
+
+// QueryFilter holds the available fields a query can be filtered on.
+type QueryFilter struct {
+	Email            *string
+	Role             *Role
+	Enabled          *bool
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+}
@@ -0,0 +1,149 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of error variables for external identities.
+var (
+	ErrEmailNotVerified  = errors.New("email is not verified by the provider")
+	ErrIdentityUnlinked  = errors.New("email of the account must be confirmed before linking an identity")
+	ErrIdentityNotUnique = errors.New("identity is already linked")
+)
+
+// Identity represents an account of the user at an external identity
+// provider.
+type Identity struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Provider      string
+	Subject       string
+	Email         mail.Address
+	DateLastLogin time.Time
+	DateCreated   time.Time
+}
+
+// ExternalIdentity contains the claims of an identity as asserted by its
+// provider.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         mail.Address
+	EmailVerified bool
+	Name          string
+}
+
+// LoginWithIdentity returns the user the identity is linked to. An identity
+// seen for the first time is linked to the user with the same email, or to a
+// new user when there is none, but only when the provider verified the email.
+func (b *Business) LoginWithIdentity(ctx context.Context, ext ExternalIdentity) (User, error) {
+	now := time.Now()
+
+	idn, err := b.storer.QueryIdentity(ctx, ext.Provider, ext.Subject)
+	switch {
+	case err == nil:
+		idn.DateLastLogin = now
+		if ext.Email.Address != "" {
+			idn.Email = ext.Email
+		}
+		if err := b.storer.UpdateIdentity(ctx, idn); err != nil {
+			return User{}, fmt.Errorf("update identity: identityID[%s]: %w", idn.ID, err)
+		}
+
+		usr, err := b.storer.QueryByID(ctx, idn.UserID)
+		if err != nil {
+			return User{}, fmt.Errorf("query: userID[%s]: %w", idn.UserID, err)
+		}
+
+		return usr, nil
+
+	case !errors.Is(err, ErrNotFound):
+		return User{}, fmt.Errorf("query identity: provider[%s]: %w", ext.Provider, err)
+	}
+
+	if !ext.EmailVerified {
+		return User{}, fmt.Errorf("provider[%s]: %w", ext.Provider, ErrEmailNotVerified)
+	}
+
+	usr, err := b.storer.QueryByEmail(ctx, ext.Email)
+	switch {
+	case err == nil:
+		// Someone could have registered the email without owning it, linking
+		// would then give them the account of the real owner.
+		if !usr.EmailConfirmed {
+			return User{}, fmt.Errorf("userID[%s]: %w", usr.ID, ErrIdentityUnlinked)
+		}
+
+	case errors.Is(err, ErrNotFound):
+		password, err := generateToken()
+		if err != nil {
+			return User{}, fmt.Errorf("generate password: %w", err)
+		}
+
+		usr, err = b.Create(ctx, NewUser{
+			Name:           identityName(ext),
+			Email:          ext.Email,
+			Roles:          []Role{Roles.User},
+			Password:       password,
+			EmailConfirmed: true,
+		})
+		if err != nil {
+			return User{}, fmt.Errorf("create: %w", err)
+		}
+
+	default:
+		return User{}, fmt.Errorf("query by email: %w", err)
+	}
+
+	idn = Identity{
+		ID:            uuid.New(),
+		UserID:        usr.ID,
+		Provider:      ext.Provider,
+		Subject:       ext.Subject,
+		Email:         ext.Email,
+		DateLastLogin: now,
+		DateCreated:   now,
+	}
+
+	if err := b.storer.CreateIdentity(ctx, idn); err != nil {
+		return User{}, fmt.Errorf("create identity: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
+}
+
+// identityName derives a valid name from the name given by the provider,
+// falling back to the email.
+func identityName(ext ExternalIdentity) Name {
+	candidates := []string{
+		ext.Name,
+		strings.Split(ext.Email.Address, "@")[0],
+	}
+
+	for _, c := range candidates {
+		c = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '\'' || r == ' ' || r == '-' {
+				return r
+			}
+			return -1
+		}, c)
+
+		c = strings.TrimSpace(c)
+		if len(c) > 20 {
+			c = strings.TrimSpace(c[:20])
+		}
+
+		if name, err := ParseName(c); err == nil {
+			return name
+		}
+	}
+
+	return MustParseName("Customer")
+}
@@ -11,36 +11,46 @@ import (
 
 // User represents information about an individual user.
 type User struct {
-	ID                uuid.UUID
-	Name              Name
-	Email             mail.Address
-	Roles             []Role
-	PasswordHash      string
-	Enabled           bool
-	EmailConfirmToken string
-	DateCreated       time.Time
-	DateUpdated       time.Time
+	ID             uuid.UUID
+	Name           Name
+	Email          mail.Address
+	Roles          []Role
+	PasswordHash   string
+	Enabled        bool
+	EmailConfirmed bool
+	// SessionsValidAfter is the time before which every session of the
+	// user is rejected. Zero means no session was revoked.
+	SessionsValidAfter time.Time
+	// TokenVersion is embedded in every access token, tokens carrying an
+	// older version are rejected. It starts at InitialTokenVersion.
+	TokenVersion int
+	Version      int
+	DateCreated  time.Time
+	DateUpdated  time.Time
 }
 
 // =============================================================================
 
 // NewUser contains information needed to create a new user.
 type NewUser struct {
-	Name              Name
-	Email             mail.Address
-	Roles             []Role
-	Password          string
-	EmailConfirmToken string
+	Name           Name
+	Email          mail.Address
+	Roles          []Role
+	Password       string
+	EmailConfirmed bool
 }
 
 // =============================================================================
 
 // UpdateUser contains information needed to update a user.
 type UpdateUser struct {
-	Name              *Name
-	Email             *mail.Address
-	Roles             []Role
-	Password          *string
-	Enabled           *bool
-	EmailConfirmToken *string
+	Name           *Name
+	Email          *mail.Address
+	Roles          []Role
+	Password       *string
+	Enabled        *bool
+	EmailConfirmed *bool
+	// SessionsValidAfter revokes every session started before it and every
+	// access token issued until now.
+	SessionsValidAfter *time.Time
 }
@@ -0,0 +1,65 @@
+package userbus
+
+import "fmt"
+
+type purposeSet struct {
+	EmailConfirmation Purpose
+	PasswordReset     Purpose
+	MFAChallenge      Purpose
+}
+
+// Purposes represents the set of purposes a user token can be issued for.
+var Purposes = purposeSet{
+	EmailConfirmation: newPurpose("EMAIL_CONFIRMATION"),
+	PasswordReset:     newPurpose("PASSWORD_RESET"),
+	MFAChallenge:      newPurpose("MFA_CHALLENGE"),
+}
+
+// =============================================================================
+
+// Set of known purposes.
+var purposes = make(map[string]Purpose)
+
+// Purpose represents what a user token can be used for.
+type Purpose struct {
+	name string
+}
+
+func newPurpose(purpose string) Purpose {
+	p := Purpose{purpose}
+	purposes[purpose] = p
+	return p
+}
+
+// String returns the name of the purpose.
+func (p Purpose) String() string {
+	return p.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Purpose) Equal(p2 Purpose) bool {
+	return p.name == p2.name
+}
+
+// =============================================================================
+
+// ParsePurpose parses the string value and returns a purpose if one exists.
+func ParsePurpose(value string) (Purpose, error) {
+	purpose, exists := purposes[value]
+	if !exists {
+		return Purpose{}, fmt.Errorf("invalid purpose %q", value)
+	}
+
+	return purpose, nil
+}
+
+// MustParsePurpose parses the string value and returns a purpose if one
+// exists. If an error occurs the function panics.
+func MustParsePurpose(value string) Purpose {
+	purpose, err := ParsePurpose(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return purpose
+}
@@ -0,0 +1,150 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of error variables for refresh tokens.
+var (
+	ErrTokenReused  = errors.New("refresh token was already used")
+	ErrTokenRevoked = errors.New("refresh token was revoked")
+)
+
+// RefreshToken represents an opaque token used to get a new access token.
+// Every refresh token belongs to a family that starts at login. Each time a
+// token is used it is replaced by a new token of the same family.
+type RefreshToken struct {
+	ID          uuid.UUID
+	FamilyID    uuid.UUID
+	UserID      uuid.UUID
+	Hash        string
+	DateExpires time.Time
+	DateUsed    time.Time
+	DateRevoked time.Time
+	DateCreated time.Time
+}
+
+// IssueRefreshToken starts a new token family for the user and returns the
+// plaintext of its first token.
+func (b *Business) IssueRefreshToken(ctx context.Context, usr User, ttl time.Duration) (string, error) {
+	plain, err := b.createRefreshToken(ctx, usr.ID, uuid.New(), ttl)
+	if err != nil {
+		return "", err
+	}
+
+	return plain, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one of the same
+// family and returns the user it belongs to. Presenting a token that was
+// already used or revoked means it was stolen or replayed, the whole family
+// is then revoked.
+func (b *Business) RotateRefreshToken(ctx context.Context, plain string, ttl time.Duration) (User, string, error) {
+	rt, err := b.storer.QueryRefreshTokenByHash(ctx, hashToken(plain))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return User{}, "", ErrInvalidToken
+		}
+		return User{}, "", fmt.Errorf("query refresh token: %w", err)
+	}
+
+	now := time.Now()
+
+	switch {
+	case !rt.DateRevoked.IsZero():
+		return User{}, "", b.revokeFamily(ctx, rt, ErrTokenRevoked)
+	case !rt.DateUsed.IsZero():
+		return User{}, "", b.revokeFamily(ctx, rt, ErrTokenReused)
+	case now.After(rt.DateExpires):
+		return User{}, "", fmt.Errorf("refreshTokenID[%s]: %w", rt.ID, ErrTokenExpired)
+	}
+
+	usr, err := b.storer.QueryByID(ctx, rt.UserID)
+	if err != nil {
+		return User{}, "", fmt.Errorf("query: userID[%s]: %w", rt.UserID, err)
+	}
+
+	if rt.DateCreated.Before(usr.SessionsValidAfter) {
+		return User{}, "", fmt.Errorf("refreshTokenID[%s]: %w", rt.ID, ErrTokenRevoked)
+	}
+
+	// The store only marks tokens that are still unused, losing the race
+	// against another request means the token was used twice.
+	rt.DateUsed = now
+	if err := b.storer.UseRefreshToken(ctx, rt); err != nil {
+		if errors.Is(err, ErrTokenReused) {
+			return User{}, "", b.revokeFamily(ctx, rt, ErrTokenReused)
+		}
+		return User{}, "", fmt.Errorf("use refresh token: refreshTokenID[%s]: %w", rt.ID, err)
+	}
+
+	next, err := b.createRefreshToken(ctx, rt.UserID, rt.FamilyID, ttl)
+	if err != nil {
+		return User{}, "", err
+	}
+
+	return usr, next, nil
+}
+
+// RevokeRefreshToken revokes a single refresh token, which ends the session
+// it belongs to.
+func (b *Business) RevokeRefreshToken(ctx context.Context, plain string) error {
+	rt, err := b.storer.QueryRefreshTokenByHash(ctx, hashToken(plain))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("query refresh token: %w", err)
+	}
+
+	if !rt.DateRevoked.IsZero() {
+		return nil
+	}
+
+	rt.DateRevoked = time.Now()
+	if err := b.storer.RevokeRefreshToken(ctx, rt); err != nil {
+		return fmt.Errorf("revoke refresh token: refreshTokenID[%s]: %w", rt.ID, err)
+	}
+
+	return nil
+}
+
+func (b *Business) createRefreshToken(ctx context.Context, userID uuid.UUID, familyID uuid.UUID, ttl time.Duration) (string, error) {
+	plain, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+
+	now := time.Now()
+
+	rt := RefreshToken{
+		ID:          uuid.New(),
+		FamilyID:    familyID,
+		UserID:      userID,
+		Hash:        hashToken(plain),
+		DateExpires: now.Add(ttl),
+		DateCreated: now,
+	}
+
+	if err := b.storer.CreateRefreshToken(ctx, rt); err != nil {
+		return "", fmt.Errorf("create refresh token: %w", err)
+	}
+
+	return plain, nil
+}
+
+// revokeFamily revokes every token of the family of rt and returns reason.
+func (b *Business) revokeFamily(ctx context.Context, rt RefreshToken, reason error) error {
+	b.log.Warn(ctx, "refresh token family revoked", "familyID", rt.FamilyID, "userID", rt.UserID, "reason", reason)
+
+	if err := b.storer.RevokeRefreshFamily(ctx, rt.FamilyID, time.Now()); err != nil {
+		return fmt.Errorf("revoke family: familyID[%s]: %w", rt.FamilyID, err)
+	}
+
+	return fmt.Errorf("refreshTokenID[%s]: %w", rt.ID, reason)
+}
@@ -0,0 +1,119 @@
+package userbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_RotateRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	usr := createUser(t, bus)
+
+	first, err := bus.IssueRefreshToken(ctx, usr, time.Hour)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	got, second, err := bus.RotateRefreshToken(ctx, first, time.Hour)
+	if err != nil {
+		t.Fatalf("Should rotate the refresh token: %s", err)
+	}
+
+	if got.ID != usr.ID {
+		t.Errorf("Should return the user of the token: got %s, want %s", got.ID, usr.ID)
+	}
+
+	if second == first || store.refresh[hashToken(second)].FamilyID != store.refresh[hashToken(first)].FamilyID {
+		t.Errorf("Should replace the token by a new one of the same family")
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, second, time.Hour); err != nil {
+		t.Errorf("Should rotate the new token: %s", err)
+	}
+}
+
+func Test_RotateRefreshTokenReuse(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	usr := createUser(t, bus)
+
+	first, err := bus.IssueRefreshToken(ctx, usr, time.Hour)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	other, err := bus.IssueRefreshToken(ctx, usr, time.Hour)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	_, second, err := bus.RotateRefreshToken(ctx, first, time.Hour)
+	if err != nil {
+		t.Fatalf("Should rotate the refresh token: %s", err)
+	}
+
+	_, third, err := bus.RotateRefreshToken(ctx, second, time.Hour)
+	if err != nil {
+		t.Fatalf("Should rotate the refresh token: %s", err)
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, first, time.Hour); !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("Should reject a reused token: got %v", err)
+	}
+
+	familyID := store.refresh[hashToken(first)].FamilyID
+	for _, rt := range store.refresh {
+		if rt.FamilyID == familyID && rt.DateRevoked.IsZero() {
+			t.Errorf("Should revoke every token of the family: refreshTokenID[%s]", rt.ID)
+		}
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, third, time.Hour); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Should reject the latest token of the revoked family: got %v", err)
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, other, time.Hour); err != nil {
+		t.Errorf("Should keep the other sessions of the user: %s", err)
+	}
+}
+
+func Test_RotateRefreshTokenRejected(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	usr := createUser(t, bus)
+
+	if _, _, err := bus.RotateRefreshToken(ctx, "unknown", time.Hour); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should reject an unknown token: got %v", err)
+	}
+
+	expired, err := bus.IssueRefreshToken(ctx, usr, -time.Second)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, expired, time.Hour); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Should reject an expired token: got %v", err)
+	}
+
+	revoked, err := bus.IssueRefreshToken(ctx, usr, time.Hour)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	if err := bus.RevokeRefreshToken(ctx, revoked); err != nil {
+		t.Fatalf("Should revoke the refresh token: %s", err)
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, revoked, time.Hour); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Should reject a revoked token: got %v", err)
+	}
+}
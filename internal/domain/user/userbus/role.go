@@ -3,14 +3,21 @@ package userbus
 import "fmt"
 
 type roleSet struct {
-	Admin Role
-	User  Role
+	Admin          Role
+	User           Role
+	CatalogManager Role
+	OrderFulfiller Role
+	SupportAgent   Role
 }
 
-// Roles represents the set of roles that can be used.
+// Roles represents the set of roles that can be used. What a role allows is
+// decided by the permissions it is granted.
 var Roles = roleSet{
-	Admin: newRole("ADMIN"),
-	User:  newRole("USER"),
+	Admin:          newRole("ADMIN"),
+	User:           newRole("USER"),
+	CatalogManager: newRole("CATALOG_MANAGER"),
+	OrderFulfiller: newRole("ORDER_FULFILLER"),
+	SupportAgent:   newRole("SUPPORT_AGENT"),
 }
 
 // =============================================================================
@@ -0,0 +1,155 @@
+package userbus
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Set of error variables for user tokens.
+var (
+	ErrInvalidToken   = errors.New("token is invalid")
+	ErrTokenExpired   = errors.New("token has expired")
+	ErrTokenUsed      = errors.New("token has already been used")
+	ErrTokenThrottled = errors.New("token was requested too recently")
+	ErrEmailConfirmed = errors.New("email is already confirmed")
+)
+
+// Lifetimes of the tokens issued per purpose.
+const (
+	EmailConfirmationTTL = 24 * time.Hour
+	PasswordResetTTL     = 30 * time.Minute
+	MFAChallengeTTL      = 5 * time.Minute
+)
+
+// TokenResendInterval is the minimum time between two tokens issued to the
+// same user for the same purpose.
+const TokenResendInterval = time.Minute
+
+// Token represents a single-use token issued to a user. Only the hash of the
+// token is stored, the plaintext is handed to the user once.
+type Token struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Purpose     Purpose
+	Hash        string
+	DateExpires time.Time
+	DateUsed    time.Time
+	DateCreated time.Time
+}
+
+// IssueToken creates a new token for the purpose and returns its plaintext.
+// Tokens previously issued for the same purpose and not used yet are
+// revoked. A new token can't be issued within TokenResendInterval of the
+// last one.
+func (b *Business) IssueToken(ctx context.Context, usr User, purpose Purpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	last, err := b.storer.QueryLatestToken(ctx, usr.ID, purpose)
+	switch {
+	case err == nil:
+		if now.Sub(last.DateCreated) < TokenResendInterval {
+			return "", fmt.Errorf("issue token: userID[%s] purpose[%s]: %w", usr.ID, purpose, ErrTokenThrottled)
+		}
+	case !errors.Is(err, ErrNotFound):
+		return "", fmt.Errorf("query latest token: userID[%s]: %w", usr.ID, err)
+	}
+
+	return b.issueToken(ctx, usr, purpose, ttl)
+}
+
+// issueToken revokes the unused tokens of the purpose and creates a new one.
+func (b *Business) issueToken(ctx context.Context, usr User, purpose Purpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	if err := b.storer.DeleteUnusedTokens(ctx, usr.ID, purpose); err != nil {
+		return "", fmt.Errorf("delete unused tokens: userID[%s]: %w", usr.ID, err)
+	}
+
+	plain, err := generateToken()
+	if err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+
+	tkn := Token{
+		ID:          uuid.New(),
+		UserID:      usr.ID,
+		Purpose:     purpose,
+		Hash:        hashToken(plain),
+		DateExpires: now.Add(ttl),
+		DateCreated: now,
+	}
+
+	if err := b.storer.CreateToken(ctx, tkn); err != nil {
+		return "", fmt.Errorf("create token: %w", err)
+	}
+
+	return plain, nil
+}
+
+// ConsumeToken marks the token as used and returns it. A token can only be
+// consumed once and only before it expires.
+func (b *Business) ConsumeToken(ctx context.Context, plain string, purpose Purpose) (Token, error) {
+	tkn, err := b.queryValidToken(ctx, plain, purpose)
+	if err != nil {
+		return Token{}, err
+	}
+
+	tkn.DateUsed = time.Now()
+
+	// The store only marks tokens that are still unused so two concurrent
+	// requests can't both consume the same token.
+	if err := b.storer.UseToken(ctx, tkn); err != nil {
+		return Token{}, fmt.Errorf("use token: tokenID[%s]: %w", tkn.ID, err)
+	}
+
+	return tkn, nil
+}
+
+// queryValidToken returns the token if it was issued for the purpose, has not
+// been used and has not expired.
+func (b *Business) queryValidToken(ctx context.Context, plain string, purpose Purpose) (Token, error) {
+	tkn, err := b.storer.QueryTokenByHash(ctx, hashToken(plain))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Token{}, ErrInvalidToken
+		}
+		return Token{}, fmt.Errorf("query token: %w", err)
+	}
+
+	if !tkn.Purpose.Equal(purpose) {
+		return Token{}, ErrInvalidToken
+	}
+
+	if !tkn.DateUsed.IsZero() {
+		return Token{}, fmt.Errorf("tokenID[%s]: %w", tkn.ID, ErrTokenUsed)
+	}
+
+	if time.Now().After(tkn.DateExpires) {
+		return Token{}, fmt.Errorf("tokenID[%s]: %w", tkn.ID, ErrTokenExpired)
+	}
+
+	return tkn, nil
+}
+
+// generateToken returns 32 random bytes encoded for use in urls.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -6,9 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
 	"golang.org/x/crypto/bcrypt"
 	"net/mail"
+	"slices"
 	"time"
 )
 
@@ -17,21 +21,56 @@ var (
 	ErrNotFound              = errors.New("user not found")
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
+	ErrConflict              = errors.New("user was modified concurrently")
 )
 
+// Set of error variables for the changes users can't make to their own
+// account.
+var (
+	ErrSelfDisable = errors.New("users can't disable their own account")
+	ErrSelfDemote  = errors.New("users can't take away their own permission to manage roles")
+)
+
+// InitialTokenVersion is the token version of a new user. Users created
+// before token versions were introduced start at it too.
+const InitialTokenVersion = 1
+
 // Storer interface declares the behavior this package needs to perists and retrieve data.
 type Storer interface {
+	NewWithTx(tx sqldb.CommitRollbacker) (Storer, error)
 	Create(ctx context.Context, user User) error
 	Update(ctx context.Context, user User) error
+	Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]User, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
 	QueryByID(ctx context.Context, userID uuid.UUID) (User, error)
 	QueryByEmail(ctx context.Context, email mail.Address) (User, error)
-	QueryByEmailConfirmToken(ctx context.Context, token string) (User, error)
+	CreateToken(ctx context.Context, token Token) error
+	UseToken(ctx context.Context, token Token) error
+	DeleteUnusedTokens(ctx context.Context, userID uuid.UUID, purpose Purpose) error
+	RevokeTokens(ctx context.Context, userID uuid.UUID) error
+	QueryTokenByHash(ctx context.Context, hash string) (Token, error)
+	QueryLatestToken(ctx context.Context, userID uuid.UUID, purpose Purpose) (Token, error)
+	CreateRefreshToken(ctx context.Context, token RefreshToken) error
+	UseRefreshToken(ctx context.Context, token RefreshToken) error
+	RevokeRefreshToken(ctx context.Context, token RefreshToken) error
+	RevokeRefreshFamily(ctx context.Context, familyID uuid.UUID, now time.Time) error
+	RevokeRefreshTokens(ctx context.Context, userID uuid.UUID, now time.Time) error
+	QueryRefreshTokenByHash(ctx context.Context, hash string) (RefreshToken, error)
+	CreateIdentity(ctx context.Context, identity Identity) error
+	UpdateIdentity(ctx context.Context, identity Identity) error
+	QueryIdentity(ctx context.Context, provider string, subject string) (Identity, error)
+	NotifyChanged(ctx context.Context, userID uuid.UUID) error
 }
 
+// ChangeHook is called when a change of the user can affect their sessions:
+// enabled, email confirmation, roles, password or revoked sessions.
+type ChangeHook func(ctx context.Context, userID uuid.UUID)
+
 // Business manages the set of APIs for user access.
 type Business struct {
 	log    *logger.Logger
 	storer Storer
+	hooks  []ChangeHook
 }
 
 // NewBusiness constructs a user business API for use.
@@ -42,6 +81,29 @@ func NewBusiness(log *logger.Logger, storer Storer) *Business {
 	}
 }
 
+// NewWithTx constructs a new business value that will use the specified transaction in any store related calls.
+func (b *Business) NewWithTx(tx sqldb.CommitRollbacker) (*Business, error) {
+	storerTx, err := b.storer.NewWithTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	bus := Business{
+		log:    b.log,
+		storer: storerTx,
+		hooks:  b.hooks,
+	}
+
+	return &bus, nil
+}
+
+// OnChange registers a hook called once a change that can affect the
+// sessions of a user is committed. Hooks are registered before the business
+// is used.
+func (b *Business) OnChange(hook ChangeHook) {
+	b.hooks = append(b.hooks, hook)
+}
+
 func (b *Business) Create(ctx context.Context, newUser NewUser) (User, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -51,15 +113,17 @@ func (b *Business) Create(ctx context.Context, newUser NewUser) (User, error) {
 	now := time.Now()
 
 	user := User{
-		ID:                uuid.New(),
-		Name:              newUser.Name,
-		Email:             newUser.Email,
-		PasswordHash:      string(hash),
-		Roles:             newUser.Roles,
-		Enabled:           true,
-		EmailConfirmToken: newUser.EmailConfirmToken,
-		DateCreated:       now,
-		DateUpdated:       now,
+		ID:             uuid.New(),
+		Name:           newUser.Name,
+		Email:          newUser.Email,
+		PasswordHash:   string(hash),
+		Roles:          newUser.Roles,
+		Enabled:        true,
+		EmailConfirmed: newUser.EmailConfirmed,
+		TokenVersion:   InitialTokenVersion,
+		Version:        1,
+		DateCreated:    now,
+		DateUpdated:    now,
 	}
 
 	if err := b.storer.Create(ctx, user); err != nil {
@@ -70,6 +134,8 @@ func (b *Business) Create(ctx context.Context, newUser NewUser) (User, error) {
 }
 
 func (b *Business) Update(ctx context.Context, user User, updateUser UpdateUser) (User, error) {
+	before := user
+
 	if updateUser.Name != nil {
 		user.Name = *updateUser.Name
 	}
@@ -94,8 +160,13 @@ func (b *Business) Update(ctx context.Context, user User, updateUser UpdateUser)
 		user.Enabled = *updateUser.Enabled
 	}
 
-	if updateUser.EmailConfirmToken != nil {
-		user.EmailConfirmToken = *updateUser.EmailConfirmToken
+	if updateUser.EmailConfirmed != nil {
+		user.EmailConfirmed = *updateUser.EmailConfirmed
+	}
+
+	if updateUser.SessionsValidAfter != nil {
+		user.SessionsValidAfter = *updateUser.SessionsValidAfter
+		user.TokenVersion++
 	}
 
 	user.DateUpdated = time.Now()
@@ -104,9 +175,72 @@ func (b *Business) Update(ctx context.Context, user User, updateUser UpdateUser)
 		return User{}, fmt.Errorf("update: %w", err)
 	}
 
+	user.Version++
+
+	if sessionsAffected(before, user) {
+		// Other processes hear of the change once the transaction commits,
+		// and so does this one. Running the hooks earlier would let a
+		// concurrent request cache the state the transaction replaces.
+		if err := b.storer.NotifyChanged(ctx, user.ID); err != nil {
+			return User{}, fmt.Errorf("notify changed: %w", err)
+		}
+
+		hooks := b.hooks
+		userID := user.ID
+		sqldb.AfterCommit(ctx, func(ctx context.Context) {
+			for _, hook := range hooks {
+				hook(ctx, userID)
+			}
+		})
+	}
+
 	return user, nil
 }
 
+// sessionsAffected reports whether the update changes anything the sessions
+// of the user depend on.
+func sessionsAffected(before User, after User) bool {
+	return before.Enabled != after.Enabled ||
+		before.EmailConfirmed != after.EmailConfirmed ||
+		before.PasswordHash != after.PasswordHash ||
+		before.TokenVersion != after.TokenVersion ||
+		!before.SessionsValidAfter.Equal(after.SessionsValidAfter) ||
+		!slices.Equal(before.Roles, after.Roles)
+}
+
+// CheckSelfUpdate keeps the actor from locking themselves out when they change
+// their own account: they can't disable it nor take away their permission to
+// manage roles, which manageRoles reports for a set of roles. Changes to
+// other users are not restricted.
+func CheckSelfUpdate(actorID uuid.UUID, usr User, updateUser UpdateUser, manageRoles func(roles []Role) bool) error {
+	if actorID != usr.ID {
+		return nil
+	}
+
+	if updateUser.Enabled != nil && !*updateUser.Enabled {
+		return fmt.Errorf("userID[%s]: %w", usr.ID, ErrSelfDisable)
+	}
+
+	if updateUser.Roles != nil && manageRoles(usr.Roles) && !manageRoles(updateUser.Roles) {
+		return fmt.Errorf("userID[%s]: %w", usr.ID, ErrSelfDemote)
+	}
+
+	return nil
+}
+
+func (b *Business) Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]User, error) {
+	users, err := b.storer.Query(ctx, filter, sortBy, page)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+func (b *Business) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return b.storer.Count(ctx, filter)
+}
+
 func (b *Business) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
 	user, err := b.storer.QueryByID(ctx, userID)
 	if err != nil {
@@ -141,16 +275,148 @@ func (b *Business) QueryByEmail(ctx context.Context, email mail.Address) (User,
 	return user, nil
 }
 
-func (b *Business) ConfirmEmail(ctx context.Context, confirmToken string) error {
-	usr, err := b.storer.QueryByEmailConfirmToken(ctx, confirmToken)
+// RequestEmailConfirmation issues a new email confirmation token for a user
+// who hasn't confirmed their email yet.
+func (b *Business) RequestEmailConfirmation(ctx context.Context, usr User) (string, error) {
+	if usr.EmailConfirmed {
+		return "", fmt.Errorf("userID[%s]: %w", usr.ID, ErrEmailConfirmed)
+	}
+
+	token, err := b.IssueToken(ctx, usr, Purposes.EmailConfirmation, EmailConfirmationTTL)
 	if err != nil {
-		return fmt.Errorf("query: confirmToken[%s]: %w", confirmToken, err)
+		return "", err
 	}
 
-	_, err = b.Update(ctx, usr, UpdateUser{EmailConfirmToken: new(string)})
+	return token, nil
+}
+
+// ConfirmEmail consumes the email confirmation token and marks the email of
+// its user as confirmed. Expired or already used tokens are rejected.
+func (b *Business) ConfirmEmail(ctx context.Context, token string) (User, error) {
+	tkn, err := b.ConsumeToken(ctx, token, Purposes.EmailConfirmation)
 	if err != nil {
-		return fmt.Errorf("update: confirmToken[%s]: %w", confirmToken, err)
+		return User{}, fmt.Errorf("consume token: %w", err)
 	}
 
-	return nil
+	usr, err := b.storer.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", tkn.UserID, err)
+	}
+
+	confirmed := true
+	usr, err = b.Update(ctx, usr, UpdateUser{EmailConfirmed: &confirmed})
+	if err != nil {
+		return User{}, fmt.Errorf("update: userID[%s]: %w", tkn.UserID, err)
+	}
+
+	return usr, nil
+}
+
+// ForceConfirmEmail marks the email of the user as confirmed without a
+// confirmation token. Pending tokens of the user are revoked.
+func (b *Business) ForceConfirmEmail(ctx context.Context, usr User) (User, error) {
+	if usr.EmailConfirmed {
+		return User{}, fmt.Errorf("userID[%s]: %w", usr.ID, ErrEmailConfirmed)
+	}
+
+	confirmed := true
+	usr, err := b.Update(ctx, usr, UpdateUser{EmailConfirmed: &confirmed})
+	if err != nil {
+		return User{}, fmt.Errorf("update: userID[%s]: %w", usr.ID, err)
+	}
+
+	if err := b.storer.RevokeTokens(ctx, usr.ID); err != nil {
+		return User{}, fmt.Errorf("revoke tokens: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
+}
+
+// RequestPasswordReset issues a new password reset token for the user.
+func (b *Business) RequestPasswordReset(ctx context.Context, usr User) (string, error) {
+	token, err := b.IssueToken(ctx, usr, Purposes.PasswordReset, PasswordResetTTL)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword consumes the password reset token and sets the new password
+// of its user. Every session of the user is revoked and every token that has
+// not been used yet is deleted.
+func (b *Business) ResetPassword(ctx context.Context, token string, password string) (User, error) {
+	tkn, err := b.ConsumeToken(ctx, token, Purposes.PasswordReset)
+	if err != nil {
+		return User{}, fmt.Errorf("consume token: %w", err)
+	}
+
+	usr, err := b.storer.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", tkn.UserID, err)
+	}
+
+	now := time.Now()
+	usr, err = b.Update(ctx, usr, UpdateUser{
+		Password:           &password,
+		SessionsValidAfter: &now,
+	})
+	if err != nil {
+		return User{}, fmt.Errorf("update: userID[%s]: %w", tkn.UserID, err)
+	}
+
+	if err := b.storer.RevokeTokens(ctx, usr.ID); err != nil {
+		return User{}, fmt.Errorf("revoke tokens: userID[%s]: %w", usr.ID, err)
+	}
+
+	if err := b.storer.RevokeRefreshTokens(ctx, usr.ID, now); err != nil {
+		return User{}, fmt.Errorf("revoke refresh tokens: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
+}
+
+// IssueMFAChallenge issues the token a user who passed the password check
+// exchanges, with a code of their second factor, for a session. Unlike the
+// other tokens it is not throttled, every login issues a new one.
+func (b *Business) IssueMFAChallenge(ctx context.Context, usr User) (string, error) {
+	token, err := b.issueToken(ctx, usr, Purposes.MFAChallenge, MFAChallengeTTL)
+	if err != nil {
+		return "", fmt.Errorf("issue token: %w", err)
+	}
+
+	return token, nil
+}
+
+// QueryMFAChallenge returns the user of a valid MFA challenge token without
+// consuming it, so a mistyped code doesn't require logging in again.
+func (b *Business) QueryMFAChallenge(ctx context.Context, token string) (User, error) {
+	tkn, err := b.queryValidToken(ctx, token, Purposes.MFAChallenge)
+	if err != nil {
+		return User{}, err
+	}
+
+	usr, err := b.storer.QueryByID(ctx, tkn.UserID)
+	if err != nil {
+		return User{}, fmt.Errorf("query: userID[%s]: %w", tkn.UserID, err)
+	}
+
+	return usr, nil
+}
+
+// RevokeSessions logs the user out everywhere. Access tokens issued until now
+// are rejected and every refresh token is revoked.
+func (b *Business) RevokeSessions(ctx context.Context, usr User) (User, error) {
+	now := time.Now()
+
+	usr, err := b.Update(ctx, usr, UpdateUser{SessionsValidAfter: &now})
+	if err != nil {
+		return User{}, fmt.Errorf("update: userID[%s]: %w", usr.ID, err)
+	}
+
+	if err := b.storer.RevokeRefreshTokens(ctx, usr.ID, now); err != nil {
+		return User{}, fmt.Errorf("revoke refresh tokens: userID[%s]: %w", usr.ID, err)
+	}
+
+	return usr, nil
 }
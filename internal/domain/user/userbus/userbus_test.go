@@ -0,0 +1,444 @@
+package userbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	usr := createUser(t, bus)
+
+	confirm, err := bus.RequestEmailConfirmation(ctx, usr)
+	if err != nil {
+		t.Fatalf("Should issue a confirmation token: %s", err)
+	}
+
+	reset, err := bus.RequestPasswordReset(ctx, usr)
+	if err != nil {
+		t.Fatalf("Should issue a password reset token: %s", err)
+	}
+
+	refresh, err := bus.IssueRefreshToken(ctx, usr, time.Hour)
+	if err != nil {
+		t.Fatalf("Should issue a refresh token: %s", err)
+	}
+
+	before := time.Now()
+
+	updated, err := bus.ResetPassword(ctx, reset, "new-password")
+	if err != nil {
+		t.Fatalf("Should reset the password: %s", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(updated.PasswordHash), []byte("new-password")); err != nil {
+		t.Errorf("Should store the new password: %s", err)
+	}
+
+	if updated.SessionsValidAfter.Before(before) || updated.TokenVersion != usr.TokenVersion+1 {
+		t.Errorf("Should revoke the sessions of the user: got %s and version %d", updated.SessionsValidAfter, updated.TokenVersion)
+	}
+
+	if _, err := bus.ResetPassword(ctx, reset, "other-password"); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("Should consume the reset token: got %v", err)
+	}
+
+	if _, err := bus.ConfirmEmail(ctx, confirm); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should revoke the unused tokens of the user: got %v", err)
+	}
+
+	if _, _, err := bus.RotateRefreshToken(ctx, refresh, time.Hour); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Should revoke the refresh tokens of the user: got %v", err)
+	}
+}
+
+func Test_ResetPasswordInvalidToken(t *testing.T) {
+	ctx := context.Background()
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	usr := createUser(t, bus)
+
+	if _, err := bus.ResetPassword(ctx, "unknown", "new-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should reject an unknown token: got %v", err)
+	}
+
+	confirm, err := bus.RequestEmailConfirmation(ctx, usr)
+	if err != nil {
+		t.Fatalf("Should issue a confirmation token: %s", err)
+	}
+
+	if _, err := bus.ResetPassword(ctx, confirm, "new-password"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Should reject a token issued for another purpose: got %v", err)
+	}
+
+	reset, err := bus.RequestPasswordReset(ctx, usr)
+	if err != nil {
+		t.Fatalf("Should issue a password reset token: %s", err)
+	}
+
+	tkn := store.tokens[hashToken(reset)]
+	tkn.DateExpires = time.Now().Add(-time.Second)
+	store.tokens[tkn.Hash] = tkn
+
+	if _, err := bus.ResetPassword(ctx, reset, "new-password"); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Should reject an expired token: got %v", err)
+	}
+
+	if got := store.users[usr.ID]; got.PasswordHash != usr.PasswordHash {
+		t.Errorf("Should keep the password when the token is rejected")
+	}
+}
+
+func Test_CheckSelfUpdate(t *testing.T) {
+	manageRoles := func(roles []Role) bool {
+		return RolesList(roles).Contains(Roles.Admin)
+	}
+
+	disabled := false
+	enabled := true
+
+	admin := User{ID: uuid.New(), Roles: []Role{Roles.Admin}}
+	other := User{ID: uuid.New(), Roles: []Role{Roles.Admin}}
+	agent := User{ID: uuid.New(), Roles: []Role{Roles.SupportAgent}}
+
+	tests := []struct {
+		name   string
+		actor  User
+		usr    User
+		update UpdateUser
+		want   error
+	}{
+		{name: "disable self", actor: admin, usr: admin, update: UpdateUser{Enabled: &disabled}, want: ErrSelfDisable},
+		{name: "enable self", actor: admin, usr: admin, update: UpdateUser{Enabled: &enabled}},
+		{name: "disable other", actor: admin, usr: other, update: UpdateUser{Enabled: &disabled}},
+		{name: "demote self", actor: admin, usr: admin, update: UpdateUser{Roles: []Role{Roles.User}}, want: ErrSelfDemote},
+		{name: "keep admin", actor: admin, usr: admin, update: UpdateUser{Roles: []Role{Roles.Admin, Roles.User}}},
+		{name: "demote other", actor: admin, usr: other, update: UpdateUser{Roles: []Role{Roles.User}}},
+		{name: "change own roles without permission", actor: agent, usr: agent, update: UpdateUser{Roles: []Role{Roles.User}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSelfUpdate(tt.actor.ID, tt.usr, tt.update, manageRoles)
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Should allow the change: %s", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Should reject the change with %v: got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func Test_UpdateNotifiesSessionChanges(t *testing.T) {
+	ctx := context.Background()
+
+	name := MustParseName("Jill Kennedy")
+	password := "new-password"
+	disabled := false
+	validAfter := time.Now()
+
+	tests := []struct {
+		name     string
+		update   UpdateUser
+		notifies bool
+	}{
+		{name: "name", update: UpdateUser{Name: &name}},
+		{name: "roles", update: UpdateUser{Roles: []Role{Roles.Admin}}, notifies: true},
+		{name: "password", update: UpdateUser{Password: &password}, notifies: true},
+		{name: "enabled", update: UpdateUser{Enabled: &disabled}, notifies: true},
+		{name: "sessions", update: UpdateUser{SessionsValidAfter: &validAfter}, notifies: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			bus := newTestBusiness(store)
+
+			var hooked []uuid.UUID
+			bus.OnChange(func(ctx context.Context, userID uuid.UUID) {
+				hooked = append(hooked, userID)
+			})
+
+			usr := createUser(t, bus)
+
+			if _, err := bus.Update(ctx, usr, tt.update); err != nil {
+				t.Fatalf("Should update the user: %s", err)
+			}
+
+			want := 0
+			if tt.notifies {
+				want = 1
+			}
+
+			if len(store.notified) != want || len(hooked) != want {
+				t.Fatalf("Should notify the change %d times: got %d notifications and %d hook calls", want, len(store.notified), len(hooked))
+			}
+
+			if tt.notifies && (store.notified[0] != usr.ID || hooked[0] != usr.ID) {
+				t.Errorf("Should notify the change of the user: got %s and %s", store.notified[0], hooked[0])
+			}
+		})
+	}
+}
+
+func Test_UpdateHooksAfterCommit(t *testing.T) {
+	ctx := sqldb.WithCommitHooks(context.Background())
+	store := newFakeStore()
+	bus := newTestBusiness(store)
+
+	var hooked []uuid.UUID
+	bus.OnChange(func(ctx context.Context, userID uuid.UUID) {
+		hooked = append(hooked, userID)
+	})
+
+	usr := createUser(t, bus)
+
+	disabled := false
+	if _, err := bus.Update(ctx, usr, UpdateUser{Enabled: &disabled}); err != nil {
+		t.Fatalf("Should update the user: %s", err)
+	}
+
+	if len(hooked) != 0 {
+		t.Fatalf("Should not call the hooks before the transaction commits: got %d calls", len(hooked))
+	}
+
+	sqldb.RunCommitHooks(ctx)
+
+	if len(hooked) != 1 || hooked[0] != usr.ID {
+		t.Errorf("Should call the hooks once the transaction commits: got %v", hooked)
+	}
+}
+
+// =============================================================================
+
+func newTestBusiness(store *fakeStore) *Business {
+	log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+	return NewBusiness(log, store)
+}
+
+func createUser(t *testing.T, bus *Business, roles ...Role) User {
+	t.Helper()
+
+	if len(roles) == 0 {
+		roles = []Role{Roles.User}
+	}
+
+	usr, err := bus.Create(context.Background(), NewUser{
+		Name:     MustParseName("Bill Kennedy"),
+		Email:    mail.Address{Address: uuid.NewString() + "@example.com"},
+		Roles:    roles,
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatalf("Should create the user: %s", err)
+	}
+
+	return usr
+}
+
+// fakeStore keeps users and their tokens in memory with the semantics of the
+// database store.
+type fakeStore struct {
+	users    map[uuid.UUID]User
+	tokens   map[string]Token
+	refresh  map[string]RefreshToken
+	notified []uuid.UUID
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:   make(map[uuid.UUID]User),
+		tokens:  make(map[string]Token),
+		refresh: make(map[string]RefreshToken),
+	}
+}
+
+func (s *fakeStore) NewWithTx(tx sqldb.CommitRollbacker) (Storer, error) {
+	return s, nil
+}
+
+func (s *fakeStore) Create(ctx context.Context, user User) error {
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *fakeStore) Update(ctx context.Context, user User) error {
+	stored, exists := s.users[user.ID]
+	if !exists || stored.Version != user.Version {
+		return ErrConflict
+	}
+
+	user.Version++
+	s.users[user.ID] = user
+
+	return nil
+}
+
+func (s *fakeStore) Query(ctx context.Context, filter QueryFilter, sortBy sort.By, page page.Page) ([]User, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s *fakeStore) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	return 0, errors.New("not supported")
+}
+
+func (s *fakeStore) QueryByID(ctx context.Context, userID uuid.UUID) (User, error) {
+	user, exists := s.users[userID]
+	if !exists {
+		return User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *fakeStore) QueryByEmail(ctx context.Context, email mail.Address) (User, error) {
+	for _, user := range s.users {
+		if user.Email.Address == email.Address {
+			return user, nil
+		}
+	}
+	return User{}, ErrNotFound
+}
+
+func (s *fakeStore) CreateToken(ctx context.Context, token Token) error {
+	s.tokens[token.Hash] = token
+	return nil
+}
+
+func (s *fakeStore) UseToken(ctx context.Context, token Token) error {
+	stored, exists := s.tokens[token.Hash]
+	if !exists || !stored.DateUsed.IsZero() {
+		return ErrTokenUsed
+	}
+
+	s.tokens[token.Hash] = token
+
+	return nil
+}
+
+func (s *fakeStore) DeleteUnusedTokens(ctx context.Context, userID uuid.UUID, purpose Purpose) error {
+	for hash, token := range s.tokens {
+		if token.UserID == userID && token.Purpose.Equal(purpose) && token.DateUsed.IsZero() {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) RevokeTokens(ctx context.Context, userID uuid.UUID) error {
+	for hash, token := range s.tokens {
+		if token.UserID == userID && token.DateUsed.IsZero() {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) QueryTokenByHash(ctx context.Context, hash string) (Token, error) {
+	token, exists := s.tokens[hash]
+	if !exists {
+		return Token{}, ErrNotFound
+	}
+	return token, nil
+}
+
+func (s *fakeStore) QueryLatestToken(ctx context.Context, userID uuid.UUID, purpose Purpose) (Token, error) {
+	var latest Token
+	for _, token := range s.tokens {
+		if token.UserID == userID && token.Purpose.Equal(purpose) && token.DateCreated.After(latest.DateCreated) {
+			latest = token
+		}
+	}
+
+	if latest.ID == uuid.Nil {
+		return Token{}, ErrNotFound
+	}
+
+	return latest, nil
+}
+
+func (s *fakeStore) CreateRefreshToken(ctx context.Context, token RefreshToken) error {
+	s.refresh[token.Hash] = token
+	return nil
+}
+
+func (s *fakeStore) UseRefreshToken(ctx context.Context, token RefreshToken) error {
+	stored, exists := s.refresh[token.Hash]
+	if !exists || !stored.DateUsed.IsZero() || !stored.DateRevoked.IsZero() {
+		return ErrTokenReused
+	}
+
+	s.refresh[token.Hash] = token
+
+	return nil
+}
+
+func (s *fakeStore) RevokeRefreshToken(ctx context.Context, token RefreshToken) error {
+	s.refresh[token.Hash] = token
+	return nil
+}
+
+func (s *fakeStore) RevokeRefreshFamily(ctx context.Context, familyID uuid.UUID, now time.Time) error {
+	for hash, token := range s.refresh {
+		if token.FamilyID == familyID && token.DateRevoked.IsZero() {
+			token.DateRevoked = now
+			s.refresh[hash] = token
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) RevokeRefreshTokens(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	for hash, token := range s.refresh {
+		if token.UserID == userID && token.DateRevoked.IsZero() {
+			token.DateRevoked = now
+			s.refresh[hash] = token
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) QueryRefreshTokenByHash(ctx context.Context, hash string) (RefreshToken, error) {
+	token, exists := s.refresh[hash]
+	if !exists {
+		return RefreshToken{}, ErrNotFound
+	}
+	return token, nil
+}
+
+func (s *fakeStore) CreateIdentity(ctx context.Context, identity Identity) error {
+	return errors.New("not supported")
+}
+
+func (s *fakeStore) UpdateIdentity(ctx context.Context, identity Identity) error {
+	return errors.New("not supported")
+}
+
+func (s *fakeStore) QueryIdentity(ctx context.Context, provider string, subject string) (Identity, error) {
+	return Identity{}, ErrNotFound
+}
+
+func (s *fakeStore) NotifyChanged(ctx context.Context, userID uuid.UUID) error {
+	s.notified = append(s.notified, userID)
+	return nil
+}
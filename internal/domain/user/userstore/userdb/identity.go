@@ -0,0 +1,72 @@
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+func (s *Store) CreateIdentity(ctx context.Context, identity userbus.Identity) error {
+	const q = `
+	INSERT INTO user_identities
+		(identity_id, user_id, provider, subject, email, date_last_login, date_created)
+	VALUES
+		(:identity_id, :user_id, :provider, :subject, :email, :date_last_login, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBIdentity(identity)); err != nil {
+		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+			return fmt.Errorf("namedexeccontext: %w", userbus.ErrIdentityNotUnique)
+		}
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateIdentity(ctx context.Context, identity userbus.Identity) error {
+	const q = `
+	UPDATE
+		user_identities
+	SET
+		"email" = :email,
+		"date_last_login" = :date_last_login
+	WHERE
+		identity_id = :identity_id`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBIdentity(identity)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryIdentity(ctx context.Context, provider string, subject string) (userbus.Identity, error) {
+	data := struct {
+		Provider string `db:"provider"`
+		Subject  string `db:"subject"`
+	}{
+		Provider: provider,
+		Subject:  subject,
+	}
+
+	const q = `
+	SELECT
+		identity_id, user_id, provider, subject, email, date_last_login, date_created
+	FROM
+		user_identities
+	WHERE
+		provider = :provider AND subject = :subject`
+
+	var row identityRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.Identity{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.Identity{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusIdentity(row)
+}
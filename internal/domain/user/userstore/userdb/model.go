@@ -12,28 +12,34 @@ import (
 )
 
 type userRow struct {
-	ID                uuid.UUID      `db:"user_id"`
-	Name              string         `db:"name"`
-	Email             string         `db:"email"`
-	Roles             dbarray.String `db:"roles"`
-	PasswordHash      string         `db:"password_hash"`
-	Enabled           bool           `db:"enabled"`
-	EmailConfirmToken sql.NullString `db:"email_confirm_token"`
-	DateCreated       time.Time      `db:"date_created"`
-	DateUpdated       time.Time      `db:"date_updated"`
+	ID                 uuid.UUID      `db:"user_id"`
+	Name               string         `db:"name"`
+	Email              string         `db:"email"`
+	Roles              dbarray.String `db:"roles"`
+	PasswordHash       string         `db:"password_hash"`
+	Enabled            bool           `db:"enabled"`
+	EmailConfirmed     bool           `db:"email_confirmed"`
+	SessionsValidAfter sql.NullTime   `db:"sessions_valid_after"`
+	TokenVersion       int            `db:"token_version"`
+	Version            int            `db:"version"`
+	DateCreated        time.Time      `db:"date_created"`
+	DateUpdated        time.Time      `db:"date_updated"`
 }
 
 func toDBUser(bus userbus.User) userRow {
 	return userRow{
-		ID:                bus.ID,
-		Name:              bus.Name.String(),
-		Email:             bus.Email.Address,
-		Roles:             userbus.ParseRolesToString(bus.Roles),
-		PasswordHash:      bus.PasswordHash,
-		Enabled:           bus.Enabled,
-		EmailConfirmToken: sql.NullString{String: bus.EmailConfirmToken, Valid: bus.EmailConfirmToken != ""},
-		DateCreated:       bus.DateCreated.UTC(),
-		DateUpdated:       bus.DateUpdated.UTC(),
+		ID:                 bus.ID,
+		Name:               bus.Name.String(),
+		Email:              bus.Email.Address,
+		Roles:              userbus.ParseRolesToString(bus.Roles),
+		PasswordHash:       bus.PasswordHash,
+		Enabled:            bus.Enabled,
+		EmailConfirmed:     bus.EmailConfirmed,
+		SessionsValidAfter: sql.NullTime{Time: bus.SessionsValidAfter.UTC(), Valid: !bus.SessionsValidAfter.IsZero()},
+		TokenVersion:       bus.TokenVersion,
+		Version:            bus.Version,
+		DateCreated:        bus.DateCreated.UTC(),
+		DateUpdated:        bus.DateUpdated.UTC(),
 	}
 }
 
@@ -52,16 +58,174 @@ func toBusUser(row userRow) (userbus.User, error) {
 		return userbus.User{}, fmt.Errorf("parse name: %w", err)
 	}
 
+	var sessionsValidAfter time.Time
+	if row.SessionsValidAfter.Valid {
+		sessionsValidAfter = row.SessionsValidAfter.Time.UTC()
+	}
+
 	bus := userbus.User{
-		ID:                row.ID,
-		Name:              name,
-		Email:             addr,
-		Roles:             roles,
-		PasswordHash:      row.PasswordHash,
-		Enabled:           row.Enabled,
-		EmailConfirmToken: row.EmailConfirmToken.String,
-		DateCreated:       row.DateCreated.UTC(),
-		DateUpdated:       row.DateUpdated.UTC(),
+		ID:                 row.ID,
+		Name:               name,
+		Email:              addr,
+		Roles:              roles,
+		PasswordHash:       row.PasswordHash,
+		Enabled:            row.Enabled,
+		EmailConfirmed:     row.EmailConfirmed,
+		SessionsValidAfter: sessionsValidAfter,
+		TokenVersion:       row.TokenVersion,
+		Version:            row.Version,
+		DateCreated:        row.DateCreated.UTC(),
+		DateUpdated:        row.DateUpdated.UTC(),
+	}
+
+	return bus, nil
+}
+
+func toBusUsers(rows []userRow) ([]userbus.User, error) {
+	bus := make([]userbus.User, len(rows))
+
+	for i, row := range rows {
+		var err error
+		bus[i], err = toBusUser(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type tokenRow struct {
+	ID          uuid.UUID    `db:"token_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Purpose     string       `db:"purpose"`
+	Hash        string       `db:"token_hash"`
+	DateExpires time.Time    `db:"date_expires"`
+	DateUsed    sql.NullTime `db:"date_used"`
+	DateCreated time.Time    `db:"date_created"`
+}
+
+func toDBToken(bus userbus.Token) tokenRow {
+	return tokenRow{
+		ID:          bus.ID,
+		UserID:      bus.UserID,
+		Purpose:     bus.Purpose.String(),
+		Hash:        bus.Hash,
+		DateExpires: bus.DateExpires.UTC(),
+		DateUsed:    sql.NullTime{Time: bus.DateUsed.UTC(), Valid: !bus.DateUsed.IsZero()},
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusToken(row tokenRow) (userbus.Token, error) {
+	purpose, err := userbus.ParsePurpose(row.Purpose)
+	if err != nil {
+		return userbus.Token{}, fmt.Errorf("parse purpose: %w", err)
+	}
+
+	bus := userbus.Token{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Purpose:     purpose,
+		Hash:        row.Hash,
+		DateExpires: row.DateExpires.UTC(),
+		DateCreated: row.DateCreated.UTC(),
+	}
+
+	if row.DateUsed.Valid {
+		bus.DateUsed = row.DateUsed.Time.UTC()
+	}
+
+	return bus, nil
+}
+
+// =============================================================================
+
+type refreshTokenRow struct {
+	ID          uuid.UUID    `db:"refresh_token_id"`
+	FamilyID    uuid.UUID    `db:"family_id"`
+	UserID      uuid.UUID    `db:"user_id"`
+	Hash        string       `db:"token_hash"`
+	DateExpires time.Time    `db:"date_expires"`
+	DateUsed    sql.NullTime `db:"date_used"`
+	DateRevoked sql.NullTime `db:"date_revoked"`
+	DateCreated time.Time    `db:"date_created"`
+}
+
+func toDBRefreshToken(bus userbus.RefreshToken) refreshTokenRow {
+	return refreshTokenRow{
+		ID:          bus.ID,
+		FamilyID:    bus.FamilyID,
+		UserID:      bus.UserID,
+		Hash:        bus.Hash,
+		DateExpires: bus.DateExpires.UTC(),
+		DateUsed:    sql.NullTime{Time: bus.DateUsed.UTC(), Valid: !bus.DateUsed.IsZero()},
+		DateRevoked: sql.NullTime{Time: bus.DateRevoked.UTC(), Valid: !bus.DateRevoked.IsZero()},
+		DateCreated: bus.DateCreated.UTC(),
+	}
+}
+
+func toBusRefreshToken(row refreshTokenRow) userbus.RefreshToken {
+	bus := userbus.RefreshToken{
+		ID:          row.ID,
+		FamilyID:    row.FamilyID,
+		UserID:      row.UserID,
+		Hash:        row.Hash,
+		DateExpires: row.DateExpires.UTC(),
+		DateCreated: row.DateCreated.UTC(),
+	}
+
+	if row.DateUsed.Valid {
+		bus.DateUsed = row.DateUsed.Time.UTC()
+	}
+
+	if row.DateRevoked.Valid {
+		bus.DateRevoked = row.DateRevoked.Time.UTC()
+	}
+
+	return bus
+}
+
+// =============================================================================
+
+type identityRow struct {
+	ID            uuid.UUID `db:"identity_id"`
+	UserID        uuid.UUID `db:"user_id"`
+	Provider      string    `db:"provider"`
+	Subject       string    `db:"subject"`
+	Email         string    `db:"email"`
+	DateLastLogin time.Time `db:"date_last_login"`
+	DateCreated   time.Time `db:"date_created"`
+}
+
+func toDBIdentity(bus userbus.Identity) identityRow {
+	return identityRow{
+		ID:            bus.ID,
+		UserID:        bus.UserID,
+		Provider:      bus.Provider,
+		Subject:       bus.Subject,
+		Email:         bus.Email.Address,
+		DateLastLogin: bus.DateLastLogin.UTC(),
+		DateCreated:   bus.DateCreated.UTC(),
+	}
+}
+
+func toBusIdentity(row identityRow) (userbus.Identity, error) {
+	addr, err := mail.ParseAddress(row.Email)
+	if err != nil {
+		return userbus.Identity{}, fmt.Errorf("parse email: %w", err)
+	}
+
+	bus := userbus.Identity{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		Provider:      row.Provider,
+		Subject:       row.Subject,
+		Email:         *addr,
+		DateLastLogin: row.DateLastLogin.UTC(),
+		DateCreated:   row.DateCreated.UTC(),
 	}
 
 	return bus, nil
@@ -0,0 +1,142 @@
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, token userbus.RefreshToken) error {
+	const q = `
+	INSERT INTO refresh_tokens
+		(refresh_token_id, family_id, user_id, token_hash, date_expires, date_used, date_revoked, date_created)
+	VALUES
+		(:refresh_token_id, :family_id, :user_id, :token_hash, :date_expires, :date_used, :date_revoked, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBRefreshToken(token)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UseRefreshToken sets the date the token was used, unless it has already
+// been used or revoked.
+func (s *Store) UseRefreshToken(ctx context.Context, token userbus.RefreshToken) error {
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"date_used" = :date_used
+	WHERE
+		refresh_token_id = :refresh_token_id AND date_used IS NULL AND date_revoked IS NULL
+	RETURNING
+		refresh_token_id`
+
+	var row struct {
+		ID uuid.UUID `db:"refresh_token_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBRefreshToken(token), &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrTokenReused)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, token userbus.RefreshToken) error {
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		refresh_token_id = :refresh_token_id AND date_revoked IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBRefreshToken(token)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeRefreshFamily(ctx context.Context, familyID uuid.UUID, now time.Time) error {
+	data := struct {
+		FamilyID    string    `db:"family_id"`
+		DateRevoked time.Time `db:"date_revoked"`
+	}{
+		FamilyID:    familyID.String(),
+		DateRevoked: now.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		family_id = :family_id AND date_revoked IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) RevokeRefreshTokens(ctx context.Context, userID uuid.UUID, now time.Time) error {
+	data := struct {
+		UserID      string    `db:"user_id"`
+		DateRevoked time.Time `db:"date_revoked"`
+	}{
+		UserID:      userID.String(),
+		DateRevoked: now.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		"date_revoked" = :date_revoked
+	WHERE
+		user_id = :user_id AND date_revoked IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryRefreshTokenByHash(ctx context.Context, hash string) (userbus.RefreshToken, error) {
+	data := struct {
+		Hash string `db:"token_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	SELECT
+		refresh_token_id, family_id, user_id, token_hash, date_expires, date_used, date_revoked, date_created
+	FROM
+		refresh_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	var row refreshTokenRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.RefreshToken{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.RefreshToken{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusRefreshToken(row), nil
+}
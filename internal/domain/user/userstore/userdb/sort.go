@@ -0,0 +1,23 @@
+package userdb
+
+import (
+	"fmt"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
+)
+
+var sortByFields = map[string]string{
+	userbus.SortByDateCreated: "date_created",
+	userbus.SortByEmail:       "email",
+	userbus.SortByName:        "name",
+}
+
+func orderByClause(sortBy sort.By) (string, error) {
+	by, exists := sortByFields[sortBy.Field]
+	if !exists {
+		return "", fmt.Errorf("field %q does not exist", sortBy.Field)
+	}
+
+	return " ORDER BY " + by + " " + sortBy.Direction, nil
+}
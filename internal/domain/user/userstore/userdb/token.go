@@ -0,0 +1,150 @@
+package userdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+)
+
+func (s *Store) CreateToken(ctx context.Context, token userbus.Token) error {
+	const q = `
+	INSERT INTO user_tokens
+		(token_id, user_id, purpose, token_hash, date_expires, date_used, date_created)
+	VALUES
+		(:token_id, :user_id, :purpose, :token_hash, :date_expires, :date_used, :date_created)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBToken(token)); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// UseToken sets the date the token was used, unless it has already been used.
+func (s *Store) UseToken(ctx context.Context, token userbus.Token) error {
+	const q = `
+	UPDATE
+		user_tokens
+	SET
+		"date_used" = :date_used
+	WHERE
+		token_id = :token_id AND date_used IS NULL
+	RETURNING
+		token_id`
+
+	var row struct {
+		ID uuid.UUID `db:"token_id"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBToken(token), &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrTokenUsed)
+		}
+		return fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteUnusedTokens(ctx context.Context, userID uuid.UUID, purpose userbus.Purpose) error {
+	data := struct {
+		UserID  string `db:"user_id"`
+		Purpose string `db:"purpose"`
+	}{
+		UserID:  userID.String(),
+		Purpose: purpose.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND purpose = :purpose AND date_used IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeTokens deletes every token of the user that has not been used yet.
+func (s *Store) RevokeTokens(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID.String(),
+	}
+
+	const q = `
+	DELETE FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND date_used IS NULL`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryTokenByHash(ctx context.Context, hash string) (userbus.Token, error) {
+	data := struct {
+		Hash string `db:"token_hash"`
+	}{
+		Hash: hash,
+	}
+
+	const q = `
+	SELECT
+		token_id, user_id, purpose, token_hash, date_expires, date_used, date_created
+	FROM
+		user_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	var row tokenRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.Token{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.Token{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusToken(row)
+}
+
+func (s *Store) QueryLatestToken(ctx context.Context, userID uuid.UUID, purpose userbus.Purpose) (userbus.Token, error) {
+	data := struct {
+		UserID  string `db:"user_id"`
+		Purpose string `db:"purpose"`
+	}{
+		UserID:  userID.String(),
+		Purpose: purpose.String(),
+	}
+
+	const q = `
+	SELECT
+		token_id, user_id, purpose, token_hash, date_expires, date_used, date_created
+	FROM
+		user_tokens
+	WHERE
+		user_id = :user_id AND purpose = :purpose
+	ORDER BY
+		date_created DESC
+	LIMIT 1`
+
+	var row tokenRow
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
+		if errors.Is(err, sqldb.ErrDBNotFound) {
+			return userbus.Token{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
+		}
+		return userbus.Token{}, fmt.Errorf("db: %w", err)
+	}
+
+	return toBusToken(row)
+}
@@ -2,17 +2,24 @@
 package userdb
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sort"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
 	"net/mail"
 )
 
+// ChangeChannel is the channel notified with the id of a user when a change
+// affects their sessions.
+const ChangeChannel = "user_changed"
+
 // Store manages the set of APIs for user database access.
 type Store struct {
 	log *logger.Logger
@@ -27,12 +34,28 @@ func NewStore(log *logger.Logger, db *sqlx.DB) *Store {
 	}
 }
 
+// NewWithTx constructs a new Store value replacing the sqlx DB
+// value with a sqlx DB value that is currently inside a transaction.
+func (s *Store) NewWithTx(tx sqldb.CommitRollbacker) (userbus.Storer, error) {
+	ec, err := sqldb.GetExtContext(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	store := Store{
+		log: s.log,
+		db:  ec,
+	}
+
+	return &store, nil
+}
+
 func (s *Store) Create(ctx context.Context, user userbus.User) error {
 	const q = `
 	INSERT INTO users
-		(user_id, name, email, password_hash, roles, enabled, email_confirm_token, date_created, date_updated)
+		(user_id, name, email, password_hash, roles, enabled, email_confirmed, sessions_valid_after, token_version, version, date_created, date_updated)
 	VALUES
-		(:user_id, :name, :email, :password_hash, :roles, :enabled, :email_confirm_token, :date_created, :date_updated)`
+		(:user_id, :name, :email, :password_hash, :roles, :enabled, :email_confirmed, :sessions_valid_after, :token_version, :version, :date_created, :date_updated)`
 
 	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBUser(user)); err != nil {
 		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
@@ -54,61 +77,121 @@ func (s *Store) Update(ctx context.Context, user userbus.User) error {
 		"roles" = :roles,
 		"password_hash" = :password_hash,
 		"enabled" = :enabled,
-		"email_confirm_token" = :email_confirm_token,
+		"email_confirmed" = :email_confirmed,
+		"sessions_valid_after" = :sessions_valid_after,
+		"token_version" = :token_version,
+		"version" = version + 1,
 		"date_updated" = :date_updated
 	WHERE
-		user_id = :user_id`
+		user_id = :user_id AND version = :version
+	RETURNING
+		version`
 
-	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, toDBUser(user)); err != nil {
-		if errors.Is(err, sqldb.ErrDBDuplicatedEntry) {
+	var row struct {
+		Version int `db:"version"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, toDBUser(user), &row); err != nil {
+		switch {
+		case errors.Is(err, sqldb.ErrDBNotFound):
+			return fmt.Errorf("namedquerystruct: %w", userbus.ErrConflict)
+		case errors.Is(err, sqldb.ErrDBDuplicatedEntry):
 			return userbus.ErrUniqueEmail
 		}
-		return fmt.Errorf("namedexeccontext: %w", err)
+		return fmt.Errorf("namedquerystruct: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
+// NotifyChanged notifies the listeners of ChangeChannel of the user. Inside
+// a transaction the notification is only delivered when it commits.
+func (s *Store) NotifyChanged(ctx context.Context, userID uuid.UUID) error {
 	data := struct {
-		ID string `db:"user_id"`
+		Channel string `db:"channel"`
+		UserID  string `db:"user_id"`
 	}{
-		ID: userID.String(),
+		Channel: ChangeChannel,
+		UserID:  userID.String(),
 	}
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, enabled, email_confirm_token, date_created, date_updated
+		pg_notify(:channel, :user_id)`
+
+	if err := sqldb.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexeccontext: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Query(ctx context.Context, filter userbus.QueryFilter, sortBy sort.By, page page.Page) ([]userbus.User, error) {
+	data := map[string]any{
+		"offset":        (page.Number() - 1) * page.RowsPerPage(),
+		"rows_per_page": page.RowsPerPage(),
+	}
+
+	const q = `
+	SELECT
+        user_id, name, email, password_hash, roles, enabled, email_confirmed, sessions_valid_after, token_version, version, date_created, date_updated
 	FROM
-		users
-	WHERE 
-		user_id = :user_id`
+		users`
 
-	var row userRow
-	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
-		if errors.Is(err, sqldb.ErrDBNotFound) {
-			return userbus.User{}, fmt.Errorf("db: %w", userbus.ErrNotFound)
-		}
-		return userbus.User{}, fmt.Errorf("db: %w", err)
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	orderByClause, err := orderByClause(sortBy)
+	if err != nil {
+		return nil, err
 	}
 
-	return toBusUser(row)
+	buf.WriteString(orderByClause)
+	buf.WriteString(" OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY")
+
+	var rows []userRow
+	if err := sqldb.NamedQuerySlice(ctx, s.log, s.db, buf.String(), data, &rows); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toBusUsers(rows)
 }
 
-func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
+func (s *Store) Count(ctx context.Context, filter userbus.QueryFilter) (int, error) {
+	data := map[string]any{}
+
+	const q = `
+	SELECT
+		count(1)
+	FROM
+		users`
+
+	buf := bytes.NewBufferString(q)
+	applyFilter(filter, data, buf)
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, buf.String(), data, &count); err != nil {
+		return 0, fmt.Errorf("db: %w", err)
+	}
+
+	return count.Count, nil
+}
+
+func (s *Store) QueryByID(ctx context.Context, userID uuid.UUID) (userbus.User, error) {
 	data := struct {
-		Email string `db:"email"`
+		ID string `db:"user_id"`
 	}{
-		Email: email.Address,
+		ID: userID.String(),
 	}
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, enabled, email_confirm_token, date_created, date_updated
+        user_id, name, email, password_hash, roles, enabled, email_confirmed, sessions_valid_after, token_version, version, date_created, date_updated
 	FROM
 		users
-	WHERE
-		email = :email`
+	WHERE 
+		user_id = :user_id`
 
 	var row userRow
 	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
@@ -121,20 +204,20 @@ func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.U
 	return toBusUser(row)
 }
 
-func (s *Store) QueryByEmailConfirmToken(ctx context.Context, emailConfirmToken string) (userbus.User, error) {
+func (s *Store) QueryByEmail(ctx context.Context, email mail.Address) (userbus.User, error) {
 	data := struct {
-		EmailConfirmToken string `db:"email_confirm_token"`
+		Email string `db:"email"`
 	}{
-		EmailConfirmToken: emailConfirmToken,
+		Email: email.Address,
 	}
 
 	const q = `
 	SELECT
-        user_id, name, email, password_hash, roles, enabled, email_confirm_token, date_created, date_updated
+        user_id, name, email, password_hash, roles, enabled, email_confirmed, sessions_valid_after, token_version, version, date_created, date_updated
 	FROM
 		users
 	WHERE
-		email_confirm_token = :email_confirm_token`
+		email = :email`
 
 	var row userRow
 	if err := sqldb.NamedQueryStruct(ctx, s.log, s.db, q, data, &row); err != nil {
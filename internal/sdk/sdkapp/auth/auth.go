@@ -10,10 +10,14 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeybus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/apikey/apikeystore/apikeydb"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userstore/userdb"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"slices"
 	"strings"
+	"time"
 )
 
 // ErrForbidden is returned when a auth issue is identified.
@@ -22,13 +26,21 @@ var ErrForbidden = errors.New("attempted action is not allowed")
 // Claims represents the authorization claims transmitted via a JWT.
 type Claims struct {
 	jwt.RegisteredClaims
-	Roles []string `json:"roles"`
+	Roles        []string `json:"roles"`
+	TokenVersion int      `json:"token_version"`
+
+	// APIKey is set when a service authenticated with an API key instead of
+	// a user with a token, the subject is the id of the key. The service
+	// holds the permissions of the key rather than roles.
+	APIKey      bool         `json:"-"`
+	Permissions []Permission `json:"-"`
 }
 
 // KeyLookup declares a method set of behavior for looking up
 // private and public keys for JWT use. The return could be a
 // PEM encoded string or a JWS based key.
 type KeyLookup interface {
+	ActiveKID() string
 	PrivateKey(kid string) (key string, err error)
 	PublicKey(kid string) (key string, err error)
 }
@@ -38,7 +50,21 @@ type Config struct {
 	Log       *logger.Logger
 	DB        *sqlx.DB
 	KeyLookup KeyLookup
-	Issuer    string
+	// Issuer is stamped on the tokens we issue and expected on the tokens
+	// we authenticate.
+	Issuer string
+	// Audiences are stamped on the tokens we issue, a token is accepted when
+	// it is intended for any of them. Any audience is accepted when empty.
+	Audiences []string
+	// Leeway is the clock skew tolerated when checking the time claims.
+	Leeway time.Duration
+	// UserCacheTTL is how long the validity of a user is cached, the cache
+	// is disabled when 0. UserCacheSize bounds the number of users cached.
+	UserCacheTTL  time.Duration
+	UserCacheSize int
+	// RolePermissions are the permissions granted by each role, the
+	// defaults are used when nil.
+	RolePermissions RolePermissions
 }
 
 // Auth is used to authenticate clients. It can generate a token for a
@@ -46,25 +72,63 @@ type Config struct {
 type Auth struct {
 	keyLookup KeyLookup
 	userBus   *userbus.Business
-	method    jwt.SigningMethod
+	apiKeyBus *apikeybus.Business
 	parser    *jwt.Parser
+	parsers   map[string]*jwt.Parser
 	issuer    string
+	audiences []string
+	leeway    time.Duration
+	rolePerms map[string]map[Permission]bool
+	userCache *userCache
+	now       func() time.Time
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(cfg Config) (*Auth, error) {
-	// If a database connection is not provided, we won't perform the user enabled check.
+	// If a database connection is not provided, we won't perform the user
+	// enabled check and API keys can't be used.
 	var userBus *userbus.Business
+	var apiKeyBus *apikeybus.Business
 	if cfg.DB != nil {
 		userBus = userbus.NewBusiness(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
+		apiKeyBus = apikeybus.NewBusiness(cfg.Log, apikeydb.NewStore(cfg.Log, cfg.DB))
+	}
+
+	rp := cfg.RolePermissions
+	if rp == nil {
+		rp = DefaultRolePermissions()
+	}
+
+	rolePerms := make(map[string]map[Permission]bool, len(rp))
+	for role, perms := range rp {
+		rolePerms[role] = make(map[Permission]bool, len(perms))
+		for _, p := range perms {
+			rolePerms[role][p] = true
+		}
+	}
+
+	// Every key only verifies tokens signed with the method of its type,
+	// a token can't pick another one to confuse us about the key.
+	parsers := make(map[string]*jwt.Parser, len(signingMethods))
+	for _, m := range signingMethods {
+		parsers[m.Alg()] = jwt.NewParser(jwt.WithValidMethods([]string{m.Alg()}), jwt.WithoutClaimsValidation())
 	}
 
 	a := Auth{
 		keyLookup: cfg.KeyLookup,
 		userBus:   userBus,
-		method:    jwt.GetSigningMethod(jwt.SigningMethodRS256.Name),
-		parser:    jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name})),
+		apiKeyBus: apiKeyBus,
+		parser:    jwt.NewParser(),
+		parsers:   parsers,
 		issuer:    cfg.Issuer,
+		audiences: cfg.Audiences,
+		leeway:    cfg.Leeway,
+		rolePerms: rolePerms,
+		now:       time.Now,
+	}
+
+	if cfg.UserCacheTTL > 0 && cfg.UserCacheSize > 0 {
+		a.userCache = newUserCache(cfg.UserCacheTTL, cfg.UserCacheSize)
 	}
 
 	return &a, nil
@@ -75,21 +139,32 @@ func (a *Auth) Issuer() string {
 	return a.issuer
 }
 
-// GenerateToken generates a signed JWT token string representing the user Claims.
-func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = kid
+// Audiences provides the configured audiences stamped on the tokens we issue.
+func (a *Auth) Audiences() []string {
+	return a.audiences
+}
+
+// ActiveKID provides the id of the key new tokens are signed with.
+func (a *Auth) ActiveKID() string {
+	return a.keyLookup.ActiveKID()
+}
 
+// GenerateToken generates a signed JWT token string representing the user
+// Claims. The signing method follows from the type of the key.
+func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
 	privateKeyPEM, err := a.keyLookup.PrivateKey(kid)
 	if err != nil {
 		return "", fmt.Errorf("private key: %w", err)
 	}
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+	privateKey, method, err := parsePrivateKey(privateKeyPEM)
 	if err != nil {
 		return "", fmt.Errorf("parsing private pem: %w", err)
 	}
 
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
 	str, err := token.SignedString(privateKey)
 	if err != nil {
 		return "", fmt.Errorf("signing token: %w", err)
@@ -99,9 +174,10 @@ func (a *Auth) GenerateToken(kid string, claims Claims) (string, error) {
 }
 
 // Authenticate processes the token to validate the sender's token is valid.
+// A rejected token is reported as a *TokenError telling why.
 func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, error) {
 	if !strings.HasPrefix(bearerToken, "Bearer ") {
-		return Claims{}, errors.New("expected authorization header format: Bearer <token>")
+		return Claims{}, newTokenError(Reasons.Malformed, "expected authorization header format: Bearer <token>")
 	}
 
 	jwtTokenStr := bearerToken[7:]
@@ -109,81 +185,239 @@ func (a *Auth) Authenticate(ctx context.Context, bearerToken string) (Claims, er
 	var claims Claims
 	token, _, err := a.parser.ParseUnverified(jwtTokenStr, &claims)
 	if err != nil {
-		return Claims{}, fmt.Errorf("error parsing token: %w", err)
+		return Claims{}, newTokenError(Reasons.Malformed, "error parsing token: %w", err)
 	}
 
 	kidRaw, exists := token.Header["kid"]
 	if !exists {
-		return Claims{}, fmt.Errorf("kid missing from header: %w", err)
+		return Claims{}, newTokenError(Reasons.Malformed, "kid missing from header")
 	}
 
 	kid, ok := kidRaw.(string)
 	if !ok {
-		return Claims{}, fmt.Errorf("kid malformed: %w", err)
+		return Claims{}, newTokenError(Reasons.Malformed, "kid malformed")
 	}
 
 	pem, err := a.keyLookup.PublicKey(kid)
 	if err != nil {
-		return Claims{}, fmt.Errorf("failed to fetch public key: %w", err)
+		return Claims{}, newTokenError(Reasons.Invalid, "failed to fetch public key: %w", err)
+	}
+
+	publicKey, method, err := parsePublicKey(pem)
+	if err != nil {
+		return Claims{}, fmt.Errorf("parsing public pem: %w", err)
+	}
+
+	if token.Method.Alg() != method.Alg() {
+		return Claims{}, newTokenError(Reasons.Invalid, "token signed with %s, key %s verifies %s", token.Method.Alg(), kid, method.Alg())
 	}
 
-	_, err = a.parser.Parse(jwtTokenStr, func(token *jwt.Token) (interface{}, error) {
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+	_, err = a.parsers[method.Alg()].Parse(jwtTokenStr, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
 	})
 	if err != nil {
-		return Claims{}, fmt.Errorf("authentication failed : %w", err)
+		return Claims{}, newTokenError(Reasons.Invalid, "authentication failed : %w", err)
+	}
+
+	if err := a.validateClaims(claims); err != nil {
+		return Claims{}, err
 	}
 
 	// Check the database for this user to verify they are still enabled.
 	if err := a.isValidUser(ctx, claims); err != nil {
-		return Claims{}, fmt.Errorf("invalid user : %w", err)
+		return Claims{}, newTokenError(Reasons.Revoked, "invalid user : %w", err)
 	}
 
 	return claims, nil
 }
 
-// Authorize attempts to authorize the user with the provided input roles, if
-// none of the input roles are within the user's claims, we return an error
-// otherwise the user is authorized.
-func (a *Auth) Authorize(_ context.Context, claims Claims, userID uuid.UUID, rule Rule) error {
-	var (
-		roles userbus.RolesList
-		err   error
-	)
-	roles, err = userbus.ParseRoles(claims.Roles)
+// AuthenticateAPIKey processes the API key of a service and returns the
+// claims of its service principal. A rejected key is reported as a
+// *TokenError telling why.
+func (a *Auth) AuthenticateAPIKey(ctx context.Context, authorization string) (Claims, error) {
+	if !strings.HasPrefix(authorization, "ApiKey ") {
+		return Claims{}, newTokenError(Reasons.Malformed, "expected authorization header format: ApiKey <key>")
+	}
+
+	if a.apiKeyBus == nil {
+		return Claims{}, newTokenError(Reasons.Invalid, "api keys are not supported")
+	}
+
+	key, err := a.apiKeyBus.Authenticate(ctx, authorization[7:])
 	if err != nil {
-		return fmt.Errorf("parsing roles: %w", err)
+		switch {
+		case errors.Is(err, apikeybus.ErrInvalidKey):
+			return Claims{}, newTokenError(Reasons.Invalid, "%w", err)
+		case errors.Is(err, apikeybus.ErrExpired):
+			return Claims{}, newTokenError(Reasons.Expired, "%w", err)
+		case errors.Is(err, apikeybus.ErrRevoked):
+			return Claims{}, newTokenError(Reasons.Revoked, "%w", err)
+		}
+		return Claims{}, fmt.Errorf("authenticate api key: %w", err)
+	}
+
+	perms, err := ParsePermissions(key.Permissions)
+	if err != nil {
+		return Claims{}, fmt.Errorf("keyID[%s]: %w", key.ID, err)
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: key.ID.String(),
+			Issuer:  a.issuer,
+		},
+		APIKey:      true,
+		Permissions: perms,
+	}
+
+	return claims, nil
+}
+
+// validateClaims checks the token was issued by us, for us, and is valid at
+// this time give or take the leeway. Tokens without expiration are refused.
+func (a *Auth) validateClaims(claims Claims) error {
+	now := a.now()
+
+	if claims.ExpiresAt == nil {
+		return newTokenError(Reasons.Invalid, "exp is missing")
+	}
+
+	if now.After(claims.ExpiresAt.Add(a.leeway)) {
+		return newTokenError(Reasons.Expired, "expired at %s", claims.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	if claims.NotBefore != nil && now.Add(a.leeway).Before(claims.NotBefore.Time) {
+		return newTokenError(Reasons.NotYetValid, "not valid before %s", claims.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	if claims.IssuedAt != nil && now.Add(a.leeway).Before(claims.IssuedAt.Time) {
+		return newTokenError(Reasons.Invalid, "issued in the future at %s", claims.IssuedAt.UTC().Format(time.RFC3339))
+	}
+
+	if claims.Issuer != a.issuer {
+		return newTokenError(Reasons.Invalid, "issuer %q is not %q", claims.Issuer, a.issuer)
+	}
+
+	if len(a.audiences) > 0 && !a.intendedForUs(claims.Audience) {
+		return newTokenError(Reasons.Invalid, "audience %q is not one of %q", []string(claims.Audience), a.audiences)
+	}
+
+	return nil
+}
+
+// intendedForUs reports whether any audience of the token is one of ours.
+func (a *Auth) intendedForUs(audience jwt.ClaimStrings) bool {
+	for _, aud := range audience {
+		for _, ours := range a.audiences {
+			if aud == ours {
+				return true
+			}
+		}
 	}
 
-	switch rule {
-	case Rules.Any:
-		if !roles.Contains(userbus.Roles.Admin) && !roles.Contains(userbus.Roles.User) {
-			return fmt.Errorf("rule_any: %w", ErrForbidden)
+	return false
+}
+
+// Authorize checks the claims satisfy the rule. The owner of the resource is
+// identified by ownerID, it is the zero value when the route has no
+// resource.
+func (a *Auth) Authorize(_ context.Context, claims Claims, ownerID uuid.UUID, rule Rule) error {
+	if rule.owner && ownerID != uuid.Nil && claims.Subject == ownerID.String() {
+		return nil
+	}
+
+	if rule.owner && len(rule.permissions) == 0 {
+		return fmt.Errorf("%s: %w", rule, ErrForbidden)
+	}
+
+	for _, p := range rule.permissions {
+		holds, err := a.Holds(claims, p)
+		if err != nil {
+			return err
 		}
-	case Rules.Admin:
-		if !roles.Contains(userbus.Roles.Admin) {
-			return fmt.Errorf("rule_admin_only: %w", ErrForbidden)
+
+		if !holds {
+			return fmt.Errorf("%s: missing %s: %w", rule, p, ErrForbidden)
 		}
-	case Rules.User:
-		if !roles.Contains(userbus.Roles.User) {
-			return fmt.Errorf("rule_user_only: %w", ErrForbidden)
+	}
+
+	return nil
+}
+
+// Holds reports whether the caller of the claims holds the permission,
+// through their roles or the API key they authenticated with.
+func (a *Auth) Holds(claims Claims, permission Permission) (bool, error) {
+	if claims.APIKey {
+		return slices.Contains(claims.Permissions, permission), nil
+	}
+
+	roles, err := userbus.ParseRoles(claims.Roles)
+	if err != nil {
+		return false, fmt.Errorf("parsing roles: %w", err)
+	}
+
+	return a.HasPermission(roles, permission), nil
+}
+
+// Outranks reports whether the caller of the claims holds every permission
+// granted by the roles, so changing a user holding them can't reach beyond
+// the permissions of the caller.
+func (a *Auth) Outranks(claims Claims, roles []userbus.Role) (bool, error) {
+	for _, role := range roles {
+		for p := range a.rolePerms[role.String()] {
+			holds, err := a.Holds(claims, p)
+			if err != nil {
+				return false, err
+			}
+
+			if !holds {
+				return false, nil
+			}
 		}
-	case Rules.Owner:
-		if claims.Subject != userID.String() {
-			return fmt.Errorf("rule_owner: %w", ErrForbidden)
+	}
+
+	return true, nil
+}
+
+// HasPermission reports whether any of the roles grants the permission.
+func (a *Auth) HasPermission(roles []userbus.Role, permission Permission) bool {
+	for _, role := range roles {
+		if a.rolePerms[role.String()][permission] {
+			return true
 		}
-	case Rules.AdminOrOwner:
-		if !roles.Contains(userbus.Roles.Admin) && claims.Subject != userID.String() {
-			return fmt.Errorf("rule_admin_or_owner: %w", ErrForbidden)
+	}
+
+	return false
+}
+
+// Grants reports whether the roles hold every permission granted by role.
+func (a *Auth) Grants(roles []userbus.Role, role userbus.Role) bool {
+	for p := range a.rolePerms[role.String()] {
+		if !a.HasPermission(roles, p) {
+			return false
 		}
-	default:
-		return fmt.Errorf("unknown rule: %s", rule)
 	}
 
-	return nil
+	return true
+}
+
+// InvalidateUser forgets the cached validity of the user, the next token of
+// the user is checked against the database.
+func (a *Auth) InvalidateUser(userID uuid.UUID) {
+	if a.userCache != nil {
+		a.userCache.invalidate(userID)
+	}
+}
+
+// InvalidateUsers forgets the cached validity of every user.
+func (a *Auth) InvalidateUsers() {
+	if a.userCache != nil {
+		a.userCache.purge()
+	}
 }
 
-// isValidUser checks the user is still valid in the system: not disabled and email confirmed.
+// isValidUser checks the user is still valid in the system: not disabled, email confirmed
+// and the session not revoked.
 // If userBus is not provided, we skip this check.
 func (a *Auth) isValidUser(ctx context.Context, claims Claims) error {
 	if a.userBus == nil {
@@ -195,18 +429,65 @@ func (a *Auth) isValidUser(ctx context.Context, claims Claims) error {
 		return fmt.Errorf("parse user: %w", err)
 	}
 
-	usr, err := a.userBus.QueryByID(ctx, userID)
+	uv, err := a.userValidity(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("query user: %w", err)
+		return err
 	}
 
-	if !usr.Enabled {
+	if !uv.enabled {
 		return fmt.Errorf("user disabled")
 	}
 
-	if usr.EmailConfirmToken != "" {
+	if !uv.emailConfirmed {
 		return fmt.Errorf("user not confirm email")
 	}
 
+	// The token version catches tokens issued in the same second the
+	// sessions were revoked, iat only has a precision of seconds. Tokens
+	// issued before versions were introduced carry none, they hold the
+	// version every user started at.
+	tokenVersion := claims.TokenVersion
+	if tokenVersion == 0 {
+		tokenVersion = userbus.InitialTokenVersion
+	}
+
+	if tokenVersion < uv.tokenVersion {
+		return fmt.Errorf("session revoked: token version %d", claims.TokenVersion)
+	}
+
+	if !uv.sessionsValidAfter.IsZero() {
+		if claims.IssuedAt == nil || claims.IssuedAt.Time.Before(uv.sessionsValidAfter.Truncate(time.Second)) {
+			return fmt.Errorf("session revoked: issued at %v", claims.IssuedAt)
+		}
+	}
+
 	return nil
 }
+
+// userValidity returns the validity of the user from the cache, or from the
+// database when it is not cached.
+func (a *Auth) userValidity(ctx context.Context, userID uuid.UUID) (userValidity, error) {
+	if a.userCache != nil {
+		if uv, found := a.userCache.get(userID); found {
+			return uv, nil
+		}
+	}
+
+	usr, err := a.userBus.QueryByID(ctx, userID)
+	if err != nil {
+		return userValidity{}, fmt.Errorf("query user: %w", err)
+	}
+
+	uv := userValidity{
+		enabled:            usr.Enabled,
+		emailConfirmed:     usr.EmailConfirmed,
+		tokenVersion:       usr.TokenVersion,
+		sessionsValidAfter: usr.SessionsValidAfter,
+	}
+
+	if a.userCache != nil {
+		a.userCache.set(userID, uv)
+	}
+
+	return uv, nil
+}
@@ -3,10 +3,21 @@ package auth_test
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"strings"
 	"testing"
 	"time"
 
@@ -59,19 +70,19 @@ func test1(ath *auth.Auth) func(t *testing.T) {
 
 		userID := uuid.MustParse(claims.Subject)
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Admin)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require(auth.Permissions.UserRoles))
 		if err != nil {
-			t.Errorf("Should be able to authorize the Roles.Admin claims : %s", err)
+			t.Errorf("Should be able to authorize the user:roles permission with Roles.Admin : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.User)
+		err = ath.Authorize(context.Background(), parsedClaims, uuid.New(), auth.Owner())
 		if err == nil {
-			t.Error("Should NOT be able to authorize the Roles.User claim")
+			t.Error("Should NOT be able to authorize the owner rule for another user with Roles.Admin")
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.AdminOrOwner)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.OwnerOr(auth.Permissions.UserRead))
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAdminOrSubject claim with Roles.Admin only : %s", err)
+			t.Errorf("Should be able to authorize the owner or user:read rule with Roles.Admin only : %s", err)
 		}
 	}
 
@@ -102,24 +113,24 @@ func test2(ath *auth.Auth) func(t *testing.T) {
 
 		userID := uuid.MustParse(claims.Subject)
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.User)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Owner())
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleUserOnly claim with Roles.User only : %s", err)
+			t.Errorf("Should be able to authorize the owner rule with Roles.User only : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Admin)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require(auth.Permissions.UserRoles))
 		if err == nil {
-			t.Error("Should NOT be able to authorize the RuleAdminOnly claim with Roles.User only")
+			t.Error("Should NOT be able to authorize the user:roles permission with Roles.User only")
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.AdminOrOwner)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.OwnerOr(auth.Permissions.UserRead))
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAdminOrSubject claim with Roles.User only : %s", err)
+			t.Errorf("Should be able to authorize the owner or user:read rule with Roles.User only : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Any)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require())
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAny any claim with Roles.User only : %s", err)
+			t.Errorf("Should be able to authorize the authenticated rule with Roles.User only : %s", err)
 		}
 	}
 
@@ -150,9 +161,9 @@ func test3(ath *auth.Auth) func(t *testing.T) {
 
 		userID := uuid.MustParse("9e979baa-61c9-4b50-81f2-f216d53f5c15")
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.AdminOrOwner)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.OwnerOr(auth.Permissions.UserRead))
 		if err == nil {
-			t.Error("Should NOT be able to authorize the RuleAdminOrSubject claim with Roles.User only and different userID")
+			t.Error("Should NOT be able to authorize the owner or user:read rule with Roles.User only and different userID")
 		}
 	}
 
@@ -182,9 +193,9 @@ func test4(ath *auth.Auth) func(t *testing.T) {
 			t.Fatalf("Should be able to authenticate the claims : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Any)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require())
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAny any claim with Roles.User and Roles.Admin : %s", err)
+			t.Errorf("Should be able to authorize the authenticated rule with Roles.User and Roles.Admin : %s", err)
 		}
 	}
 
@@ -214,9 +225,9 @@ func test5(ath *auth.Auth) func(t *testing.T) {
 			t.Fatalf("Should be able to authenticate the claims : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Any)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require())
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAny any claim with Roles.User only : %s", err)
+			t.Errorf("Should be able to authorize the authenticated rule with Roles.User only : %s", err)
 		}
 	}
 
@@ -246,15 +257,353 @@ func test6(ath *auth.Auth) func(t *testing.T) {
 			t.Fatalf("Should be able to authenticate the claims : %s", err)
 		}
 
-		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Rules.Any)
+		err = ath.Authorize(context.Background(), parsedClaims, userID, auth.Require())
 		if err != nil {
-			t.Errorf("Should be able to authorize the RuleAny any claim with Roles.Admin only : %s", err)
+			t.Errorf("Should be able to authorize the authenticated rule with Roles.Admin only : %s", err)
 		}
 	}
 
 	return f
 }
 
+func Test_Authorize(t *testing.T) {
+	ath, err := auth.New(auth.Config{
+		Log:       newUnit(t),
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	self := uuid.MustParse("97ee07e2-ebbb-4c69-a681-d5fe165c2cb9")
+	other := uuid.MustParse("9e979baa-61c9-4b50-81f2-f216d53f5c15")
+
+	var (
+		admin    = userbus.Roles.Admin
+		user     = userbus.Roles.User
+		catalog  = userbus.Roles.CatalogManager
+		fulfill  = userbus.Roles.OrderFulfiller
+		support  = userbus.Roles.SupportAgent
+		everyone = []userbus.Role{admin, user, catalog, fulfill, support}
+	)
+
+	tests := []struct {
+		name    string
+		rule    auth.Rule
+		owner   uuid.UUID
+		allowed []userbus.Role
+	}{
+		{name: "authenticated", rule: auth.Require(), owner: uuid.Nil, allowed: everyone},
+		{name: "owner of own", rule: auth.Owner(), owner: self, allowed: everyone},
+		{name: "owner of other", rule: auth.Owner(), owner: other, allowed: nil},
+		{name: "owner without resource", rule: auth.Owner(), owner: uuid.Nil, allowed: nil},
+		{name: "product:write", rule: auth.Require(auth.Permissions.ProductWrite), allowed: []userbus.Role{admin, catalog}},
+		{name: "product:export", rule: auth.Require(auth.Permissions.ProductExport), allowed: []userbus.Role{admin, catalog}},
+		{name: "stock:read", rule: auth.Require(auth.Permissions.StockRead), allowed: []userbus.Role{admin, catalog, fulfill}},
+		{name: "stock:write", rule: auth.Require(auth.Permissions.StockWrite), allowed: []userbus.Role{admin, catalog}},
+		{name: "order:read", rule: auth.Require(auth.Permissions.OrderRead), allowed: []userbus.Role{admin, fulfill, support}},
+		{name: "order:update_status", rule: auth.Require(auth.Permissions.OrderUpdateStatus), allowed: []userbus.Role{admin, fulfill}},
+		{name: "order:delete", rule: auth.Require(auth.Permissions.OrderDelete), allowed: []userbus.Role{admin}},
+		{name: "review:read", rule: auth.Require(auth.Permissions.ReviewRead), allowed: []userbus.Role{admin, catalog, support}},
+		{name: "review:moderate", rule: auth.Require(auth.Permissions.ReviewModerate), allowed: []userbus.Role{admin, catalog}},
+		{name: "user:read", rule: auth.Require(auth.Permissions.UserRead), allowed: []userbus.Role{admin, support}},
+		{name: "user:update", rule: auth.Require(auth.Permissions.UserUpdate), allowed: []userbus.Role{admin, support}},
+		{name: "user:roles", rule: auth.Require(auth.Permissions.UserRoles), allowed: []userbus.Role{admin}},
+		{name: "every permission", rule: auth.Require(auth.Permissions.OrderRead, auth.Permissions.StockWrite), allowed: []userbus.Role{admin}},
+		{name: "own order or order:read", rule: auth.OwnerOr(auth.Permissions.OrderRead), owner: self, allowed: everyone},
+		{name: "other order or order:read", rule: auth.OwnerOr(auth.Permissions.OrderRead), owner: other, allowed: []userbus.Role{admin, fulfill, support}},
+		{name: "other review or review:delete", rule: auth.OwnerOr(auth.Permissions.ReviewDelete), owner: other, allowed: []userbus.Role{admin, catalog}},
+	}
+
+	for _, tt := range tests {
+		for _, role := range everyone {
+			t.Run(tt.name+"/"+role.String(), func(t *testing.T) {
+				claims := auth.Claims{
+					RegisteredClaims: jwt.RegisteredClaims{Subject: self.String()},
+					Roles:            []string{role.String()},
+				}
+
+				want := userbus.RolesList(tt.allowed).Contains(role)
+
+				err := ath.Authorize(context.Background(), claims, tt.owner, tt.rule)
+				if want && err != nil {
+					t.Errorf("Should be able to authorize %s for %s : %s", role, tt.rule, err)
+				}
+				if !want && !errors.Is(err, auth.ErrForbidden) {
+					t.Errorf("Should NOT be able to authorize %s for %s, got %v", role, tt.rule, err)
+				}
+			})
+		}
+	}
+
+	t.Run("configured permissions", func(t *testing.T) {
+		rp, err := auth.ParseRolePermissions([]string{"SUPPORT_AGENT=order:read, order:update_status", "CATALOG_MANAGER="})
+		if err != nil {
+			t.Fatalf("Should be able to parse the role permissions : %s", err)
+		}
+
+		ath, err := auth.New(auth.Config{Log: newUnit(t), KeyLookup: &keyStore{}, RolePermissions: rp})
+		if err != nil {
+			t.Fatalf("Should be able to create an authenticator: %s", err)
+		}
+
+		if !ath.HasPermission([]userbus.Role{support}, auth.Permissions.OrderUpdateStatus) {
+			t.Error("Should grant the configured permission")
+		}
+		if ath.HasPermission([]userbus.Role{support}, auth.Permissions.UserRead) {
+			t.Error("Should NOT grant a default permission replaced by the configuration")
+		}
+		if ath.HasPermission([]userbus.Role{catalog}, auth.Permissions.ProductWrite) {
+			t.Error("Should NOT grant any permission to a role configured without permissions")
+		}
+		if !ath.Grants([]userbus.Role{admin}, support) || ath.Grants([]userbus.Role{support}, admin) {
+			t.Error("Should only grant roles with permissions the granting roles hold")
+		}
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: uuid.NewString()},
+			Roles:            []string{admin.String()},
+			APIKey:           true,
+			Permissions:      []auth.Permission{auth.Permissions.OrderRead, auth.Permissions.OrderUpdateStatus},
+		}
+
+		if err := ath.Authorize(context.Background(), claims, uuid.Nil, auth.Require(auth.Permissions.OrderRead, auth.Permissions.OrderUpdateStatus)); err != nil {
+			t.Errorf("Should be able to authorize the permissions of the key : %s", err)
+		}
+		if err := ath.Authorize(context.Background(), claims, uuid.Nil, auth.Require(auth.Permissions.OrderDelete)); !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("Should NOT be able to authorize a permission the key lacks, even with roles, got %v", err)
+		}
+		if err := ath.Authorize(context.Background(), claims, self, auth.Owner()); !errors.Is(err, auth.ErrForbidden) {
+			t.Errorf("Should NOT be able to authorize a key as the owner of a user resource, got %v", err)
+		}
+	})
+
+	t.Run("outranks", func(t *testing.T) {
+		claimsOf := func(roles ...userbus.Role) auth.Claims {
+			return auth.Claims{Roles: userbus.ParseRolesToString(roles)}
+		}
+
+		tests := []struct {
+			name   string
+			claims auth.Claims
+			roles  []userbus.Role
+			want   bool
+		}{
+			{name: "admin on admin", claims: claimsOf(admin), roles: []userbus.Role{admin}, want: true},
+			{name: "admin on support", claims: claimsOf(admin), roles: []userbus.Role{support, user}, want: true},
+			{name: "support on user", claims: claimsOf(support), roles: []userbus.Role{user}, want: true},
+			{name: "support on support", claims: claimsOf(support), roles: []userbus.Role{support}, want: true},
+			{name: "support on admin", claims: claimsOf(support), roles: []userbus.Role{admin}, want: false},
+			{name: "support on catalog", claims: claimsOf(support), roles: []userbus.Role{user, catalog}, want: false},
+			{name: "key on user", claims: auth.Claims{APIKey: true, Permissions: []auth.Permission{auth.Permissions.UserUpdate}}, roles: []userbus.Role{user}, want: true},
+			{name: "key on support", claims: auth.Claims{APIKey: true, Permissions: []auth.Permission{auth.Permissions.UserUpdate}}, roles: []userbus.Role{support}, want: false},
+		}
+
+		for _, tt := range tests {
+			got, err := ath.Outranks(tt.claims, tt.roles)
+			if err != nil {
+				t.Fatalf("%s: Should be able to compare the permissions : %s", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s: Should outrank %t, got %t", tt.name, tt.want, got)
+			}
+		}
+	})
+
+	t.Run("invalid configured permissions", func(t *testing.T) {
+		for _, entry := range []string{"SUPPORT_AGENT", "UNKNOWN=order:read", "SUPPORT_AGENT=order:fly"} {
+			if _, err := auth.ParseRolePermissions([]string{entry}); err == nil {
+				t.Errorf("Should NOT be able to parse %q", entry)
+			}
+		}
+	})
+}
+
+func Test_Claims(t *testing.T) {
+	ath, err := auth.New(auth.Config{
+		Log:       newUnit(t),
+		KeyLookup: &keyStore{},
+		Issuer:    "service project",
+		Audiences: []string{"service api", "service admin"},
+		Leeway:    time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	now := time.Now().UTC()
+	at := func(d time.Duration) *jwt.NumericDate {
+		return jwt.NewNumericDate(now.Add(d))
+	}
+
+	tests := []struct {
+		name   string
+		claims jwt.RegisteredClaims
+		reason *auth.Reason
+	}{
+		{name: "valid", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(time.Hour)}},
+		{name: "any audience", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"other", "service admin"}, ExpiresAt: at(time.Hour)}},
+		{name: "expired within leeway", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(-30 * time.Second)}},
+		{name: "not before within leeway", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(time.Hour), NotBefore: at(30 * time.Second)}},
+		{name: "expired", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(-2 * time.Minute)}, reason: &auth.Reasons.Expired},
+		{name: "not before", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(time.Hour), NotBefore: at(2 * time.Minute)}, reason: &auth.Reasons.NotYetValid},
+		{name: "issued in the future", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(time.Hour), IssuedAt: at(2 * time.Minute)}, reason: &auth.Reasons.Invalid},
+		{name: "missing exp", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"service api"}}, reason: &auth.Reasons.Invalid},
+		{name: "wrong issuer", claims: jwt.RegisteredClaims{Issuer: "ecommerce", Audience: jwt.ClaimStrings{"service api"}, ExpiresAt: at(time.Hour)}, reason: &auth.Reasons.Invalid},
+		{name: "wrong audience", claims: jwt.RegisteredClaims{Issuer: "service project", Audience: jwt.ClaimStrings{"other"}, ExpiresAt: at(time.Hour)}, reason: &auth.Reasons.Invalid},
+		{name: "missing audience", claims: jwt.RegisteredClaims{Issuer: "service project", ExpiresAt: at(time.Hour)}, reason: &auth.Reasons.Invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claims.Subject = "97ee07e2-ebbb-4c69-a681-d5fe165c2cb9"
+
+			token, err := ath.GenerateToken(kid, auth.Claims{RegisteredClaims: tt.claims})
+			if err != nil {
+				t.Fatalf("Should be able to generate a JWT : %s", err)
+			}
+
+			_, err = ath.Authenticate(context.Background(), "Bearer "+token)
+
+			if tt.reason == nil {
+				if err != nil {
+					t.Fatalf("Should be able to authenticate the claims : %s", err)
+				}
+				return
+			}
+
+			var tokenErr *auth.TokenError
+			if !errors.As(err, &tokenErr) {
+				t.Fatalf("Should get a token error, got %v", err)
+			}
+
+			if !tokenErr.Reason.Equal(*tt.reason) {
+				t.Errorf("Should be rejected as %s, got %s", tt.reason, tokenErr.Reason)
+			}
+		})
+	}
+
+	_, err = ath.Authenticate(context.Background(), "Basic dXNlcjpwYXNz")
+
+	var tokenErr *auth.TokenError
+	if !errors.As(err, &tokenErr) || !tokenErr.Reason.Equal(auth.Reasons.Malformed) {
+		t.Errorf("Should reject another scheme as malformed, got %v", err)
+	}
+}
+
+func Test_Algorithms(t *testing.T) {
+	ks := keyStores{
+		"rsa":     {privatePEM: privateKeyPEM, publicPEM: publicKeyPEM},
+		"ecdsa":   genKey(t, "ecdsa"),
+		"ed25519": genKey(t, "ed25519"),
+	}
+
+	ath, err := auth.New(auth.Config{
+		Log:       newUnit(t),
+		KeyLookup: ks,
+		Issuer:    "service project",
+	})
+	if err != nil {
+		t.Fatalf("Should be able to create an authenticator: %s", err)
+	}
+
+	claims := auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ath.Issuer(),
+			Subject:   "97ee07e2-ebbb-4c69-a681-d5fe165c2cb9",
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		},
+		Roles: []string{userbus.Roles.User.String()},
+	}
+
+	tests := []struct {
+		kid string
+		alg string
+	}{
+		{kid: "rsa", alg: "RS256"},
+		{kid: "ecdsa", alg: "ES256"},
+		{kid: "ed25519", alg: "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			token, err := ath.GenerateToken(tt.kid, claims)
+			if err != nil {
+				t.Fatalf("Should be able to generate a JWT : %s", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+			if err != nil {
+				t.Fatalf("Should be able to parse the JWT : %s", err)
+			}
+
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("Should sign with %s, got %s", tt.alg, parsed.Method.Alg())
+			}
+
+			parsedClaims, err := ath.Authenticate(context.Background(), "Bearer "+token)
+			if err != nil {
+				t.Fatalf("Should be able to authenticate the claims : %s", err)
+			}
+
+			if parsedClaims.Subject != claims.Subject {
+				t.Errorf("Should get back the subject %s, got %s", claims.Subject, parsedClaims.Subject)
+			}
+		})
+	}
+
+	// Tokens naming a key but signed with another algorithm than the one of
+	// the key must be rejected.
+	t.Run("confusion", func(t *testing.T) {
+		ecdsaToken, err := ath.GenerateToken("ecdsa", claims)
+		if err != nil {
+			t.Fatalf("Should be able to generate a JWT : %s", err)
+		}
+
+		hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		hmacToken.Header["kid"] = "rsa"
+		hmac, err := hmacToken.SignedString([]byte(publicKeyPEM))
+		if err != nil {
+			t.Fatalf("Should be able to sign the HMAC token : %s", err)
+		}
+
+		noneToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+		noneToken.Header["kid"] = "rsa"
+		none, err := noneToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatalf("Should be able to sign the none token : %s", err)
+		}
+
+		ed25519Token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		ed25519Token.Header["kid"] = "ecdsa"
+		edKey, err := jwt.ParseEdPrivateKeyFromPEM([]byte(ks["ed25519"].privatePEM))
+		if err != nil {
+			t.Fatalf("Should be able to parse the Ed25519 key : %s", err)
+		}
+		ed, err := ed25519Token.SignedString(edKey)
+		if err != nil {
+			t.Fatalf("Should be able to sign the EdDSA token : %s", err)
+		}
+
+		tokens := map[string]string{
+			"ES256 token with RSA kid":   swapKID(t, ecdsaToken, "rsa"),
+			"HS256 keyed with RSA PEM":   hmac,
+			"none with RSA kid":          none,
+			"EdDSA token with ECDSA kid": ed,
+		}
+
+		for name, token := range tokens {
+			if _, err := ath.Authenticate(context.Background(), "Bearer "+token); err == nil {
+				t.Errorf("%s: Should NOT be able to authenticate", name)
+			}
+		}
+	})
+}
+
 // =============================================================================
 
 func newUnit(t *testing.T) *logger.Logger {
@@ -276,6 +625,10 @@ func newUnit(t *testing.T) *logger.Logger {
 
 type keyStore struct{}
 
+func (ks *keyStore) ActiveKID() string {
+	return kid
+}
+
 func (ks *keyStore) PrivateKey(kid string) (string, error) {
 	return privateKeyPEM, nil
 }
@@ -284,6 +637,90 @@ func (ks *keyStore) PublicKey(kid string) (string, error) {
 	return publicKeyPEM, nil
 }
 
+type storedKey struct {
+	privatePEM string
+	publicPEM  string
+}
+
+type keyStores map[string]storedKey
+
+func (ks keyStores) ActiveKID() string {
+	return "rsa"
+}
+
+func (ks keyStores) PrivateKey(kid string) (string, error) {
+	k, exists := ks[kid]
+	if !exists {
+		return "", errors.New("kid lookup failed")
+	}
+	return k.privatePEM, nil
+}
+
+func (ks keyStores) PublicKey(kid string) (string, error) {
+	k, exists := ks[kid]
+	if !exists {
+		return "", errors.New("kid lookup failed")
+	}
+	return k.publicPEM, nil
+}
+
+func genKey(t *testing.T, keyType string) storedKey {
+	t.Helper()
+
+	var pk crypto.Signer
+	var err error
+	switch keyType {
+	case "ecdsa":
+		pk, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ed25519":
+		_, pk, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatalf("Should be able to generate a %s key : %s", keyType, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the private key : %s", err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(pk.Public())
+	if err != nil {
+		t.Fatalf("Should be able to marshal the public key : %s", err)
+	}
+
+	return storedKey{
+		privatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		publicPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub})),
+	}
+}
+
+// swapKID rewrites the kid of the token header, leaving the signature as is.
+func swapKID(t *testing.T, token string, kid string) string {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatalf("Should be able to decode the header : %s", err)
+	}
+
+	var h map[string]any
+	if err := json.Unmarshal(header, &h); err != nil {
+		t.Fatalf("Should be able to unmarshal the header : %s", err)
+	}
+	h["kid"] = kid
+
+	header, err = json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the header : %s", err)
+	}
+	parts[0] = base64.RawURLEncoding.EncodeToString(header)
+
+	return strings.Join(parts, ".")
+}
+
 const (
 	kid = "s4sKIjD9kIRjxs2tulPqGLdxSfgPErRN1Mu3Hd9k9NQ"
 
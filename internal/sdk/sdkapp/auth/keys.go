@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// signingMethods are the methods tokens can be signed with, one per type of
+// key.
+var signingMethods = []jwt.SigningMethod{
+	jwt.SigningMethodRS256,
+	jwt.SigningMethodES256,
+	jwt.SigningMethodEdDSA,
+}
+
+// parsePrivateKey parses a PEM encoded private key and returns the method
+// signing tokens with it.
+func parsePrivateKey(privatePEM string) (any, jwt.SigningMethod, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, nil, errors.New("invalid key: key must be PEM encoded")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, jwt.SigningMethodRS256, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return k, jwt.SigningMethodES256, nil
+	case ed25519.PrivateKey:
+		return k, jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+// parsePublicKey parses a PEM encoded public key and returns the only method
+// tokens verified with it may be signed with.
+func parsePublicKey(publicPEM string) (any, jwt.SigningMethod, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, nil, errors.New("invalid key: key must be PEM encoded")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return k, jwt.SigningMethodES256, nil
+	case ed25519.PublicKey:
+		return k, jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported public key type %T", key)
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+)
+
+// Permission represents an action a role allows on a kind of resource.
+type Permission struct {
+	name string
+}
+
+// String returns the name of the permission.
+func (p Permission) String() string {
+	return p.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Permission) Equal(p2 Permission) bool {
+	return p.name == p2.name
+}
+
+// Set of known permissions.
+var permissions = make(map[string]Permission)
+
+func newPermission(permission string) Permission {
+	p := Permission{permission}
+	permissions[permission] = p
+	return p
+}
+
+type permissionSet struct {
+	ProductWrite      Permission
+	ProductExport     Permission
+	StockRead         Permission
+	StockWrite        Permission
+	OrderRead         Permission
+	OrderUpdateStatus Permission
+	OrderDelete       Permission
+	ReviewRead        Permission
+	ReviewModerate    Permission
+	ReviewDelete      Permission
+	UserRead          Permission
+	UserUpdate        Permission
+	UserRoles         Permission
+	APIKeyManage      Permission
+}
+
+// Permissions represents the set of permissions that can be granted.
+var Permissions = permissionSet{
+	ProductWrite:      newPermission("product:write"),
+	ProductExport:     newPermission("product:export"),
+	StockRead:         newPermission("stock:read"),
+	StockWrite:        newPermission("stock:write"),
+	OrderRead:         newPermission("order:read"),
+	OrderUpdateStatus: newPermission("order:update_status"),
+	OrderDelete:       newPermission("order:delete"),
+	ReviewRead:        newPermission("review:read"),
+	ReviewModerate:    newPermission("review:moderate"),
+	ReviewDelete:      newPermission("review:delete"),
+	UserRead:          newPermission("user:read"),
+	UserUpdate:        newPermission("user:update"),
+	UserRoles:         newPermission("user:roles"),
+	APIKeyManage:      newPermission("apikey:manage"),
+}
+
+// ParsePermission parses the string value and returns a permission if one
+// exists.
+func ParsePermission(value string) (Permission, error) {
+	p, exists := permissions[value]
+	if !exists {
+		return Permission{}, fmt.Errorf("invalid permission %q", value)
+	}
+
+	return p, nil
+}
+
+// ParsePermissions parses the string values and returns the permissions.
+func ParsePermissions(values []string) ([]Permission, error) {
+	perms := make([]Permission, len(values))
+	for i, v := range values {
+		p, err := ParsePermission(v)
+		if err != nil {
+			return nil, err
+		}
+		perms[i] = p
+	}
+
+	return perms, nil
+}
+
+// ParsePermissionsToString converts the permissions to their names.
+func ParsePermissionsToString(perms []Permission) []string {
+	names := make([]string, len(perms))
+	for i, p := range perms {
+		names[i] = p.String()
+	}
+
+	return names
+}
+
+// =============================================================================
+
+// RolePermissions maps the name of a role to the permissions it grants.
+type RolePermissions map[string][]Permission
+
+// DefaultRolePermissions returns the permissions granted by each role unless
+// configured otherwise. Users hold no permission, everything they can do is
+// on resources they own.
+func DefaultRolePermissions() RolePermissions {
+	all := make([]Permission, 0, len(permissions))
+	for _, p := range permissions {
+		all = append(all, p)
+	}
+
+	return RolePermissions{
+		userbus.Roles.Admin.String(): all,
+		userbus.Roles.User.String():  nil,
+		userbus.Roles.CatalogManager.String(): {
+			Permissions.ProductWrite,
+			Permissions.ProductExport,
+			Permissions.StockRead,
+			Permissions.StockWrite,
+			Permissions.ReviewRead,
+			Permissions.ReviewModerate,
+			Permissions.ReviewDelete,
+		},
+		userbus.Roles.OrderFulfiller.String(): {
+			Permissions.OrderRead,
+			Permissions.OrderUpdateStatus,
+			Permissions.StockRead,
+		},
+		userbus.Roles.SupportAgent.String(): {
+			Permissions.UserRead,
+			Permissions.UserUpdate,
+			Permissions.OrderRead,
+			Permissions.ReviewRead,
+		},
+	}
+}
+
+// ParseRolePermissions overrides the default permissions of the roles with
+// entries of the form ROLE=permission,permission. An empty list takes every
+// permission away from the role.
+func ParseRolePermissions(entries []string) (RolePermissions, error) {
+	rp := DefaultRolePermissions()
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, list, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid role permissions %q: expected ROLE=permission,permission", entry)
+		}
+
+		role, err := userbus.ParseRole(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+
+		var perms []Permission
+		for _, v := range strings.Split(list, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+
+			p, err := ParsePermission(v)
+			if err != nil {
+				return nil, fmt.Errorf("role %s: %w", role, err)
+			}
+			perms = append(perms, p)
+		}
+
+		rp[role.String()] = perms
+	}
+
+	return rp, nil
+}
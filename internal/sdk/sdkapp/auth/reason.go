@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+)
+
+// Reason represents why a token failed to authenticate.
+type Reason struct {
+	name string
+}
+
+// String returns the name of the reason.
+func (r Reason) String() string {
+	return r.name
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Reason) Equal(r2 Reason) bool {
+	return r.name == r2.name
+}
+
+type reasonSet struct {
+	Malformed   Reason
+	Expired     Reason
+	NotYetValid Reason
+	Invalid     Reason
+	Revoked     Reason
+}
+
+// Reasons represents the set of reasons a token is rejected for.
+var Reasons = reasonSet{
+	Malformed:   Reason{"malformed"},
+	Expired:     Reason{"expired"},
+	NotYetValid: Reason{"not yet valid"},
+	Invalid:     Reason{"invalid"},
+	Revoked:     Reason{"revoked"},
+}
+
+// TokenError is returned by Authenticate when the token is rejected.
+type TokenError struct {
+	Reason Reason
+	Err    error
+}
+
+func newTokenError(reason Reason, format string, v ...any) *TokenError {
+	return &TokenError{
+		Reason: reason,
+		Err:    fmt.Errorf(format, v...),
+	}
+}
+
+// Error implements the error interface.
+func (te *TokenError) Error() string {
+	return fmt.Sprintf("token %s: %s", te.Reason, te.Err)
+}
+
+// Unwrap returns the cause of the rejection.
+func (te *TokenError) Unwrap() error {
+	return te.Err
+}
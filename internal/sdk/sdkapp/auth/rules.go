@@ -1,61 +1,48 @@
 package auth
 
 import (
-	_ "embed"
-	"fmt"
+	"strings"
 )
 
-// Rule represents an authorize rule in the system.
+// Rule represents what a caller needs to be authorized for a route: a set of
+// permissions, being the owner of the resource, or either of them.
 type Rule struct {
-	name string
+	permissions []Permission
+	owner       bool
 }
 
-// Equal provides support for the go-cmp package and testing.
-func (r Rule) Equal(r2 Rule) bool {
-	return r.name == r2.name
+// Require returns a rule that authorizes callers holding every one of the
+// permissions. Without permissions any authenticated caller is authorized.
+func Require(permissions ...Permission) Rule {
+	return Rule{permissions: permissions}
 }
 
-// Set of known rules.
-var rules = make(map[string]Rule)
-
-func newRule(rule string) Rule {
-	r := Rule{rule}
-	rules[rule] = r
-	return r
-}
-
-type ruleSet struct {
-	Any          Rule
-	Admin        Rule
-	User         Rule
-	AdminOrOwner Rule
-	Owner        Rule
+// Owner returns a rule that only authorizes the owner of the resource.
+func Owner() Rule {
+	return Rule{owner: true}
 }
 
-var Rules = ruleSet{
-	Any:          newRule("rule_any"),
-	Admin:        newRule("rule_admin_only"),
-	User:         newRule("rule_user_only"),
-	AdminOrOwner: newRule("rule_admin_or_owner"),
-	Owner:        newRule("rule_owner"),
+// OwnerOr returns a rule that authorizes the owner of the resource, and
+// anyone else holding every one of the permissions.
+func OwnerOr(permissions ...Permission) Rule {
+	return Rule{permissions: permissions, owner: true}
 }
 
-// =============================================================================
-
-func ParseRule(value string) (Rule, error) {
-	role, exists := rules[value]
-	if !exists {
-		return Rule{}, fmt.Errorf("invalid rule %q", value)
+// String returns a description of the rule for errors and logs.
+func (r Rule) String() string {
+	names := make([]string, len(r.permissions))
+	for i, p := range r.permissions {
+		names[i] = p.String()
 	}
 
-	return role, nil
-}
-
-func MustParseRule(value string) Rule {
-	role, err := ParseRule(value)
-	if err != nil {
-		panic(err)
+	switch {
+	case r.owner && len(names) == 0:
+		return "owner"
+	case r.owner:
+		return "owner or " + strings.Join(names, "+")
+	case len(names) == 0:
+		return "authenticated"
 	}
 
-	return role
+	return strings.Join(names, "+")
 }
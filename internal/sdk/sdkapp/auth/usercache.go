@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+)
+
+// userValidity is the part of a user the authentication of their tokens
+// depends on.
+type userValidity struct {
+	enabled            bool
+	emailConfirmed     bool
+	tokenVersion       int
+	sessionsValidAfter time.Time
+}
+
+type userCacheEntry struct {
+	userID    uuid.UUID
+	validity  userValidity
+	expiresAt time.Time
+}
+
+// userCache keeps the validity of the most recently authenticated users for
+// a while, the least recently used user makes room when it is full.
+type userCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List
+}
+
+func newUserCache(ttl time.Duration, size int) *userCache {
+	return &userCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the validity of the user if it is cached and fresh.
+func (uc *userCache) get(userID uuid.UUID) (userValidity, bool) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	elem, exists := uc.entries[userID]
+	if !exists {
+		metrics.UserCacheEvent("miss")
+		return userValidity{}, false
+	}
+
+	entry := elem.Value.(*userCacheEntry)
+	if uc.now().After(entry.expiresAt) {
+		uc.remove(elem)
+		metrics.UserCacheEvent("miss")
+		return userValidity{}, false
+	}
+
+	uc.lru.MoveToFront(elem)
+	metrics.UserCacheEvent("hit")
+
+	return entry.validity, true
+}
+
+// set caches the validity of the user.
+func (uc *userCache) set(userID uuid.UUID, validity userValidity) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if elem, exists := uc.entries[userID]; exists {
+		uc.remove(elem)
+	}
+
+	for uc.lru.Len() >= uc.size {
+		uc.remove(uc.lru.Back())
+		metrics.UserCacheEvent("eviction")
+	}
+
+	uc.entries[userID] = uc.lru.PushFront(&userCacheEntry{
+		userID:    userID,
+		validity:  validity,
+		expiresAt: uc.now().Add(uc.ttl),
+	})
+}
+
+// invalidate forgets the user.
+func (uc *userCache) invalidate(userID uuid.UUID) {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if elem, exists := uc.entries[userID]; exists {
+		uc.remove(elem)
+		metrics.UserCacheEvent("invalidation")
+	}
+}
+
+// purge forgets every user.
+func (uc *userCache) purge() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.entries = make(map[uuid.UUID]*list.Element)
+	uc.lru.Init()
+}
+
+func (uc *userCache) remove(elem *list.Element) {
+	uc.lru.Remove(elem)
+	delete(uc.entries, elem.Value.(*userCacheEntry).userID)
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_UserCacheEviction(t *testing.T) {
+	uc := newUserCache(time.Minute, 2)
+
+	first := uuid.New()
+	second := uuid.New()
+	third := uuid.New()
+
+	uc.set(first, userValidity{tokenVersion: 1})
+	uc.set(second, userValidity{tokenVersion: 2})
+
+	// Using the first user makes the second the least recently used.
+	if _, exists := uc.get(first); !exists {
+		t.Fatalf("Should find the first user")
+	}
+
+	uc.set(third, userValidity{tokenVersion: 3})
+
+	if _, exists := uc.get(second); exists {
+		t.Errorf("Should evict the least recently used user")
+	}
+
+	if v, exists := uc.get(first); !exists || v.tokenVersion != 1 {
+		t.Errorf("Should keep the recently used user: got %+v", v)
+	}
+
+	if v, exists := uc.get(third); !exists || v.tokenVersion != 3 {
+		t.Errorf("Should cache the new user: got %+v", v)
+	}
+
+	uc.set(third, userValidity{tokenVersion: 4})
+
+	if v, _ := uc.get(third); v.tokenVersion != 4 || uc.lru.Len() != 2 {
+		t.Errorf("Should replace the cached user: got %+v for %d users", v, uc.lru.Len())
+	}
+}
+
+func Test_UserCacheExpiry(t *testing.T) {
+	now := time.Now()
+
+	uc := newUserCache(time.Minute, 10)
+	uc.now = func() time.Time { return now }
+
+	userID := uuid.New()
+	uc.set(userID, userValidity{enabled: true})
+
+	uc.now = func() time.Time { return now.Add(time.Minute) }
+
+	if _, exists := uc.get(userID); !exists {
+		t.Errorf("Should find the user up to the end of the ttl")
+	}
+
+	uc.now = func() time.Time { return now.Add(time.Minute + time.Second) }
+
+	if _, exists := uc.get(userID); exists {
+		t.Errorf("Should not find the user after the ttl")
+	}
+
+	if _, exists := uc.entries[userID]; exists {
+		t.Errorf("Should drop the expired user")
+	}
+}
+
+func Test_UserCacheInvalidate(t *testing.T) {
+	uc := newUserCache(time.Minute, 10)
+
+	first := uuid.New()
+	second := uuid.New()
+
+	uc.set(first, userValidity{enabled: true})
+	uc.set(second, userValidity{enabled: true})
+
+	uc.invalidate(first)
+
+	if _, exists := uc.get(first); exists {
+		t.Errorf("Should forget the invalidated user")
+	}
+
+	if _, exists := uc.get(second); !exists {
+		t.Errorf("Should keep the other users")
+	}
+
+	uc.purge()
+
+	if _, exists := uc.get(second); exists {
+		t.Errorf("Should forget every user once purged")
+	}
+}
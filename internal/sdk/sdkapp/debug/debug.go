@@ -0,0 +1,28 @@
+// Package debug provides the handlers of the debug listener, they are kept
+// off the api listener so they are never exposed to the clients.
+package debug
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+)
+
+// Mux returns the router of the debug listener. The standard library
+// registers pprof on the default mux when it is imported, a mux of our own
+// keeps the debug handlers to the listener they are meant for.
+func Mux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	return mux
+}
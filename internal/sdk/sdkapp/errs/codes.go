@@ -111,6 +111,14 @@ var (
 	// exceeded their rate limit and/or quota and must wait before making
 	// futhur requests.
 	TooManyRequests = ErrCode{value: 18}
+
+	// PreconditionFailed indicates that a condition of the request, such as
+	// an If-Match header, does not hold for the current state of the resource.
+	PreconditionFailed = ErrCode{value: 19}
+
+	// AccountLocked indicates that the account or the client is temporarily
+	// locked out after too many failed login attempts.
+	AccountLocked = ErrCode{value: 20}
 )
 
 var codeNumbers = map[string]ErrCode{
@@ -133,6 +141,8 @@ var codeNumbers = map[string]ErrCode{
 	"data_loss":           DataLoss,
 	"unauthenticated":     Unauthenticated,
 	"too_many_requests":   TooManyRequests,
+	"precondition_failed": PreconditionFailed,
+	"account_locked":      AccountLocked,
 }
 
 var codeNames = map[ErrCode]string{
@@ -155,6 +165,8 @@ var codeNames = map[ErrCode]string{
 	DataLoss:           "data_loss",
 	Unauthenticated:    "unauthenticated",
 	TooManyRequests:    "too_many_requests",
+	PreconditionFailed: "precondition_failed",
+	AccountLocked:      "account_locked",
 }
 
 var httpStatus = map[ErrCode]int{
@@ -177,4 +189,6 @@ var httpStatus = map[ErrCode]int{
 	DataLoss:           http.StatusInternalServerError,
 	Unauthenticated:    http.StatusUnauthorized,
 	TooManyRequests:    http.StatusTooManyRequests,
+	PreconditionFailed: http.StatusPreconditionFailed,
+	AccountLocked:      http.StatusLocked,
 }
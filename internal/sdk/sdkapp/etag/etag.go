@@ -0,0 +1,32 @@
+// Package etag provides support for entity tags used by clients to detect
+// concurrent modifications of a resource.
+package etag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Format returns the entity tag representing the version of a resource.
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Match reports whether the value of an If-Match header matches the version
+// of a resource. An empty header or a wildcard matches any version.
+func Match(ifMatch string, version int) bool {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+
+	tag := Format(version)
+	for _, v := range strings.Split(ifMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == tag {
+			return true
+		}
+	}
+
+	return false
+}
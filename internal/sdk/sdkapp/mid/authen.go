@@ -1,19 +1,66 @@
 package mid
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"strings"
 )
 
-func Authenticate(l *logger.Logger, auth *auth.Auth) gin.HandlerFunc {
+// authResult is the outcome of the authentication of an Authorization header.
+type authResult struct {
+	authorization string
+	scheme        string
+	claims        auth.Claims
+	err           error
+}
+
+// authenticate checks the credentials of the header. Services authenticate
+// with an API key, users with a token.
+func authenticate(ctx context.Context, ath *auth.Auth, authorization string) authResult {
+	res := authResult{
+		authorization: authorization,
+		scheme:        "Bearer",
+	}
+
+	authFn := ath.Authenticate
+	if strings.HasPrefix(authorization, "ApiKey ") {
+		res.scheme = "ApiKey"
+		authFn = ath.AuthenticateAPIKey
+	}
+
+	res.claims, res.err = authFn(ctx, authorization)
+
+	return res
+}
+
+// Authenticate checks the credentials of the request, those already checked
+// by the rate limiter are not checked again.
+func Authenticate(l *logger.Logger, ath *auth.Auth) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		claims, err := auth.Authenticate(ctx, c.GetHeader("Authorization"))
+		authorization := c.GetHeader("Authorization")
+
+		res, exists := getAuthResult(ctx, authorization)
+		if !exists {
+			res = authenticate(ctx, ath, authorization)
+		}
+
+		claims, err := res.claims, res.err
 		if err != nil {
+			// Clients refresh an expired token but have to log in again
+			// for any other reason, the header tells them which one it is.
+			var tokenErr *auth.TokenError
+			if errors.As(err, &tokenErr) {
+				c.Header("WWW-Authenticate", fmt.Sprintf(`%s error="invalid_token", error_description="token %s"`, res.scheme, tokenErr.Reason))
+			}
+
 			respond.Error(c, l, errs.New(errs.Unauthenticated, err))
 			return
 		}
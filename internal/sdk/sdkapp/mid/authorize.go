@@ -5,6 +5,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
@@ -15,18 +16,19 @@ import (
 // ErrInvalidID represents a condition where the id is not a uuid.
 var ErrInvalidID = errors.New("ID is not in its proper form")
 
+// Authorize checks the rule for routes that don't act on a resource owned by
+// a user, so only the permissions of the rule can authorize the caller.
 func Authorize(l *logger.Logger, auth *auth.Auth, rule auth.Rule) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
-		userID, err := GetUserID(ctx)
-		if err != nil {
+		if _, err := GetUserID(ctx); err != nil {
 			respond.Error(c, l, errs.New(errs.Unauthenticated, err))
 			return
 		}
 
 		claims := GetClaims(ctx)
 
-		if err := auth.Authorize(ctx, claims, userID, rule); err != nil {
+		if err := auth.Authorize(ctx, claims, uuid.Nil, rule); err != nil {
 			respond.Error(c, l, errs.New(errs.Unauthenticated, err))
 			return
 		}
@@ -35,9 +37,23 @@ func Authorize(l *logger.Logger, auth *auth.Auth, rule auth.Rule) gin.HandlerFun
 	}
 }
 
-// AuthorizeUser executes the specified role and extracts the specified
-// user from the DB if a user id is specified in the call. Depending on the rule
-// specified, the userid from the claims may be compared with the specified user id.
+// RequireUser rejects the callers authenticated with an API key on routes
+// that act as the caller, like placing an order, since the id of a key is not
+// the id of a user.
+func RequireUser(l *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c.Request.Context())
+		if claims.APIKey {
+			respond.Error(c, l, errs.Newf(errs.PermissionDenied, "keyID[%s]: only users can call this route", claims.Subject))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthorizeUser extracts the specified user from the DB if a user id is
+// specified in the call and checks the rule, the user owns itself.
 func AuthorizeUser(l *logger.Logger, auth *auth.Auth, userBus *userbus.Business, rule auth.Rule) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
@@ -78,6 +94,42 @@ func AuthorizeUser(l *logger.Logger, auth *auth.Auth, userBus *userbus.Business,
 	}
 }
 
+// AuthorizeUserChange runs after AuthorizeUser on routes that change the
+// user. Callers other than the user themselves must hold every permission of
+// the roles of the user, a support agent can't disable an admin.
+func AuthorizeUserChange(l *logger.Logger, ath *auth.Auth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		usr, err := GetUser(ctx)
+		if err != nil {
+			respond.Error(c, l, errs.New(errs.Internal, err))
+			return
+		}
+
+		claims := GetClaims(ctx)
+		if claims.Subject == usr.ID.String() {
+			c.Next()
+			return
+		}
+
+		outranks, err := ath.Outranks(claims, usr.Roles)
+		if err != nil {
+			respond.Error(c, l, errs.New(errs.Unauthenticated, err))
+			return
+		}
+
+		if !outranks {
+			respond.Error(c, l, errs.Newf(errs.PermissionDenied, "userID[%s] holds permissions you don't hold", usr.ID))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AuthorizeOrder extracts the specified order from the DB and checks the rule
+// against the user that placed the order.
 func AuthorizeOrder(l *logger.Logger, auth *auth.Auth, orderBus *orderbus.Business, rule auth.Rule) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
@@ -118,3 +170,45 @@ func AuthorizeOrder(l *logger.Logger, auth *auth.Auth, orderBus *orderbus.Busine
 		c.Next()
 	}
 }
+
+// AuthorizeReview extracts the specified review from the DB and checks the
+// rule against the user that wrote the review.
+func AuthorizeReview(l *logger.Logger, auth *auth.Auth, reviewBus *reviewbus.Business, rule auth.Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var userID uuid.UUID
+
+		id := c.Param("review_id")
+		if id != "" {
+			reviewID, err := uuid.Parse(id)
+			if err != nil {
+				respond.Error(c, l, errs.New(errs.Unauthenticated, ErrInvalidID))
+				return
+			}
+
+			rvw, err := reviewBus.QueryByID(ctx, reviewID)
+			if err != nil {
+				switch {
+				case errors.Is(err, reviewbus.ErrNotFound):
+					respond.Error(c, l, errs.New(errs.NotFound, err))
+					return
+				default:
+					respond.Error(c, l, errs.Newf(errs.Unauthenticated, "querybyid: reviewID[%s]: %s", reviewID, err))
+					return
+				}
+			}
+
+			userID = rvw.UserID
+			ctx = setReview(ctx, rvw)
+		}
+
+		claims := GetClaims(ctx)
+		if err := auth.Authorize(ctx, claims, userID, rule); err != nil {
+			respond.Error(c, l, errs.New(errs.Unauthenticated, err))
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,166 @@
+package mid
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig defines the cross origin requests the api accepts.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins like https://shop.example.com,
+	// wildcard subdomains like https://*.example.com, or * for any origin.
+	// Any origin can't be allowed along with credentials, * is then ignored.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS handles the cross origin requests of browsers. Preflight requests are
+// answered here and never reach the routes, the headers of the other requests
+// tell the browser whether the response can be read.
+func CORS(cfg CORSConfig) gin.HandlerFunc {
+	var (
+		anyOrigin bool
+		exact     = make(map[string]struct{})
+		wildcards []originWildcard
+	)
+
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch {
+		case o == "*":
+			anyOrigin = !cfg.AllowCredentials
+		case strings.Contains(o, "*"):
+			prefix, suffix, _ := strings.Cut(o, "*")
+			wildcards = append(wildcards, originWildcard{prefix: prefix, suffix: suffix})
+		case o != "":
+			exact[o] = struct{}{}
+		}
+	}
+
+	allowed := func(origin string) bool {
+		if anyOrigin {
+			return true
+		}
+
+		origin = strings.ToLower(origin)
+		if _, ok := exact[origin]; ok {
+			return true
+		}
+
+		for _, w := range wildcards {
+			if w.match(origin) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	methods := make([]string, len(cfg.AllowedMethods))
+	for i, m := range cfg.AllowedMethods {
+		methods[i] = strings.ToUpper(m)
+	}
+
+	allowedMethods := strings.Join(methods, ", ")
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		h := c.Writer.Header()
+
+		// The response depends on the origin unless every origin gets the
+		// same, caches must not serve it to another origin.
+		if !anyOrigin {
+			h.Add("Vary", "Origin")
+		}
+
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if preflight && !slices.Contains(methods, strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		switch anyOrigin {
+		case true:
+			h.Set("Access-Control-Allow-Origin", "*")
+		default:
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if cfg.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposedHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposedHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		h.Set("Access-Control-Allow-Methods", allowedMethods)
+		if allowedHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// originWildcard matches the origins of the subdomains of a host, at any
+// depth, like https://*.example.com does https://shop.eu.example.com but not
+// https://example.com.
+type originWildcard struct {
+	prefix string
+	suffix string
+}
+
+func (w originWildcard) match(origin string) bool {
+	if len(origin) <= len(w.prefix)+len(w.suffix) || !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+
+	// Only the labels of a host can stand for the wildcard, so the origin
+	// can't smuggle another host or a port in front of the suffix.
+	sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	for _, r := range sub {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return !strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".")
+}
@@ -0,0 +1,183 @@
+package mid_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/mid"
+)
+
+var corsConfig = mid.CORSConfig{
+	AllowedOrigins:   []string{"https://shop.example.com", "https://*.example.org"},
+	AllowedMethods:   []string{"GET", "POST", "PUT"},
+	AllowedHeaders:   []string{"Authorization", "Content-Type", "If-Match"},
+	ExposedHeaders:   []string{"ETag"},
+	AllowCredentials: true,
+	MaxAge:           10 * time.Minute,
+}
+
+func corsEngine(cfg mid.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(mid.CORS(cfg))
+	r.GET("/products", func(c *gin.Context) {
+		c.Header("ETag", `"1"`)
+		c.Status(http.StatusOK)
+	})
+	r.PUT("/products", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return r
+}
+
+func Test_CORSPreflight(t *testing.T) {
+	r := corsEngine(corsConfig)
+
+	tests := []struct {
+		name       string
+		origin     string
+		method     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{name: "exact origin", origin: "https://shop.example.com", method: "PUT", wantStatus: http.StatusNoContent, wantOrigin: "https://shop.example.com"},
+		{name: "subdomain", origin: "https://admin.example.org", method: "PUT", wantStatus: http.StatusNoContent, wantOrigin: "https://admin.example.org"},
+		{name: "nested subdomain", origin: "https://a.eu.example.org", method: "GET", wantStatus: http.StatusNoContent, wantOrigin: "https://a.eu.example.org"},
+		{name: "apex of wildcard", origin: "https://example.org", method: "PUT", wantStatus: http.StatusForbidden},
+		{name: "lookalike host", origin: "https://evilexample.org", method: "PUT", wantStatus: http.StatusForbidden},
+		{name: "other host in front", origin: "https://evil.com/.example.org", method: "PUT", wantStatus: http.StatusForbidden},
+		{name: "other scheme", origin: "http://shop.example.com", method: "PUT", wantStatus: http.StatusForbidden},
+		{name: "unknown origin", origin: "https://evil.com", method: "GET", wantStatus: http.StatusForbidden},
+		{name: "method not allowed", origin: "https://shop.example.com", method: "DELETE", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/products", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			req.Header.Set("Access-Control-Request-Headers", "authorization, if-match")
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Should get status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Fatalf("Should allow origin %q, got %q", tt.wantOrigin, got)
+			}
+			if tt.wantStatus != http.StatusNoContent {
+				return
+			}
+
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT" {
+				t.Errorf("Should allow the configured methods, got %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type, If-Match" {
+				t.Errorf("Should allow the configured headers, got %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("Should allow credentials, got %q", got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Should cache the preflight for 600 seconds, got %q", got)
+			}
+			if got := w.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+				t.Errorf("Should vary on the origin, got %q", got)
+			}
+		})
+	}
+}
+
+func Test_CORSSimple(t *testing.T) {
+	r := corsEngine(corsConfig)
+
+	t.Run("allowed origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Origin", "https://shop.example.com")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Should reach the route, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.com" {
+			t.Errorf("Should allow the origin, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+			t.Errorf("Should expose the ETag, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+			t.Errorf("Should only send the allowed methods on preflight, got %q", got)
+		}
+	})
+
+	t.Run("unknown origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Origin", "https://evil.com")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Should reach the route, the browser blocks the response, got %d", w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Should not allow the origin, got %q", got)
+		}
+	})
+
+	t.Run("no origin", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/products", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Should reach the route, got %d", w.Code)
+		}
+		if got := w.Header().Get("Vary"); got != "" {
+			t.Errorf("Should leave same origin requests alone, got Vary %q", got)
+		}
+	})
+}
+
+func Test_CORSAnyOrigin(t *testing.T) {
+	cfg := corsConfig
+	cfg.AllowedOrigins = []string{"*"}
+
+	t.Run("without credentials", func(t *testing.T) {
+		cfg := cfg
+		cfg.AllowCredentials = false
+
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Origin", "https://anywhere.com")
+
+		w := httptest.NewRecorder()
+		corsEngine(cfg).ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Should allow any origin, got %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("Should not allow credentials, got %q", got)
+		}
+	})
+
+	t.Run("with credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Origin", "https://anywhere.com")
+
+		w := httptest.NewRecorder()
+		corsEngine(cfg).ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Should never allow any origin with credentials, got %q", got)
+		}
+	})
+}
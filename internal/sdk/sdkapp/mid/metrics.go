@@ -0,0 +1,22 @@
+package mid
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+)
+
+// Metrics records the count, latency and in flight requests by route. The
+// requests matching no route share one label so scans can't grow the number
+// of series.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		done := metrics.RequestStarted(c.Request.Method, route)
+		c.Next()
+		done(c.Writer.Status())
+	}
+}
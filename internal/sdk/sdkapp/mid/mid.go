@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/google/uuid"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/order/orderbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/review/reviewbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
@@ -19,6 +20,8 @@ const (
 	userIDKey      ctxKey = 3
 	userKey        ctxKey = 4
 	orderKey       ctxKey = 5
+	reviewKey      ctxKey = 6
+	authResultKey  ctxKey = 7
 )
 
 func setClaims(ctx context.Context, claims auth.Claims) context.Context {
@@ -75,6 +78,20 @@ func GetOrder(ctx context.Context) (orderbus.Order, error) {
 	return v, nil
 }
 
+func setReview(ctx context.Context, rvw reviewbus.Review) context.Context {
+	return context.WithValue(ctx, reviewKey, rvw)
+}
+
+// GetReview returns the review from the context.
+func GetReview(ctx context.Context) (reviewbus.Review, error) {
+	v, ok := ctx.Value(reviewKey).(reviewbus.Review)
+	if !ok {
+		return reviewbus.Review{}, errors.New("review not found in context")
+	}
+
+	return v, nil
+}
+
 func setTran(ctx context.Context, tx sqldb.CommitRollbacker) context.Context {
 	return context.WithValue(ctx, transactionKey, tx)
 }
@@ -88,3 +105,18 @@ func GetTran(ctx context.Context) (sqldb.CommitRollbacker, error) {
 
 	return v, nil
 }
+
+func setAuthResult(ctx context.Context, res authResult) context.Context {
+	return context.WithValue(ctx, authResultKey, res)
+}
+
+// getAuthResult returns the outcome of the authentication of the header when
+// it was already authenticated while handling the request.
+func getAuthResult(ctx context.Context, authorization string) (authResult, bool) {
+	v, ok := ctx.Value(authResultKey).(authResult)
+	if !ok || v.authorization != authorization {
+		return authResult{}, false
+	}
+
+	return v, true
+}
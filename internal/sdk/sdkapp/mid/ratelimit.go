@@ -0,0 +1,122 @@
+package mid
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/ratelimit/ratelimitbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/auth"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/errs"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkapp/respond"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitConfig defines the rate limit policies of the routes.
+type RateLimitConfig struct {
+	// Routes are the policies of the routes keyed by method and route, like
+	// GET /api/v1/products.
+	Routes map[string]ratelimitbus.Policy
+	// Default is the policy of the routes not listed, every route shares the
+	// same bucket.
+	Default ratelimitbus.Policy
+}
+
+// ParseRateLimitRoutes parses the policies of the routes, an entry looks like
+// POST /api/v1/users/register=5/1h.
+func ParseRateLimitRoutes(entries []string) (map[string]ratelimitbus.Policy, error) {
+	routes := make(map[string]ratelimitbus.Policy, len(entries))
+	for _, entry := range entries {
+		route, policy, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("entry %q: should look like POST /api/v1/users/register=5/1h", entry)
+		}
+
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("entry %q: route should be a method and a path", entry)
+		}
+		route = strings.ToUpper(method) + " " + strings.TrimSpace(path)
+
+		p, err := ratelimitbus.ParsePolicy(route, policy)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: %w", entry, err)
+		}
+		routes[route] = p
+	}
+
+	return routes, nil
+}
+
+// RateLimit limits the requests of the clients by route. A client is the API
+// key or the user the request authenticates as, or else its address. The
+// outcome of the authentication is kept for Authenticate so the credentials
+// are checked once, a client failing authentication is limited by address
+// and the route still rejects it. The requests are let through when the
+// limits can't be checked.
+func RateLimit(l *logger.Logger, ath *auth.Auth, rateLimitBus *ratelimitbus.Business, cfg RateLimitConfig) gin.HandlerFunc {
+	client := func(c *gin.Context) string {
+		ctx := c.Request.Context()
+		authorization := c.GetHeader("Authorization")
+
+		if !strings.HasPrefix(authorization, "ApiKey ") && !strings.HasPrefix(authorization, "Bearer ") {
+			return "ip:" + c.ClientIP()
+		}
+
+		res := authenticate(ctx, ath, authorization)
+		c.Request = c.Request.WithContext(setAuthResult(ctx, res))
+
+		switch {
+		case res.err != nil:
+			return "ip:" + c.ClientIP()
+		case res.claims.APIKey:
+			return "apikey:" + res.claims.Subject
+		default:
+			return "user:" + res.claims.Subject
+		}
+	}
+
+	return func(c *gin.Context) {
+		policy, exists := cfg.Routes[c.Request.Method+" "+c.FullPath()]
+		if !exists {
+			policy = cfg.Default
+		}
+
+		if policy.Unlimited() {
+			c.Next()
+			return
+		}
+
+		key := client(c)
+		ctx := c.Request.Context()
+
+		res, err := rateLimitBus.Take(ctx, policy, key)
+		if err != nil {
+			l.Error(ctx, "rate limit", "policy", policy.Name, "err", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Period.Seconds())))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+		if !res.Allowed {
+			metrics.RateLimited(policy.Name)
+			h.Set("Retry-After", ceilSeconds(res.RetryAfter))
+			respond.Error(c, l, errs.Newf(errs.ResourceExhausted, "rate limit: %s: retry in %s", policy.Name, res.RetryAfter.Round(time.Second)))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
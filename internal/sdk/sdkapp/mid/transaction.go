@@ -37,6 +37,7 @@ func BeginCommitRollback(l *logger.Logger, bgn sqldb.Beginner) gin.HandlerFunc {
 		}()
 
 		ctx = setTran(ctx, tx)
+		ctx = sqldb.WithCommitHooks(ctx)
 
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
@@ -52,5 +53,7 @@ func BeginCommitRollback(l *logger.Logger, bgn sqldb.Beginner) gin.HandlerFunc {
 		}
 
 		hasCommitted = true
+
+		sqldb.RunCommitHooks(ctx)
 	}
 }
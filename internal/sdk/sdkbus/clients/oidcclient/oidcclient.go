@@ -0,0 +1,365 @@
+// Package oidcclient implements the relying party side of the OpenID Connect
+// authorization code flow with PKCE.
+package oidcclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Set of error variables for the client.
+var (
+	ErrInvalidToken = errors.New("id token is invalid")
+	ErrUnknownKey   = errors.New("id token is signed by an unknown key")
+)
+
+// leeway is the clock skew allowed between the provider and us.
+const leeway = time.Minute
+
+// minRefreshInterval limits how often the keys of the provider are fetched
+// again when a token refers to an unknown key.
+const minRefreshInterval = time.Minute
+
+// Config represents the registration of the client with a provider.
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	HTTPClient   *http.Client
+}
+
+// IDToken represents the verified claims of an ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Client performs the authorization code flow against a single provider. The
+// metadata and the keys of the provider are fetched on first use.
+type Client struct {
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	metadata    *metadata
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+// New constructs a client for the provider.
+func New(cfg Config) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &Client{
+		cfg:    cfg,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// AuthCodeURL returns the URL of the provider the user is sent to. The
+// verifier is kept by the caller and handed back to Exchange, only its
+// challenge is sent to the provider.
+func (c *Client) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	md, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.cfg.ClientID)
+	v.Set("redirect_uri", c.cfg.RedirectURL)
+	v.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", Challenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return md.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange redeems the authorization code at the provider and returns the
+// claims of the ID token once verified against the nonce.
+func (c *Client) Exchange(ctx context.Context, code string, verifier string, nonce string) (IDToken, error) {
+	md, err := c.discover(ctx)
+	if err != nil {
+		return IDToken{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", verifier)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return IDToken{}, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var resp struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		if resp.Error != "" {
+			return IDToken{}, fmt.Errorf("token endpoint: %s: %s", resp.Error, resp.ErrorDescription)
+		}
+		return IDToken{}, fmt.Errorf("token endpoint: %w", err)
+	}
+
+	if resp.IDToken == "" {
+		return IDToken{}, fmt.Errorf("token endpoint: no id token: %w", ErrInvalidToken)
+	}
+
+	return c.Verify(ctx, resp.IDToken, nonce)
+}
+
+// Verify checks the signature of the ID token against the keys of the
+// provider and validates its claims.
+func (c *Client) Verify(ctx context.Context, rawIDToken string, nonce string) (IDToken, error) {
+	md, err := c.discover(ctx)
+	if err != nil {
+		return IDToken{}, err
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Name, jwt.SigningMethodES256.Name}),
+		jwt.WithoutClaimsValidation(),
+	)
+
+	var claims idTokenClaims
+	_, err = parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return IDToken{}, ErrUnknownKey
+		}
+		return IDToken{}, fmt.Errorf("parse: %s: %w", err, ErrInvalidToken)
+	}
+
+	if err := claims.validate(c.now(), md.Issuer, c.cfg.ClientID, nonce); err != nil {
+		return IDToken{}, fmt.Errorf("%s: %w", err, ErrInvalidToken)
+	}
+
+	idt := IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+
+	return idt, nil
+}
+
+// =============================================================================
+
+// GenerateVerifier returns a new PKCE code verifier.
+func GenerateVerifier() (string, error) {
+	return random(32)
+}
+
+// GenerateState returns a new random value fit for the state and the nonce.
+func GenerateState() (string, error) {
+	return random(16)
+}
+
+// Challenge returns the S256 code challenge of the verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func random(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// =============================================================================
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover fetches the metadata of the provider once.
+func (c *Client) discover(ctx context.Context) (metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata != nil {
+		return *c.metadata, nil
+	}
+
+	u := strings.TrimSuffix(c.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return metadata{}, fmt.Errorf("new request: %w", err)
+	}
+
+	var md metadata
+	if err := c.do(req, &md); err != nil {
+		return metadata{}, fmt.Errorf("discovery: %w", err)
+	}
+
+	// The issuer of the metadata must be the one we were configured with,
+	// otherwise the provider could vouch for tokens of another issuer.
+	if strings.TrimSuffix(md.Issuer, "/") != strings.TrimSuffix(c.cfg.IssuerURL, "/") {
+		return metadata{}, fmt.Errorf("discovery: issuer %q does not match %q", md.Issuer, c.cfg.IssuerURL)
+	}
+
+	if md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "" {
+		return metadata{}, errors.New("discovery: incomplete metadata")
+	}
+
+	c.metadata = &md
+	return md, nil
+}
+
+// key returns the public key with the kid, the keys are fetched again when
+// the kid is unknown since the provider may have rotated them.
+func (c *Client) key(ctx context.Context, kid string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+
+	if !c.keysFetched.IsZero() && c.now().Sub(c.keysFetched) < minRefreshInterval {
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := c.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	c.keysFetched = c.now()
+
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// lookup finds the key with the kid, a token without kid can only be checked
+// when the provider has a single key.
+func (c *Client) lookup(kid string) (any, bool) {
+	if kid == "" {
+		if len(c.keys) != 1 {
+			return nil, false
+		}
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+
+	k, ok := c.keys[kid]
+	return k, ok
+}
+
+func (c *Client) fetchKeys(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	var set jwks
+	if err := c.do(req, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.publicKey()
+		if err != nil {
+			// Providers publish keys of types we don't use, they can't sign
+			// the tokens we accept anyway.
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	return keys, nil
+}
+
+// do sends the request and decodes the JSON response into v.
+func (c *Client) do(req *http.Request, v any) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	// Error responses of the token endpoint are JSON too, decode them so
+	// the caller can report the reason.
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
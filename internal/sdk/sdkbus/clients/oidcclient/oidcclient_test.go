@@ -0,0 +1,380 @@
+package oidcclient_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/clients/oidcclient"
+)
+
+const (
+	clientID     = "ecommerce"
+	clientSecret = "secret"
+	redirectURL  = "http://localhost:8080/api/v1/users/oidc/fake/callback"
+)
+
+func Test_Exchange(t *testing.T) {
+	p := newProvider(t)
+	client := p.client()
+	ctx := context.Background()
+
+	t.Run("valid code", func(t *testing.T) {
+		code, verifier, nonce := p.authorize(t, client, p.claims("user-1"))
+
+		idt, err := client.Exchange(ctx, code, verifier, nonce)
+		if err != nil {
+			t.Fatalf("Should be able to exchange the code : %s", err)
+		}
+
+		if idt.Subject != "user-1" || idt.Email != "user-1@example.com" || !idt.EmailVerified {
+			t.Fatalf("Should get the claims of the user, got %+v", idt)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code, _, nonce := p.authorize(t, client, p.claims("user-1"))
+
+		other, _ := oidcclient.GenerateVerifier()
+		if _, err := client.Exchange(ctx, code, other, nonce); err == nil {
+			t.Fatalf("Should NOT be able to exchange the code with another verifier")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		code, verifier, _ := p.authorize(t, client, p.claims("user-1"))
+
+		_, err := client.Exchange(ctx, code, verifier, "other")
+		if !errors.Is(err, oidcclient.ErrInvalidToken) {
+			t.Fatalf("Should NOT accept an id token of another nonce, got %v", err)
+		}
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		code, verifier, nonce := p.authorize(t, client, p.claims("user-1"))
+
+		if _, err := client.Exchange(ctx, code, verifier, nonce); err != nil {
+			t.Fatalf("Should be able to exchange the code : %s", err)
+		}
+
+		if _, err := client.Exchange(ctx, code, verifier, nonce); err == nil {
+			t.Fatalf("Should NOT be able to exchange the code twice")
+		}
+	})
+}
+
+func Test_Verify(t *testing.T) {
+	p := newProvider(t)
+	client := p.client()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		modify func(c jwt.MapClaims)
+	}{
+		{name: "other issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "other audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{name: "other authorized party", modify: func(c jwt.MapClaims) {
+			c["aud"] = []string{clientID, "other"}
+			c["azp"] = "other"
+		}},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "missing expiration", modify: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "not valid yet", modify: func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() }},
+		{name: "missing subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := p.claims("user-1")
+			tt.modify(claims)
+
+			_, err := client.Verify(ctx, p.sign(t, p.kid, p.key, claims), "nonce")
+			if !errors.Is(err, oidcclient.ErrInvalidToken) {
+				t.Fatalf("Should NOT accept the id token, got %v", err)
+			}
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		if _, err := client.Verify(ctx, p.sign(t, p.kid, p.key, p.claims("user-1")), "nonce"); err != nil {
+			t.Fatalf("Should accept the id token : %s", err)
+		}
+	})
+
+	t.Run("email verified as string", func(t *testing.T) {
+		claims := p.claims("user-1")
+		claims["email_verified"] = "true"
+
+		idt, err := client.Verify(ctx, p.sign(t, p.kid, p.key, claims), "nonce")
+		if err != nil || !idt.EmailVerified {
+			t.Fatalf("Should accept a verified email sent as a string, got %+v : %v", idt, err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, p.claims("user-1"))
+		token.Header["kid"] = p.kid
+		raw, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if err != nil {
+			t.Fatalf("Should be able to build an unsigned token : %s", err)
+		}
+
+		if _, err := client.Verify(ctx, raw, "nonce"); !errors.Is(err, oidcclient.ErrInvalidToken) {
+			t.Fatalf("Should NOT accept an unsigned id token, got %v", err)
+		}
+	})
+
+	t.Run("hmac with the public key", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, p.claims("user-1"))
+		token.Header["kid"] = p.kid
+		raw, err := token.SignedString(p.key.PublicKey.N.Bytes())
+		if err != nil {
+			t.Fatalf("Should be able to build an hmac token : %s", err)
+		}
+
+		if _, err := client.Verify(ctx, raw, "nonce"); !errors.Is(err, oidcclient.ErrInvalidToken) {
+			t.Fatalf("Should NOT accept an hmac id token, got %v", err)
+		}
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		other := newKey(t)
+
+		if _, err := client.Verify(ctx, p.sign(t, p.kid, other, p.claims("user-1")), "nonce"); !errors.Is(err, oidcclient.ErrInvalidToken) {
+			t.Fatalf("Should NOT accept an id token signed by another key, got %v", err)
+		}
+	})
+}
+
+func Test_KeyRotation(t *testing.T) {
+	p := newProvider(t)
+	client := p.client()
+	ctx := context.Background()
+
+	if _, err := client.Verify(ctx, p.sign(t, p.kid, p.key, p.claims("user-1")), "nonce"); err != nil {
+		t.Fatalf("Should accept the id token : %s", err)
+	}
+
+	p.rotate(t)
+
+	if _, err := client.Verify(ctx, p.sign(t, p.kid, p.key, p.claims("user-1")), "nonce"); !errors.Is(err, oidcclient.ErrUnknownKey) {
+		t.Fatalf("Should NOT fetch the keys again right after fetching them, got %v", err)
+	}
+
+	client = p.client()
+	if _, err := client.Verify(ctx, p.sign(t, p.kid, p.key, p.claims("user-1")), "nonce"); err != nil {
+		t.Fatalf("Should accept the id token signed by the new key : %s", err)
+	}
+}
+
+// =============================================================================
+
+// provider is a fake OpenID Connect provider.
+type provider struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	kid   string
+	key   *rsa.PrivateKey
+	codes map[string]grant
+}
+
+// grant is what the provider remembers about an authorization code.
+type grant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newProvider(t *testing.T) *provider {
+	p := provider{
+		kid:   "key-1",
+		key:   newKey(t),
+		codes: make(map[string]grant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token(t))
+
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return &p
+}
+
+func (p *provider) client() *oidcclient.Client {
+	return oidcclient.New(oidcclient.Config{
+		IssuerURL:    p.server.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		HTTPClient:   p.server.Client(),
+	})
+}
+
+func (p *provider) claims(subject string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            subject,
+		"aud":            clientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          "nonce",
+		"email":          subject + "@example.com",
+		"email_verified": true,
+	}
+}
+
+// authorize plays the part of the user approving the login at the provider
+// and returns the code sent back to the redirect URL.
+func (p *provider) authorize(t *testing.T, client *oidcclient.Client, claims jwt.MapClaims) (code string, verifier string, nonce string) {
+	verifier, err := oidcclient.GenerateVerifier()
+	if err != nil {
+		t.Fatalf("Should be able to generate a verifier : %s", err)
+	}
+
+	nonce, err = oidcclient.GenerateState()
+	if err != nil {
+		t.Fatalf("Should be able to generate a nonce : %s", err)
+	}
+
+	raw, err := client.AuthCodeURL(context.Background(), "state", nonce, verifier)
+	if err != nil {
+		t.Fatalf("Should be able to build the auth code url : %s", err)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("Should be able to parse the auth code url : %s", err)
+	}
+
+	q := u.Query()
+	if q.Get("client_id") != clientID || q.Get("redirect_uri") != redirectURL || q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("Should send the client, the redirect url and the challenge, got %s", raw)
+	}
+
+	claims["nonce"] = q.Get("nonce")
+
+	code, _ = oidcclient.GenerateState()
+
+	p.mu.Lock()
+	p.codes[code] = grant{challenge: q.Get("code_challenge"), claims: claims}
+	p.mu.Unlock()
+
+	return code, verifier, nonce
+}
+
+func (p *provider) rotate(t *testing.T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.kid = "key-2"
+	p.key = newKey(t)
+}
+
+func (p *provider) sign(t *testing.T, kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Should be able to sign the id token : %s", err)
+	}
+
+	return raw
+}
+
+func (p *provider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.server.URL,
+		"authorization_endpoint": p.server.URL + "/authorize",
+		"token_endpoint":         p.server.URL + "/token",
+		"jwks_uri":               p.server.URL + "/jwks",
+	})
+}
+
+func (p *provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": p.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.PublicKey.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (p *provider) token(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fail := func(reason string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": reason})
+		}
+
+		if err := r.ParseForm(); err != nil {
+			fail(err.Error())
+			return
+		}
+
+		if r.PostForm.Get("client_id") != clientID || r.PostForm.Get("client_secret") != clientSecret {
+			fail("invalid client")
+			return
+		}
+
+		if r.PostForm.Get("redirect_uri") != redirectURL {
+			fail("invalid redirect uri")
+			return
+		}
+
+		p.mu.Lock()
+		g, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		kid, key := p.kid, p.key
+		p.mu.Unlock()
+
+		if !ok {
+			fail("unknown code")
+			return
+		}
+
+		if oidcclient.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+			fail("invalid code verifier")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     p.sign(t, kid, key, g.claims),
+		})
+	}
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Should be able to generate a key : %s", err)
+	}
+
+	return key
+}
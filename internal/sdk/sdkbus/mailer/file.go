@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// File delivers messages by writing them as .eml files into a folder, or to
+// the logger when no folder is configured. It is meant for development.
+type File struct {
+	log  *logger.Logger
+	dir  string
+	from string
+}
+
+// NewFile constructs a mailer that writes messages into the folder. If the
+// folder is empty, messages are written to the logger.
+func NewFile(log *logger.Logger, dir string, from string) *File {
+	return &File{
+		log:  log,
+		dir:  dir,
+		from: from,
+	}
+}
+
+// Send implements the Mailer interface.
+func (f *File) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipient
+	}
+
+	if f.dir == "" {
+		f.log.Info(ctx, "mailer", "to", msg.To, "subject", msg.Subject, "html", msg.HTML)
+		return nil
+	}
+
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return fmt.Errorf("create folder: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	if err := os.WriteFile(filepath.Join(f.dir, name), build(f.from, msg), 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
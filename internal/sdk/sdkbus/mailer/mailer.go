@@ -0,0 +1,22 @@
+// Package mailer provides support for delivering email messages.
+package mailer
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoRecipient is returned when a message has no recipient.
+var ErrNoRecipient = errors.New("message has no recipient")
+
+// Message represents an email ready to be delivered.
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+}
+
+// Mailer declares the behavior needed to deliver a message.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,174 @@
+// Package metrics provides the prometheus metrics of the service.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ecommerce"
+
+// registry holds the metrics of the service, it is kept apart from the
+// default registry so only what is declared here is exposed.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests being handled.",
+	}, []string{"method", "route"})
+
+	httpRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Number of HTTP requests denied by a rate limit policy.",
+	}, []string{"policy"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of the database queries.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	orders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "total",
+		Help:      "Number of orders by event, created, cancelled or paid.",
+	}, []string{"event"})
+
+	payments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "payments",
+		Name:      "total",
+		Help:      "Number of payments by outcome.",
+	}, []string{"outcome"})
+
+	loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "login_failures_total",
+		Help:      "Number of failed logins by the factor that failed.",
+	}, []string{"factor"})
+
+	userCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "user_cache_total",
+		Help:      "Number of events of the user cache, hit, miss, eviction or invalidation.",
+	}, []string{"event"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		httpRateLimited,
+		dbQueryDuration,
+		orders,
+		payments,
+		loginFailures,
+		userCache,
+	)
+}
+
+// Handler returns the handler serving the metrics in the prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDBStats exposes the stats of the connection pool of the database
+// named name.
+func RegisterDBStats(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// =============================================================================
+
+// RequestStarted counts a request in flight and returns the function to call
+// once it is handled.
+func RequestStarted(method string, route string) func(status int) {
+	start := time.Now()
+
+	inFlight := httpInFlight.WithLabelValues(method, route)
+	inFlight.Inc()
+
+	return func(status int) {
+		inFlight.Dec()
+
+		code := strconv.Itoa(status)
+		httpRequests.WithLabelValues(method, route, code).Inc()
+		httpDuration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RateLimited counts a request denied by the rate limit policy.
+func RateLimited(policy string) {
+	httpRateLimited.WithLabelValues(policy).Inc()
+}
+
+// ObserveQuery records the time spent by a query of the operation since
+// start. It is meant to be deferred.
+func ObserveQuery(operation string, start time.Time) {
+	dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// OrderCreated counts a created order.
+func OrderCreated() {
+	orders.WithLabelValues("created").Inc()
+}
+
+// OrderCancelled counts a cancelled order.
+func OrderCancelled() {
+	orders.WithLabelValues("cancelled").Inc()
+}
+
+// OrderPaid counts a paid order.
+func OrderPaid() {
+	orders.WithLabelValues("paid").Inc()
+}
+
+// PaymentOutcome counts a payment that ended with the outcome.
+func PaymentOutcome(outcome string) {
+	payments.WithLabelValues(outcome).Inc()
+}
+
+// LoginFailed counts a failed login, factor is what failed like password or
+// mfa.
+func LoginFailed(factor string) {
+	loginFailures.WithLabelValues(factor).Inc()
+}
+
+// UserCacheEvent counts an event of the user cache like hit or miss.
+func UserCacheEvent(event string) {
+	userCache.WithLabelValues(event).Inc()
+}
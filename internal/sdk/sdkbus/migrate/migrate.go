@@ -10,6 +10,7 @@ import (
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
 	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"io/fs"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -27,6 +28,9 @@ var (
 
 var ErrNoChange = migrate.ErrNoChange
 
+// ErrNilVersion is returned by Version when the database was never migrated.
+var ErrNilVersion = migrate.ErrNilVersion
+
 // Migrate attempts to bring the database up to date with the migrations
 // defined in this package.
 func Migrate(ctx context.Context, db *sqlx.DB) error {
@@ -59,6 +63,47 @@ func Migrate(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
+// LatestVersion returns the version of the last migration defined in this
+// package, the version of an up to date database.
+func LatestVersion() (uint, error) {
+	d, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("construct iofs driver: %w", err)
+	}
+	defer d.Close()
+
+	version, err := d.First()
+	if err != nil {
+		return 0, fmt.Errorf("first migration: %w", err)
+	}
+
+	for {
+		next, err := d.Next(version)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return version, nil
+			}
+			return 0, fmt.Errorf("next migration: version[%d]: %w", version, err)
+		}
+		version = next
+	}
+}
+
+// Version returns the version the database is migrated to, dirty is set when
+// the last migration failed half way.
+func Version(ctx context.Context, db *sqlx.DB) (version uint, dirty bool, err error) {
+	const q = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	if err := db.QueryRowContext(ctx, q).Scan(&version, &dirty); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, ErrNilVersion
+		}
+		return 0, false, fmt.Errorf("query version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
 func MigrateDown(ctx context.Context, db *sqlx.DB) error {
 	if err := sqldb.StatusCheck(ctx, db); err != nil {
 		return fmt.Errorf("status check database: %w", err)
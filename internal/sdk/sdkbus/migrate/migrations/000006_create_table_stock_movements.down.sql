@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS stock_movements_product_id_index;
+
+ALTER TABLE stock_movements DROP CONSTRAINT fk_product_id;
+
+DROP TABLE IF EXISTS stock_movements;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    stock_movement_id   UUID        NOT NULL,
+    product_id          UUID        NOT NULL,
+    kind                TEXT        NOT NULL,
+    delta               INT         NOT NULL,
+    quantity            INT         NOT NULL,
+    actor_id            UUID            NULL,
+    reference_id        UUID            NULL,
+    reason              TEXT            NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (stock_movement_id)
+);
+
+CREATE INDEX stock_movements_product_id_index ON stock_movements (product_id, date_created);
+
+ALTER TABLE stock_movements ADD CONSTRAINT fk_product_id FOREIGN KEY (product_id) REFERENCES products (product_id) ON DELETE CASCADE;
+
+-- record the current stock as an opening balance so the ledger reconciles --
+
+INSERT INTO stock_movements (stock_movement_id, product_id, kind, delta, quantity, reason, date_created)
+SELECT gen_random_uuid(), product_id, 'ADJUSTMENT', quantity, quantity, 'opening balance', now() AT TIME ZONE 'utc'
+FROM products
+WHERE quantity <> 0;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS stock_subscriptions_open_index;
+
+ALTER TABLE stock_subscriptions DROP CONSTRAINT fk_user_id;
+ALTER TABLE stock_subscriptions DROP CONSTRAINT fk_product_id;
+
+DROP TABLE IF EXISTS stock_subscriptions;
+
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_threshold;
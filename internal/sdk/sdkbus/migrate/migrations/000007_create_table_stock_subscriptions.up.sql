@@ -0,0 +1,17 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+    subscription_id     UUID        NOT NULL,
+    product_id          UUID        NOT NULL,
+    user_id             UUID        NOT NULL,
+    date_created        TIMESTAMP   NOT NULL,
+    date_notified       TIMESTAMP       NULL,
+
+    PRIMARY KEY (subscription_id)
+);
+
+-- a user can only have one open subscription per product --
+CREATE UNIQUE INDEX stock_subscriptions_open_index ON stock_subscriptions (product_id, user_id) WHERE date_notified IS NULL;
+
+ALTER TABLE stock_subscriptions ADD CONSTRAINT fk_product_id FOREIGN KEY (product_id) REFERENCES products (product_id) ON DELETE CASCADE;
+ALTER TABLE stock_subscriptions ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
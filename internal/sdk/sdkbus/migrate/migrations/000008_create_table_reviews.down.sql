@@ -0,0 +1,9 @@
+ALTER TABLE products DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE products DROP COLUMN IF EXISTS rating_average;
+
+DROP INDEX IF EXISTS reviews_product_id_status_index;
+
+ALTER TABLE reviews DROP CONSTRAINT fk_user_id;
+ALTER TABLE reviews DROP CONSTRAINT fk_product_id;
+
+DROP TABLE IF EXISTS reviews;
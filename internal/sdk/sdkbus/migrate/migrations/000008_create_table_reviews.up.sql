@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    review_id       UUID        NOT NULL,
+    product_id      UUID        NOT NULL,
+    user_id         UUID        NOT NULL,
+    rating          SMALLINT    NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment         TEXT            NULL,
+    status          TEXT        NOT NULL,
+    date_created    TIMESTAMP   NOT NULL,
+    date_updated    TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (review_id),
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX reviews_product_id_status_index ON reviews (product_id, status);
+
+ALTER TABLE reviews ADD CONSTRAINT fk_product_id FOREIGN KEY (product_id) REFERENCES products (product_id) ON DELETE CASCADE;
+ALTER TABLE reviews ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_average NUMERIC(3, 2) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS rating_count INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS email_outbox_pending_index;
+
+DROP TABLE IF EXISTS email_outbox;
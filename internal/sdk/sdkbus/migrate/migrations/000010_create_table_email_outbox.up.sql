@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+    email_id            UUID        NOT NULL,
+    recipient           TEXT        NOT NULL,
+    template            TEXT        NOT NULL,
+    locale              TEXT        NOT NULL,
+    data                JSONB       NOT NULL,
+    status              TEXT        NOT NULL,
+    attempts            INT         NOT NULL DEFAULT 0,
+    last_error          TEXT            NULL,
+    next_attempt_at     TIMESTAMP   NOT NULL,
+    date_created        TIMESTAMP   NOT NULL,
+    date_sent           TIMESTAMP       NULL,
+
+    PRIMARY KEY (email_id)
+);
+
+CREATE INDEX email_outbox_pending_index ON email_outbox (next_attempt_at) WHERE status = 'PENDING';
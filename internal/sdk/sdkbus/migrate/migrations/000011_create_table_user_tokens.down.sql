@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_confirm_token TEXT UNIQUE NULL;
+
+-- plaintext tokens can't be recovered, unconfirmed users get a fresh one --
+UPDATE users SET email_confirm_token = gen_random_uuid()::TEXT WHERE NOT email_confirmed;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_confirmed;
+
+DROP TABLE IF EXISTS user_tokens;
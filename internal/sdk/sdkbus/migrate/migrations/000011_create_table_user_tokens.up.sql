@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS user_tokens (
+    token_id            UUID        NOT NULL,
+    user_id             UUID        NOT NULL,
+    purpose             TEXT        NOT NULL,
+    token_hash          TEXT UNIQUE NOT NULL,
+    date_expires        TIMESTAMP   NOT NULL,
+    date_used           TIMESTAMP       NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (token_id)
+);
+
+CREATE INDEX user_tokens_user_purpose_index ON user_tokens (user_id, purpose, date_created);
+
+ALTER TABLE user_tokens ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_confirmed BOOLEAN NOT NULL DEFAULT false;
+
+-- users without a pending token have already confirmed their email --
+UPDATE users SET email_confirmed = true WHERE email_confirm_token IS NULL OR email_confirm_token = '';
+
+-- keep the pending tokens working, hashed and with an expiry --
+INSERT INTO user_tokens (token_id, user_id, purpose, token_hash, date_expires, date_created)
+SELECT gen_random_uuid(), user_id, 'EMAIL_CONFIRMATION', encode(sha256(convert_to(email_confirm_token, 'UTF8')), 'hex'), (now() AT TIME ZONE 'utc') + INTERVAL '24 hours', now() AT TIME ZONE 'utc'
+FROM users
+WHERE email_confirm_token IS NOT NULL AND email_confirm_token <> '';
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_confirm_token;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_logs;
+
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_valid_after;
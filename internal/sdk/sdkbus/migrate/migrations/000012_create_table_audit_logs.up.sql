@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_valid_after TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    audit_id            UUID        NOT NULL,
+    user_id             UUID            NULL,
+    actor_id            UUID            NULL,
+    action              TEXT        NOT NULL,
+    ip_address          TEXT            NULL,
+    user_agent          TEXT            NULL,
+    details             JSONB       NOT NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (audit_id)
+);
+
+CREATE INDEX audit_logs_user_index ON audit_logs (user_id, date_created);
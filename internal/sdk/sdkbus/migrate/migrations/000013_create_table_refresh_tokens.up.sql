@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    refresh_token_id    UUID        NOT NULL,
+    family_id           UUID        NOT NULL,
+    user_id             UUID        NOT NULL,
+    token_hash          TEXT UNIQUE NOT NULL,
+    date_expires        TIMESTAMP   NOT NULL,
+    date_used           TIMESTAMP       NULL,
+    date_revoked        TIMESTAMP       NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (refresh_token_id)
+);
+
+CREATE INDEX refresh_tokens_family_index ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_index ON refresh_tokens (user_id);
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    attempt_key         TEXT        NOT NULL,
+    failures            INT         NOT NULL DEFAULT 0,
+    window_start        TIMESTAMP       NULL,
+    lockouts            INT         NOT NULL DEFAULT 0,
+    locked_until        TIMESTAMP       NULL,
+    last_failure        TIMESTAMP       NULL,
+
+    PRIMARY KEY (attempt_key)
+);
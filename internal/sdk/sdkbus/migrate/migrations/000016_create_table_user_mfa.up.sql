@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id             UUID        NOT NULL,
+    secret              TEXT        NOT NULL,
+    enabled             BOOLEAN     NOT NULL DEFAULT false,
+    last_step           BIGINT      NOT NULL DEFAULT 0,
+    date_enabled        TIMESTAMP       NULL,
+    date_created        TIMESTAMP   NOT NULL,
+    date_updated        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (user_id)
+);
+
+ALTER TABLE user_mfa ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    code_id             UUID        NOT NULL,
+    user_id             UUID        NOT NULL,
+    code_hash           TEXT        NOT NULL,
+    date_used           TIMESTAMP       NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (code_id)
+);
+
+CREATE INDEX mfa_recovery_codes_user_index ON mfa_recovery_codes (user_id, code_hash);
+
+ALTER TABLE mfa_recovery_codes ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    identity_id         UUID        NOT NULL,
+    user_id             UUID        NOT NULL,
+    provider            TEXT        NOT NULL,
+    subject             TEXT        NOT NULL,
+    email               TEXT        NOT NULL,
+    date_last_login     TIMESTAMP   NOT NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (identity_id),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX user_identities_user_index ON user_identities (user_id);
+
+ALTER TABLE user_identities ADD CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE;
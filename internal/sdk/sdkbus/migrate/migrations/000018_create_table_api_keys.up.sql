@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    api_key_id          UUID        NOT NULL,
+    name                TEXT        NOT NULL,
+    prefix              TEXT        NOT NULL,
+    key_hash            TEXT        NOT NULL,
+    permissions         TEXT[]      NOT NULL,
+    created_by          UUID        NOT NULL,
+    date_expires        TIMESTAMP       NULL,
+    date_last_used      TIMESTAMP       NULL,
+    date_revoked        TIMESTAMP       NULL,
+    date_created        TIMESTAMP   NOT NULL,
+
+    PRIMARY KEY (api_key_id),
+    UNIQUE (prefix)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket_key          TEXT                NOT NULL,
+    tokens              DOUBLE PRECISION    NOT NULL DEFAULT 0,
+    date_updated        TIMESTAMP               NULL,
+
+    PRIMARY KEY (bucket_key)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limits_date_updated_idx ON rate_limits (date_updated);
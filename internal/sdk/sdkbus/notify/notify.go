@@ -0,0 +1,46 @@
+// Package notify provides support for sending notifications to admins and
+// users through a pluggable sink.
+package notify
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// Notification represents an event that needs to be delivered. When Admins is
+// set, the notification is addressed to every admin, otherwise it is addressed
+// to the users in UserIDs.
+type Notification struct {
+	Topic   string
+	Admins  bool
+	UserIDs []uuid.UUID
+	Data    map[string]any
+}
+
+// Sink declares the behavior needed to deliver notifications.
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// =============================================================================
+
+// LogSink delivers notifications by writing them to the logger. It is used
+// when no other delivery channel is configured.
+type LogSink struct {
+	log *logger.Logger
+}
+
+// NewLogSink constructs a sink that writes notifications to the logger.
+func NewLogSink(log *logger.Logger) *LogSink {
+	return &LogSink{
+		log: log,
+	}
+}
+
+// Send writes the notification to the logger.
+func (s *LogSink) Send(ctx context.Context, n Notification) error {
+	s.log.Info(ctx, "notification", "topic", n.Topic, "admins", n.Admins, "userIDs", n.UserIDs, "data", n.Data)
+	return nil
+}
@@ -0,0 +1,81 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// maxListenBackoff bounds the wait before listening again after the
+// connection was lost.
+const maxListenBackoff = 30 * time.Second
+
+// Listen calls handler with the payload of every notification of the channel
+// until the context is canceled. It holds a connection of the pool for that
+// time, and listens again on a new one when it is lost. Notifications sent in
+// between are missed, connected is called every time listening starts so the
+// caller can catch up.
+func Listen(ctx context.Context, log *logger.Logger, db *sqlx.DB, channel string, connected func(), handler func(payload string)) {
+	backoff := time.Second
+
+	for {
+		err := listen(ctx, db, channel, func() {
+			backoff = time.Second
+			connected()
+		}, handler)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Error(ctx, "database.Listen", "channel", channel, "status", "connection lost", "retry", backoff, "ERROR", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxListenBackoff)
+	}
+}
+
+func listen(ctx context.Context, db *sqlx.DB, channel string, connected func(), handler func(payload string)) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("conn: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listen requires the pgx driver, got %T", driverConn)
+		}
+		pc := sc.Conn()
+
+		if _, err := pc.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+
+		connected()
+
+		for {
+			n, err := pc.WaitForNotification(ctx)
+			if err != nil {
+				// The connection still listens to the channel, it must not
+				// go back to the pool.
+				return errors.Join(driver.ErrBadConn, fmt.Errorf("wait for notification: %w", err))
+			}
+
+			handler(n.Payload)
+		}
+	})
+}
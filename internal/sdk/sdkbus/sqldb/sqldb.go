@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/metrics"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
 	"github.com/nhannguyenacademy/ecommerce/pkg/tracer"
 	"go.opentelemetry.io/otel/attribute"
@@ -137,17 +138,10 @@ func NamedExecContext(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	ctx, span := tracer.AddSpan(ctx, "business.api.sqldb.exec", attribute.String("query", q))
 	defer span.End()
 
+	defer metrics.ObserveQuery("exec", time.Now())
+
 	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) {
-			switch pqerr.Code {
-			case undefinedTable:
-				return ErrUndefinedTable
-			case uniqueViolation:
-				return ErrDBDuplicatedEntry
-			}
-		}
-		return err
+		return dbError(err)
 	}
 
 	return nil
@@ -185,6 +179,8 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 	ctx, span := tracer.AddSpan(ctx, "business.api.sqldb.queryslice", attribute.String("query", q))
 	defer span.End()
 
+	defer metrics.ObserveQuery("queryslice", time.Now())
+
 	var rows *sqlx.Rows
 
 	switch withIn {
@@ -209,11 +205,7 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 	}
 
 	if err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) && pqerr.Code == undefinedTable {
-			return ErrUndefinedTable
-		}
-		return err
+		return dbError(err)
 	}
 	defer rows.Close()
 
@@ -225,6 +217,11 @@ func namedQuerySlice[T any](ctx context.Context, log *logger.Logger, db sqlx.Ext
 		}
 		slice = append(slice, *v)
 	}
+
+	if err := rows.Err(); err != nil {
+		return dbError(err)
+	}
+
 	*dest = slice
 
 	return nil
@@ -261,6 +258,8 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	ctx, span := tracer.AddSpan(ctx, "business.api.sqldb.query", attribute.String("query", q))
 	defer span.End()
 
+	defer metrics.ObserveQuery("query", time.Now())
+
 	var rows *sqlx.Rows
 
 	switch withIn {
@@ -285,15 +284,16 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	}
 
 	if err != nil {
-		var pqerr *pgconn.PgError
-		if errors.As(err, &pqerr) && pqerr.Code == undefinedTable {
-			return ErrUndefinedTable
-		}
-		return err
+		return dbError(err)
 	}
 	defer rows.Close()
 
+	// the error of a statement like an UPDATE with a RETURNING clause is only
+	// reported once the rows are read
 	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return dbError(err)
+		}
 		return ErrDBNotFound
 	}
 
@@ -304,6 +304,22 @@ func namedQueryStruct(ctx context.Context, log *logger.Logger, db sqlx.ExtContex
 	return nil
 }
 
+// dbError maps the postgres errors callers need to act on to the set of
+// error variables of this package.
+func dbError(err error) error {
+	var pqerr *pgconn.PgError
+	if errors.As(err, &pqerr) {
+		switch pqerr.Code {
+		case undefinedTable:
+			return ErrUndefinedTable
+		case uniqueViolation:
+			return ErrDBDuplicatedEntry
+		}
+	}
+
+	return err
+}
+
 // queryString provides a pretty print version of the query and parameters.
 func queryString(query string, args any) string {
 	query, params, err := sqlx.Named(query, args)
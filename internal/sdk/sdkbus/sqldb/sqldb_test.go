@@ -0,0 +1,109 @@
+package sqldb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+func Test_NamedQueryStructDuplicatedEntry(t *testing.T) {
+	dupErr := &pgconn.PgError{Code: uniqueViolation}
+
+	tests := []struct {
+		name string
+		conn fakeConn
+		want error
+	}{
+		{name: "query", conn: fakeConn{queryErr: dupErr}, want: ErrDBDuplicatedEntry},
+		{name: "rows", conn: fakeConn{rowsErr: dupErr}, want: ErrDBDuplicatedEntry},
+		{name: "undefined", conn: fakeConn{rowsErr: &pgconn.PgError{Code: undefinedTable}}, want: ErrUndefinedTable},
+		{name: "empty", conn: fakeConn{rowsErr: io.EOF}, want: ErrDBNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := sqlx.NewDb(sql.OpenDB(fakeConnector{conn: tt.conn}), "pgx")
+			defer db.Close()
+
+			log := logger.New(&bytes.Buffer{}, logger.LevelInfo, "TEST", func(context.Context) string { return "" })
+
+			const q = `UPDATE users SET email = :email RETURNING id`
+			data := struct {
+				Email string `db:"email"`
+			}{Email: "a@b.c"}
+
+			var dest struct {
+				ID string `db:"id"`
+			}
+
+			err := NamedQueryStruct(context.Background(), log, db, q, data, &dest)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Should get %v: got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+// fakeConn is a database connection that fails a query either when it is run
+// or when its rows are read.
+type fakeConn struct {
+	queryErr error
+	rowsErr  error
+}
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.queryErr != nil {
+		return nil, c.queryErr
+	}
+	return fakeRows{err: c.rowsErr}, nil
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeRows struct {
+	err error
+}
+
+func (r fakeRows) Columns() []string {
+	return []string{"id"}
+}
+
+func (r fakeRows) Close() error {
+	return nil
+}
+
+func (r fakeRows) Next(dest []driver.Value) error {
+	return r.err
+}
+
+type fakeConnector struct {
+	conn fakeConn
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return nil
+}
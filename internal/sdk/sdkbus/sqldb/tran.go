@@ -1,7 +1,9 @@
 package sqldb
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -47,3 +49,52 @@ func GetExtContext(tx CommitRollbacker) (sqlx.ExtContext, error) {
 
 	return ec, nil
 }
+
+// =============================================================================
+
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context)
+}
+
+// WithCommitHooks returns a context that collects the functions registered
+// with AfterCommit until RunCommitHooks is called. It is used by the code
+// that owns the transaction.
+func WithCommitHooks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, commitHooksKey{}, &commitHooks{})
+}
+
+// AfterCommit registers fn to run once the transaction of the context is
+// committed. When the context does not track a transaction fn runs right away.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn(ctx)
+		return
+	}
+
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// RunCommitHooks runs the functions registered with AfterCommit in order. It
+// must only be called after the transaction was committed.
+func RunCommitHooks(ctx context.Context) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		return
+	}
+
+	hooks.mu.Lock()
+	fns := hooks.fns
+	hooks.fns = nil
+	hooks.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
@@ -0,0 +1,85 @@
+package aesgcm
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const key = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+
+func Test_RoundTrip(t *testing.T) {
+	c, err := New(key)
+	if err != nil {
+		t.Fatalf("Should construct the cipher: %s", err)
+	}
+
+	for _, plain := range []string{"", "JBSWY3DPEHPK3PXP", strings.Repeat("secret ", 100)} {
+		sealed, err := c.Encrypt(plain)
+		if err != nil {
+			t.Fatalf("Should encrypt: %s", err)
+		}
+
+		if plain != "" && strings.Contains(sealed, plain) {
+			t.Errorf("Should not leak the plaintext")
+		}
+
+		got, err := c.Decrypt(sealed)
+		if err != nil {
+			t.Fatalf("Should decrypt: %s", err)
+		}
+
+		if got != plain {
+			t.Errorf("Should get the plaintext back: got %q, want %q", got, plain)
+		}
+	}
+
+	a, _ := c.Encrypt("same")
+	b, _ := c.Encrypt("same")
+	if a == b {
+		t.Errorf("Should use a new nonce for every value")
+	}
+}
+
+func Test_Tamper(t *testing.T) {
+	c, err := New(key)
+	if err != nil {
+		t.Fatalf("Should construct the cipher: %s", err)
+	}
+
+	sealed, err := c.Encrypt("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Should encrypt: %s", err)
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(sealed)
+	for i := range raw {
+		tampered := append([]byte(nil), raw...)
+		tampered[i] ^= 0x01
+
+		if _, err := c.Decrypt(base64.StdEncoding.EncodeToString(tampered)); err == nil {
+			t.Fatalf("Should reject a value with byte %d changed", i)
+		}
+	}
+
+	if _, err := c.Decrypt(base64.StdEncoding.EncodeToString(raw[:8])); err == nil {
+		t.Errorf("Should reject a truncated value")
+	}
+
+	other, err := New(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatalf("Should construct the cipher: %s", err)
+	}
+
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Errorf("Should reject a value encrypted with another key")
+	}
+}
+
+func Test_NewInvalidKey(t *testing.T) {
+	for _, k := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := New(k); err == nil {
+			t.Errorf("Should reject the key %q", k)
+		}
+	}
+}
@@ -4,6 +4,10 @@ package keystore
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -12,33 +16,73 @@ import (
 	"io"
 	"io/fs"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // key represents key information.
 type key struct {
 	privatePEM string
 	publicPEM  string
+	activates  time.Time
+	retired    time.Time
+}
+
+// Config represents the rotation settings of the key store.
+type Config struct {
+	// DefaultKID is the key signing tokens when the directory has no
+	// manifest.
+	DefaultKID string
+	// GracePeriod is how long a retired key still verifies tokens.
+	GracePeriod time.Duration
 }
 
 // KeyStore represents an in memory store implementation of the
-// KeyLookup interface for use with the auth package.
+// KeyLookup interface for use with the auth package. The keys can be
+// reloaded at any time, a key retired by the manifest still verifies tokens
+// during the grace period but no longer signs them. A key staged as the next
+// key verifies tokens right away and signs them once it activates.
 type KeyStore struct {
-	store map[string]key
+	cfg Config
+	now func() time.Time
+
+	mu        sync.RWMutex
+	store     map[string]key
+	activeKID string
+	next      *NextKey
 }
 
 // New constructs an empty KeyStore ready for use.
-func New() *KeyStore {
+func New(cfg Config) *KeyStore {
 	return &KeyStore{
-		store: make(map[string]key),
+		cfg:       cfg,
+		now:       time.Now,
+		store:     make(map[string]key),
+		activeKID: cfg.DefaultKID,
 	}
 }
 
-// LoadRSAKeys loads a set of RSA PEM files rooted inside of a directory. The
-// name of each PEM file will be used as the key id.
-// Example: ks.LoadRSAKeys(os.DirFS("/configs/keys/"))
+// LoadKeys loads a set of PEM files rooted inside of a directory. The name
+// of each PEM file will be used as the key id. RSA, ECDSA P-256 and Ed25519
+// private keys are supported. The keys replace the ones loaded before,
+// unless the directory is invalid.
+// Example: ks.LoadKeys(os.DirFS("/configs/keys/"))
 // Example: /configs/keys/54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem
-func (ks *KeyStore) LoadRSAKeys(fsys fs.FS) error {
+func (ks *KeyStore) LoadKeys(fsys fs.FS) error {
+	mf, err := ReadManifest(fsys)
+	if err != nil {
+		return err
+	}
+
+	activeKID := ks.cfg.DefaultKID
+	if mf.ActiveKID != "" {
+		activeKID = mf.ActiveKID
+	}
+
+	store := make(map[string]key)
+
 	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("walkdir failure: %w", err)
@@ -52,6 +96,25 @@ func (ks *KeyStore) LoadRSAKeys(fsys fs.FS) error {
 			return nil
 		}
 
+		kid := strings.TrimSuffix(dirEntry.Name(), ".pem")
+
+		// Keys retired for longer than the grace period can't verify
+		// anything anymore, there is no need to load them.
+		retired := mf.Retired[kid]
+		var activates time.Time
+		if mf.Next != nil {
+			switch kid {
+			case activeKID:
+				retired = mf.Next.Activates
+			case mf.Next.KID:
+				activates = mf.Next.Activates
+			}
+		}
+
+		if !retired.IsZero() && ks.now().After(retired.Add(ks.cfg.GracePeriod)) {
+			return nil
+		}
+
 		file, err := fsys.Open(fileName)
 		if err != nil {
 			return fmt.Errorf("opening key file: %w", err)
@@ -69,15 +132,17 @@ func (ks *KeyStore) LoadRSAKeys(fsys fs.FS) error {
 		privatePEM := string(pem)
 		publicPEM, err := toPublicPEM(privatePEM)
 		if err != nil {
-			return fmt.Errorf("converting private PEM to public: %w", err)
+			return fmt.Errorf("converting private PEM to public: kid[%s]: %w", kid, err)
 		}
 
 		key := key{
 			privatePEM: privatePEM,
 			publicPEM:  publicPEM,
+			activates:  activates,
+			retired:    retired,
 		}
 
-		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = key
+		store[kid] = key
 
 		return nil
 	}
@@ -86,50 +151,186 @@ func (ks *KeyStore) LoadRSAKeys(fsys fs.FS) error {
 		return fmt.Errorf("walking directory: %w", err)
 	}
 
+	if mf.Next != nil {
+		if _, exists := store[mf.Next.KID]; !exists {
+			return fmt.Errorf("next key %q is missing", mf.Next.KID)
+		}
+	}
+
+	kid := currentKID(activeKID, mf.Next, ks.now())
+	if k, exists := store[kid]; !exists || !ks.signs(k) {
+		return fmt.Errorf("active key %q is missing or retired", kid)
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.store = store
+	ks.activeKID = activeKID
+	ks.next = mf.Next
+
 	return nil
 }
 
-// PrivateKey searches the key store for a given kid and returns the private key.
+// Watch reloads the keys whenever the files of the directory change, it
+// checks every interval until the context is canceled. The outcome of every
+// reload is reported to reloaded.
+func (ks *KeyStore) Watch(ctx context.Context, fsys fs.FS, interval time.Duration, reloaded func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := fingerprint(fsys)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fp, err := fingerprint(fsys)
+		if err != nil || fp == last {
+			continue
+		}
+		last = fp
+
+		reloaded(ks.LoadKeys(fsys))
+	}
+}
+
+// ActiveKID returns the id of the key signing new tokens.
+func (ks *KeyStore) ActiveKID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return currentKID(ks.activeKID, ks.next, ks.now())
+}
+
+// KIDs returns the ids of the keys that can verify tokens, sorted.
+func (ks *KeyStore) KIDs() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	kids := make([]string, 0, len(ks.store))
+	for kid, k := range ks.store {
+		if ks.verifies(k) {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+
+	return kids
+}
+
+// PrivateKey searches the key store for a given kid and returns the private
+// key. Retired keys and keys that are not active yet can't sign.
 func (ks *KeyStore) PrivateKey(kid string) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
 	key, found := ks.store[kid]
 	if !found {
 		return "", errors.New("kid lookup failed")
 	}
 
+	if !ks.signs(key) {
+		return "", errors.New("key is retired or not active yet")
+	}
+
 	return key.privatePEM, nil
 }
 
-// PublicKey searches the key store for a given kid and returns the public key.
+// PublicKey searches the key store for a given kid and returns the public
+// key. Keys retired for longer than the grace period are not returned.
 func (ks *KeyStore) PublicKey(kid string) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
 	key, found := ks.store[kid]
-	if !found {
+	if !found || !ks.verifies(key) {
 		return "", errors.New("kid lookup failed")
 	}
 
 	return key.publicPEM, nil
 }
 
+func (ks *KeyStore) verifies(k key) bool {
+	return k.retired.IsZero() || !ks.now().After(k.retired.Add(ks.cfg.GracePeriod))
+}
+
+func (ks *KeyStore) signs(k key) bool {
+	now := ks.now()
+	return (k.activates.IsZero() || !now.Before(k.activates)) && (k.retired.IsZero() || now.Before(k.retired))
+}
+
+// currentKID returns the key signing tokens at now, the next key once it
+// activated.
+func currentKID(activeKID string, next *NextKey, now time.Time) string {
+	if next != nil && !now.Before(next.Activates) {
+		return next.KID
+	}
+
+	return activeKID
+}
+
+// fingerprint summarizes the names, sizes and modification times of the
+// files of the directory.
+func fingerprint(fsys fs.FS) (string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return b.String(), nil
+}
+
 func toPublicPEM(privatePEM string) (string, error) {
 	block, _ := pem.Decode([]byte(privatePEM))
 	if block == nil {
-		return "", errors.New("invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key")
+		return "", errors.New("invalid key: Key must be a PEM encoded PKCS1, PKCS8 or SEC1 key")
 	}
 
 	var parsedKey any
-	parsedKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		parsedKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
 		if err != nil {
-			return "", err
+			parsedKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
 		}
 	}
+	if err != nil {
+		return "", err
+	}
 
-	pk, ok := parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		return "", errors.New("key is not a valid RSA private key")
+	var public any
+	switch pk := parsedKey.(type) {
+	case *rsa.PrivateKey:
+		public = &pk.PublicKey
+	case *ecdsa.PrivateKey:
+		// ES256 is the only ECDSA algorithm we sign with.
+		if pk.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported curve %s: only P-256 is supported", pk.Curve.Params().Name)
+		}
+		public = &pk.PublicKey
+	case ed25519.PrivateKey:
+		public = pk.Public()
+	default:
+		return "", fmt.Errorf("unsupported private key type %T", parsedKey)
 	}
 
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&pk.PublicKey)
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(public)
 	if err != nil {
 		return "", fmt.Errorf("marshaling public key: %w", err)
 	}
@@ -0,0 +1,242 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const gracePeriod = 30 * time.Minute
+
+func Test_ManifestRetirement(t *testing.T) {
+	now := time.Now().UTC()
+	dir := keyDir(t, "active", "recent", "old")
+
+	mf := Manifest{
+		ActiveKID: "active",
+		Retired: map[string]time.Time{
+			"recent": now.Add(-10 * time.Minute),
+			"old":    now.Add(-time.Hour),
+		},
+	}
+	if err := WriteManifest(dir, mf); err != nil {
+		t.Fatalf("Should write the manifest: %s", err)
+	}
+
+	ks := newTestStore(now)
+	if err := ks.LoadKeys(os.DirFS(dir)); err != nil {
+		t.Fatalf("Should load the keys: %s", err)
+	}
+
+	if got := ks.ActiveKID(); got != "active" {
+		t.Errorf("Should sign with the active key of the manifest: got %s", got)
+	}
+
+	if got := ks.KIDs(); !slices.Equal(got, []string{"active", "recent"}) {
+		t.Errorf("Should publish the active key and the keys within the grace period: got %v", got)
+	}
+
+	if _, err := ks.PrivateKey("recent"); err == nil {
+		t.Errorf("Should not sign with a retired key")
+	}
+
+	if _, err := ks.PublicKey("recent"); err != nil {
+		t.Errorf("Should verify with a key within the grace period: %s", err)
+	}
+
+	if _, err := ks.PublicKey("old"); err == nil {
+		t.Errorf("Should not verify with a key retired for longer than the grace period")
+	}
+}
+
+func Test_GracePeriodCutoff(t *testing.T) {
+	now := time.Now().UTC()
+	dir := keyDir(t, "active", "retired")
+
+	mf := Manifest{
+		ActiveKID: "active",
+		Retired:   map[string]time.Time{"retired": now},
+	}
+	if err := WriteManifest(dir, mf); err != nil {
+		t.Fatalf("Should write the manifest: %s", err)
+	}
+
+	ks := newTestStore(now)
+	if err := ks.LoadKeys(os.DirFS(dir)); err != nil {
+		t.Fatalf("Should load the keys: %s", err)
+	}
+
+	ks.now = func() time.Time { return now.Add(gracePeriod) }
+
+	if _, err := ks.PublicKey("retired"); err != nil {
+		t.Errorf("Should verify with the key up to the end of the grace period: %s", err)
+	}
+
+	ks.now = func() time.Time { return now.Add(gracePeriod + time.Second) }
+
+	if _, err := ks.PublicKey("retired"); err == nil {
+		t.Errorf("Should not verify with the key after the grace period")
+	}
+
+	if got := ks.KIDs(); !slices.Equal(got, []string{"active"}) {
+		t.Errorf("Should stop publishing the key after the grace period: got %v", got)
+	}
+}
+
+func Test_NextKey(t *testing.T) {
+	now := time.Now().UTC()
+	dir := keyDir(t, "active", "next")
+
+	mf := Manifest{
+		ActiveKID: "active",
+		Next:      &NextKey{KID: "next", Activates: now.Add(5 * time.Minute)},
+	}
+	if err := WriteManifest(dir, mf); err != nil {
+		t.Fatalf("Should write the manifest: %s", err)
+	}
+
+	ks := newTestStore(now)
+	if err := ks.LoadKeys(os.DirFS(dir)); err != nil {
+		t.Fatalf("Should load the keys: %s", err)
+	}
+
+	if got := ks.ActiveKID(); got != "active" {
+		t.Errorf("Should keep signing with the active key before the next one activates: got %s", got)
+	}
+
+	if _, err := ks.PrivateKey("next"); err == nil {
+		t.Errorf("Should not sign with the next key before it activates")
+	}
+
+	if got := ks.KIDs(); !slices.Equal(got, []string{"active", "next"}) {
+		t.Errorf("Should publish the next key right away: got %v", got)
+	}
+
+	ks.now = func() time.Time { return now.Add(5 * time.Minute) }
+
+	if got := ks.ActiveKID(); got != "next" {
+		t.Errorf("Should sign with the next key once it activates: got %s", got)
+	}
+
+	if _, err := ks.PrivateKey("active"); err == nil {
+		t.Errorf("Should not sign with the replaced key")
+	}
+
+	if _, err := ks.PublicKey("active"); err != nil {
+		t.Errorf("Should verify with the replaced key during the grace period: %s", err)
+	}
+
+	ks.now = func() time.Time { return now.Add(5*time.Minute + gracePeriod + time.Second) }
+
+	if got := ks.KIDs(); !slices.Equal(got, []string{"next"}) {
+		t.Errorf("Should stop publishing the replaced key after the grace period: got %v", got)
+	}
+}
+
+func Test_ReloadFailureKeepsKeys(t *testing.T) {
+	now := time.Now().UTC()
+	dir := keyDir(t, "active")
+
+	ks := newTestStore(now)
+	ks.cfg.DefaultKID = "active"
+	if err := ks.LoadKeys(os.DirFS(dir)); err != nil {
+		t.Fatalf("Should load the keys: %s", err)
+	}
+
+	if err := WriteManifest(dir, Manifest{ActiveKID: "missing"}); err != nil {
+		t.Fatalf("Should write the manifest: %s", err)
+	}
+
+	if err := ks.LoadKeys(os.DirFS(dir)); err == nil {
+		t.Fatalf("Should fail to load a manifest naming a missing key")
+	}
+
+	if err := os.Remove(filepath.Join(dir, ManifestFile)); err != nil {
+		t.Fatalf("Should remove the manifest: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0600); err != nil {
+		t.Fatalf("Should write the key: %s", err)
+	}
+
+	if err := ks.LoadKeys(os.DirFS(dir)); err == nil {
+		t.Fatalf("Should fail to load an invalid key")
+	}
+
+	if got := ks.ActiveKID(); got != "active" {
+		t.Errorf("Should keep the active key: got %s", got)
+	}
+
+	if _, err := ks.PrivateKey("active"); err != nil {
+		t.Errorf("Should keep signing with the loaded keys: %s", err)
+	}
+}
+
+func Test_Promote(t *testing.T) {
+	now := time.Now().UTC()
+	activates := now.Add(time.Minute)
+
+	mf := Manifest{
+		ActiveKID: "active",
+		Next:      &NextKey{KID: "next", Activates: activates},
+		Retired:   map[string]time.Time{"old": now.Add(-time.Hour)},
+	}
+
+	if got := mf.Promote(now); got.ActiveKID != "active" || got.Next == nil {
+		t.Errorf("Should keep the next key staged before it activates: got %+v", got)
+	}
+
+	got := mf.Promote(activates)
+	if got.ActiveKID != "next" || got.Next != nil {
+		t.Fatalf("Should make the next key active once it activates: got %+v", got)
+	}
+
+	if !got.Retired["active"].Equal(activates) || got.Retired["old"].IsZero() {
+		t.Errorf("Should retire the replaced key as of the activation: got %v", got.Retired)
+	}
+
+	if _, exists := mf.Retired["active"]; exists {
+		t.Errorf("Should not change the original manifest")
+	}
+}
+
+// =============================================================================
+
+func newTestStore(now time.Time) *KeyStore {
+	ks := New(Config{GracePeriod: gracePeriod})
+	ks.now = func() time.Time { return now }
+
+	return ks
+}
+
+// keyDir writes an ed25519 private key per kid into a new directory.
+func keyDir(t *testing.T, kids ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for _, kid := range kids {
+		_, pk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("Should generate a key: %s", err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(pk)
+		if err != nil {
+			t.Fatalf("Should marshal the key: %s", err)
+		}
+
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+			t.Fatalf("Should write the key: %s", err)
+		}
+	}
+
+	return dir
+}
@@ -0,0 +1,101 @@
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestFile is the name of the manifest inside the key directory.
+const ManifestFile = "keys.json"
+
+// Manifest records which key of the directory signs tokens and when the
+// other keys were retired. A key staged as the next key verifies tokens
+// right away but only replaces the active key once it activates, so clients
+// caching the public keys know it before it signs anything.
+type Manifest struct {
+	ActiveKID string               `json:"active_kid"`
+	Next      *NextKey             `json:"next,omitempty"`
+	Retired   map[string]time.Time `json:"retired,omitempty"`
+}
+
+// NextKey represents a key staged to replace the active key.
+type NextKey struct {
+	KID       string    `json:"kid"`
+	Activates time.Time `json:"activates"`
+}
+
+// Promote returns the manifest with the next key made active when it
+// activated by now, the key it replaces is retired as of the activation.
+func (mf Manifest) Promote(now time.Time) Manifest {
+	if mf.Next == nil || now.Before(mf.Next.Activates) {
+		return mf
+	}
+
+	retired := make(map[string]time.Time, len(mf.Retired)+1)
+	for kid, t := range mf.Retired {
+		retired[kid] = t
+	}
+
+	if mf.ActiveKID != "" {
+		retired[mf.ActiveKID] = mf.Next.Activates
+	}
+
+	return Manifest{
+		ActiveKID: mf.Next.KID,
+		Retired:   retired,
+	}
+}
+
+// ReadManifest reads the manifest of the directory, a directory without
+// manifest has an empty one.
+func ReadManifest(fsys fs.FS) (Manifest, error) {
+	data, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return Manifest{}, nil
+		}
+		return Manifest{}, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	var mf Manifest
+	if err := json.Unmarshal(data, &mf); err != nil {
+		return Manifest{}, fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	return mf, nil
+}
+
+// WriteManifest replaces the manifest of the directory. The file is renamed
+// into place so a store reloading at the same time never reads half of it.
+func WriteManifest(dir string, mf Manifest) error {
+	data, err := json.MarshalIndent(mf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ManifestFile+".*")
+	if err != nil {
+		return fmt.Errorf("creating manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing manifest: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, ManifestFile)); err != nil {
+		return fmt.Errorf("renaming manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// secret is the SHA1 seed of the RFC 6238 test vectors, "12345678901234567890".
+var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func Test_Code(t *testing.T) {
+	// The RFC lists 8 digit codes, a 6 digit code is made of their last 6
+	// digits.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(secret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Should generate the code at %d: %s", tt.unix, err)
+		}
+
+		if got != tt.code {
+			t.Errorf("Should generate the RFC 6238 code at %d: got %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok, err := Validate(secret, "050471", now, 1)
+	if err != nil || !ok {
+		t.Fatalf("Should validate the current code: %v", err)
+	}
+	if step != Step(now) {
+		t.Errorf("Should return the step of the code: got %d, want %d", step, Step(now))
+	}
+
+	previous, err := Code(secret, Step(now)-1)
+	if err != nil {
+		t.Fatalf("Should generate the code: %s", err)
+	}
+
+	step, ok, _ = Validate(secret, previous, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Should accept a code within the skew: got %d, %t", step, ok)
+	}
+
+	if _, ok, _ := Validate(secret, previous, now, 0); ok {
+		t.Errorf("Should reject a code outside the skew")
+	}
+
+	if _, ok, _ := Validate(secret, "12345", now, 1); ok {
+		t.Errorf("Should reject a code of the wrong length")
+	}
+
+	if _, _, err := Validate("not base32!", "050471", now, 1); err == nil {
+		t.Errorf("Should fail on an invalid secret")
+	}
+}
@@ -0,0 +1,74 @@
+package tracer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// writerExporter writes the spans as JSON lines, it lets the traces be
+// looked at without a collector.
+type writerExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newWriterExporter(w io.Writer) *writerExporter {
+	return &writerExporter{w: w}
+}
+
+type exportedSpan struct {
+	Name         string         `json:"name"`
+	TraceID      string         `json:"trace_id"`
+	SpanID       string         `json:"span_id"`
+	ParentSpanID string         `json:"parent_span_id,omitempty"`
+	Start        time.Time      `json:"start"`
+	Duration     string         `json:"duration"`
+	Status       string         `json:"status"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+}
+
+// ExportSpans implements the span exporter interface.
+func (e *writerExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	enc := json.NewEncoder(e.w)
+	for _, s := range spans {
+		es := exportedSpan{
+			Name:     s.Name(),
+			TraceID:  s.SpanContext().TraceID().String(),
+			SpanID:   s.SpanContext().SpanID().String(),
+			Start:    s.StartTime(),
+			Duration: s.EndTime().Sub(s.StartTime()).String(),
+			Status:   s.Status().Code.String(),
+		}
+
+		if s.Parent().HasSpanID() {
+			es.ParentSpanID = s.Parent().SpanID().String()
+		}
+
+		if attrs := s.Attributes(); len(attrs) > 0 {
+			es.Attributes = make(map[string]any, len(attrs))
+			for _, kv := range attrs {
+				es.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+			}
+		}
+
+		if err := enc.Encode(es); err != nil {
+			return fmt.Errorf("encoding span: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown implements the span exporter interface.
+func (e *writerExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
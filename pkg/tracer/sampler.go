@@ -6,21 +6,25 @@ import (
 )
 
 type endpointExcluder struct {
-	log         *logger.Logger
-	endpoints   map[string]struct{}
-	probability float64
+	log       *logger.Logger
+	endpoints map[string]struct{}
+	sampler   trace.Sampler
 }
 
+// newEndpointExcluder constructs the sampler of the service. Spans with a
+// parent follow the decision of the parent, so a trace started by a caller
+// is kept whole, root spans are sampled with the probability.
 func newEndpointExcluder(log *logger.Logger, endpoints map[string]struct{}, probability float64) endpointExcluder {
 	return endpointExcluder{
-		log:         log,
-		endpoints:   endpoints,
-		probability: probability,
+		log:       log,
+		endpoints: endpoints,
+		sampler:   trace.ParentBased(trace.TraceIDRatioBased(probability)),
 	}
 }
 
 // ShouldSample implements the sampler interface. It prevents the specified
-// endpoints from being added to the trace.
+// endpoints from being added to the trace, even when the caller sampled the
+// trace.
 func (ee endpointExcluder) ShouldSample(parameters trace.SamplingParameters) trace.SamplingResult {
 	for i := range parameters.Attributes {
 		if parameters.Attributes[i].Key == "http.target" {
@@ -30,7 +34,7 @@ func (ee endpointExcluder) ShouldSample(parameters trace.SamplingParameters) tra
 		}
 	}
 
-	return trace.TraceIDRatioBased(ee.probability).ShouldSample(parameters)
+	return ee.sampler.ShouldSample(parameters)
 }
 
 // Description implements the sampler interface.
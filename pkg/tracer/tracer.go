@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
@@ -19,6 +20,13 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// Set of exporters the spans can be sent to.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
 // Config defines the information needed to init tracing.
 type Config struct {
 	Log            *logger.Logger
@@ -26,6 +34,9 @@ type Config struct {
 	Host           string
 	ExcludedRoutes map[string]struct{}
 	Probability    float64
+	// Exporter is where the spans are sent, with none the spans are still
+	// created so the logs carry trace ids, they are just never exported.
+	Exporter string
 }
 
 // InitTracing configures open telemetry to be used with the service.
@@ -35,31 +46,45 @@ func InitTracing(cfg Config) (*sdktrace.TracerProvider, error) {
 	// compatible with your project. Please review the documentation for
 	// opentelemetry.
 
-	exporter, err := otlptrace.New(
-		context.Background(),
-		otlptracegrpc.NewClient(
-			otlptracegrpc.WithInsecure(), // This should be configurable
-			otlptracegrpc.WithEndpoint(cfg.Host),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("creating new exporter: %w", err)
-	}
-
-	traceProvider := sdktrace.NewTracerProvider(
+	opts := []sdktrace.TracerProviderOption{
 		sdktrace.WithSampler(newEndpointExcluder(cfg.Log, cfg.ExcludedRoutes, cfg.Probability)),
-		sdktrace.WithBatcher(exporter,
-			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
-			sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay*time.Millisecond),
-			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
-		),
 		sdktrace.WithResource(
 			resource.NewWithAttributes(
 				semconv.SchemaURL,
 				semconv.ServiceNameKey.String(cfg.ServiceName),
 			),
 		),
-	)
+	}
+
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		exporter, err := otlptrace.New(
+			context.Background(),
+			otlptracegrpc.NewClient(
+				otlptracegrpc.WithInsecure(), // This should be configurable
+				otlptracegrpc.WithEndpoint(cfg.Host),
+			),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("creating new exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter,
+			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
+			sdktrace.WithBatchTimeout(sdktrace.DefaultScheduleDelay*time.Millisecond),
+			sdktrace.WithMaxExportBatchSize(sdktrace.DefaultMaxExportBatchSize),
+		))
+
+	case ExporterStdout:
+		opts = append(opts, sdktrace.WithBatcher(newWriterExporter(os.Stdout)))
+
+	case ExporterNone:
+
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+	}
+
+	traceProvider := sdktrace.NewTracerProvider(opts...)
 
 	// We must set this provider as the global provider for things to work,
 	// but we pass this provider around the program where needed to collect
@@ -84,7 +109,8 @@ func StartTrace(ctx context.Context, tracer trace.Tracer, spanName string, endpo
 
 	switch {
 	case tracer != nil:
-		ctx, span = tracer.Start(ctx, spanName)
+		// The target is given at the start for the sampler to see it.
+		ctx, span = tracer.Start(ctx, spanName, trace.WithAttributes(semconv.HTTPTargetKey.String(endpoint)))
 		span.SetAttributes(attribute.String("endpoint", endpoint))
 
 	default:
@@ -98,3 +124,14 @@ func StartTrace(ctx context.Context, tracer trace.Tracer, spanName string, endpo
 
 	return ctx, span
 }
+
+// GetTraceID returns the trace id of the span in the context, or an empty
+// string when there is no span.
+func GetTraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+
+	return sc.TraceID().String()
+}
@@ -1,21 +1,40 @@
 package commands
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/pkg/keystore"
 )
 
+// KeyTypes lists the types of key that can sign auth tokens.
+var KeyTypes = []string{"rsa", "ecdsa", "ed25519"}
+
 // GenKey creates an x509 private/public key for auth tokens.
-func GenKey() error {
+func GenKey(args []string) error {
+	keyType, err := parseKeyType("genkey", args, nil)
+	if err != nil {
+		return err
+	}
 
-	// Generate a new private key.
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	privateKey, err := generateKey(keyType)
 	if err != nil {
-		return fmt.Errorf("generating key: %w", err)
+		return err
 	}
 
 	// Create a file for the private key information in PEM form.
@@ -25,15 +44,8 @@ func GenKey() error {
 	}
 	defer privateFile.Close()
 
-	// Construct a PEM block for the private key.
-	privateBlock := pem.Block{
-		Type:  "PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	}
-
-	// Write the private key to the private key file.
-	if err := pem.Encode(privateFile, &privateBlock); err != nil {
-		return fmt.Errorf("encoding to private file: %w", err)
+	if err := writePrivateKey(privateFile, privateKey); err != nil {
+		return err
 	}
 
 	// Create a file for the public key information in PEM form.
@@ -43,23 +55,168 @@ func GenKey() error {
 	}
 	defer publicFile.Close()
 
+	if err := writePublicKey(publicFile, privateKey); err != nil {
+		return err
+	}
+
+	fmt.Printf("private and public %s key files generated\n", keyType)
+	return nil
+}
+
+// KeyActivationDelay is how long a rotated key is only published before it
+// signs tokens. Clients cache the JWKS for up to 5 minutes, after the delay
+// every client knows the new key.
+const KeyActivationDelay = 5 * time.Minute
+
+// RotateKey generates a new signing key inside the keys folder and stages it
+// as the next key. Running services publish it right away and sign with it
+// once the activation delay elapsed, the key it replaces is then retired and
+// keeps verifying tokens during the grace period of the service.
+func RotateKey(keysFolder string, defaultKID string, args []string) error {
+	var activateAfter time.Duration
+
+	keyType, err := parseKeyType("rotate-key", args, func(fs *flag.FlagSet) {
+		fs.DurationVar(&activateAfter, "activate-after", KeyActivationDelay, "time the key is only published before it signs tokens")
+	})
+	if err != nil {
+		return err
+	}
+
+	mf, err := keystore.ReadManifest(os.DirFS(keysFolder))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+
+	if mf.ActiveKID == "" {
+		mf.ActiveKID = defaultKID
+	}
+
+	mf = mf.Promote(now)
+	if mf.Next != nil {
+		return fmt.Errorf("key %s is already staged, it activates at %s", mf.Next.KID, mf.Next.Activates.Format(time.RFC3339))
+	}
+
+	privateKey, err := generateKey(keyType)
+	if err != nil {
+		return err
+	}
+
+	newKID := uuid.NewString()
+
+	// The key has to be on disk before the manifest names it.
+	privateFile, err := os.OpenFile(filepath.Join(keysFolder, newKID+".pem"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("creating private file: %w", err)
+	}
+	defer privateFile.Close()
+
+	if err := writePrivateKey(privateFile, privateKey); err != nil {
+		return err
+	}
+
+	if err := privateFile.Close(); err != nil {
+		return fmt.Errorf("closing private file: %w", err)
+	}
+
+	mf.Next = &keystore.NextKey{
+		KID:       newKID,
+		Activates: now.Add(activateAfter),
+	}
+
+	if err := keystore.WriteManifest(keysFolder, mf); err != nil {
+		return err
+	}
+
+	fmt.Printf("key %s is published and replaces key %s at %s\n", newKID, mf.ActiveKID, mf.Next.Activates.Format(time.RFC3339))
+	fmt.Println("running services pick up the keys on their next watch or on SIGHUP")
+	return nil
+}
+
+// parseKeyType parses the -type flag of the key commands, keys are RSA by
+// default. Commands with more flags declare them with define.
+func parseKeyType(name string, args []string, define func(fs *flag.FlagSet)) (string, error) {
+	var keyType string
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&keyType, "type", "rsa", "type of the key: "+strings.Join(KeyTypes, ", "))
+	if define != nil {
+		define(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", fmt.Errorf("parse flags: %w", err)
+	}
+
+	if !slices.Contains(KeyTypes, keyType) {
+		fmt.Printf("help: %s [-type %s]\n", name, strings.Join(KeyTypes, "|"))
+		return "", ErrHelp
+	}
+
+	return keyType, nil
+}
+
+// generateKey generates a new private key of the type.
+func generateKey(keyType string) (crypto.Signer, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch keyType {
+	case "ecdsa":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ed25519":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	return privateKey, nil
+}
+
+// writePrivateKey writes the private key in PEM form.
+func writePrivateKey(w io.Writer, privateKey crypto.Signer) error {
+	var der []byte
+	switch pk := privateKey.(type) {
+	case *rsa.PrivateKey:
+		der = x509.MarshalPKCS1PrivateKey(pk)
+	default:
+		var err error
+		if der, err = x509.MarshalPKCS8PrivateKey(pk); err != nil {
+			return fmt.Errorf("marshaling private key: %w", err)
+		}
+	}
+
+	privateBlock := pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	}
+
+	if err := pem.Encode(w, &privateBlock); err != nil {
+		return fmt.Errorf("encoding to private file: %w", err)
+	}
+
+	return nil
+}
+
+// writePublicKey writes the public key of the private key in PEM form.
+func writePublicKey(w io.Writer, privateKey crypto.Signer) error {
 	// Marshal the public key from the private key to PKIX.
-	asn1Bytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	asn1Bytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
 	if err != nil {
 		return fmt.Errorf("marshaling public key: %w", err)
 	}
 
-	// Construct a PEM block for the public key.
 	publicBlock := pem.Block{
 		Type:  "PUBLIC KEY",
 		Bytes: asn1Bytes,
 	}
 
-	// Write the public key to the public key file.
-	if err := pem.Encode(publicFile, &publicBlock); err != nil {
+	if err := pem.Encode(w, &publicBlock); err != nil {
 		return fmt.Errorf("encoding to public file: %w", err)
 	}
 
-	fmt.Println("private and public key files generated")
 	return nil
 }
@@ -16,13 +16,9 @@ import (
 	"github.com/google/uuid"
 )
 
-// GenToken generates a JWT for the specified user.
-func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID uuid.UUID, kid string) error {
-	if kid == "" {
-		fmt.Println("help: gentoken <user_id> <kid>")
-		return ErrHelp
-	}
-
+// GenToken generates a JWT for the specified user that expires after ttl. The
+// token is signed with the active key unless a kid is given.
+func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, defaultKID string, issuer string, audiences []string, userID uuid.UUID, kid string, ttl time.Duration) error {
 	db, err := sqldb.Open(dbConfig)
 	if err != nil {
 		return fmt.Errorf("connect database: %w", err)
@@ -39,16 +35,21 @@ func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID
 		return fmt.Errorf("retrieve user: %w", err)
 	}
 
-	ks := keystore.New()
-	if err := ks.LoadRSAKeys(os.DirFS(keyPath)); err != nil {
+	ks := keystore.New(keystore.Config{DefaultKID: defaultKID})
+	if err := ks.LoadKeys(os.DirFS(keyPath)); err != nil {
 		return fmt.Errorf("reading keys: %w", err)
 	}
 
+	if kid == "" {
+		kid = ks.ActiveKID()
+	}
+
 	authCfg := auth.Config{
 		Log:       log,
 		DB:        db,
 		KeyLookup: ks,
-		Issuer:    "service project",
+		Issuer:    issuer,
+		Audiences: audiences,
 	}
 
 	ath, err := auth.New(authCfg)
@@ -58,7 +59,7 @@ func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID
 
 	// Generating a token requires defining a set of claims. In this applications
 	// case, we only care about defining the subject and the user in question and
-	// the roles they have on the database. This token will expire in a year.
+	// the roles they have on the database. This token expires after ttl.
 	//
 	// iss (issuer): Issuer of the JWT
 	// sub (subject): Subject of the JWT (the user)
@@ -69,12 +70,15 @@ func GenToken(log *logger.Logger, dbConfig sqldb.Config, keyPath string, userID
 	// jti (JWT ID): Unique identifier; can be used to prevent the JWT from being replayed (allows a token to be used only once)
 	claims := auth.Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   usr.ID.String(),
 			Issuer:    ath.Issuer(),
-			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(365 * 24 * time.Hour)),
+			Audience:  ath.Audiences(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		},
-		Roles: userbus.ParseRolesToString(usr.Roles),
+		Roles:        userbus.ParseRolesToString(usr.Roles),
+		TokenVersion: usr.TokenVersion,
 	}
 
 	// This will generate a JWT with the claims embedded in them. The database
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productio"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productstore/productdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/notify"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// ProductsImport upserts the products found in a CSV or NDJSON file.
+func ProductsImport(log *logger.Logger, cfg sqldb.Config, fileName string, dryRun bool) error {
+	if fileName == "" {
+		fmt.Println("help: products import <file.csv|file.ndjson> [dry-run]")
+		return ErrHelp
+	}
+
+	format, err := productio.ParseFormat(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if err != nil {
+		return fmt.Errorf("file extension: %w", err)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	rows, err := productio.Decode(format, file)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	productBus := productbus.NewBusiness(log, productdb.NewStore(log, db), notify.NewLogSink(log))
+
+	report, err := productBus.Import(ctx, sqldb.NewBeginner(db), rows, productbus.ImportOptions{DryRun: dryRun})
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	for _, res := range report.Results {
+		if res.Err != nil {
+			fmt.Printf("line %d: %s\n", res.Line, res.Err)
+		}
+	}
+
+	fmt.Printf("dry run: %t, created: %d, updated: %d, failed: %d\n", report.DryRun, report.Created, report.Updated, report.Failed)
+	return nil
+}
+
+// ProductsExport writes the product catalog to a CSV or NDJSON file.
+func ProductsExport(log *logger.Logger, cfg sqldb.Config, fileName string) error {
+	if fileName == "" {
+		fmt.Println("help: products export <file.csv|file.ndjson>")
+		return ErrHelp
+	}
+
+	format, err := productio.ParseFormat(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if err != nil {
+		return fmt.Errorf("file extension: %w", err)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer file.Close()
+
+	enc, err := productio.NewEncoder(format, file)
+	if err != nil {
+		return fmt.Errorf("new encoder: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	productBus := productbus.NewBusiness(log, productdb.NewStore(log, db), notify.NewLogSink(log))
+
+	total, err := productio.Export(ctx, productBus, productbus.QueryFilter{}, enc, nil)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	fmt.Println("products exported:", total)
+	return nil
+}
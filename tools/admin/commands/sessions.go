@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditstore/auditdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userstore/userdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// SessionsRevoke logs the user out everywhere. Access tokens issued until now
+// are rejected and every refresh token of the user is revoked.
+func SessionsRevoke(log *logger.Logger, cfg sqldb.Config, userID string) error {
+	if userID == "" {
+		fmt.Println("help: sessions revoke <user_id>")
+		return ErrHelp
+	}
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user id: %w", err)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := sqldb.NewBeginner(db).Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	userBus, err := userbus.NewBusiness(log, userdb.NewStore(log, db)).NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	auditBus, err := auditbus.NewBusiness(log, auditdb.NewStore(log, db)).NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	usr, err := userBus.QueryByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("retrieve user: %w", err)
+	}
+
+	if _, err := userBus.RevokeSessions(ctx, usr); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	_, err = auditBus.Record(ctx, auditbus.NewAudit{
+		UserID:  usr.ID,
+		Action:  auditbus.ActionSessionsRevoked,
+		Details: map[string]any{"source": "admin"},
+	})
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Println("sessions revoked for user:", usr.ID)
+	return nil
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/product/productstore/productdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/notify"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// ErrStockDiscrepancy is returned when a product quantity does not match its
+// stock movements.
+var ErrStockDiscrepancy = errors.New("stock discrepancies found")
+
+// StockReconcile compares every product quantity against the sum of its
+// stock movements and reports the products that don't match.
+func StockReconcile(log *logger.Logger, cfg sqldb.Config) error {
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	productBus := productbus.NewBusiness(log, productdb.NewStore(log, db), notify.NewLogSink(log))
+
+	discrepancies, err := productBus.Reconcile(ctx)
+	if err != nil {
+		return fmt.Errorf("reconcile: %w", err)
+	}
+
+	for _, d := range discrepancies {
+		fmt.Printf("product %s: quantity %d, movements total %d\n", d.ProductID, d.Quantity, d.MovementTotal)
+	}
+
+	if len(discrepancies) > 0 {
+		return fmt.Errorf("%d products: %w", len(discrepancies), ErrStockDiscrepancy)
+	}
+
+	fmt.Println("stock is consistent with the movement ledger")
+	return nil
+}
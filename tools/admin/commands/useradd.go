@@ -11,13 +11,19 @@ import (
 	"time"
 )
 
-// UserAdd adds new users into the database.
-func UserAdd(log *logger.Logger, cfg sqldb.Config, name, email, password string) error {
-	if name == "" || email == "" || password == "" {
-		fmt.Println("help: useradd <name> <email> <password>")
+// UserAdd adds new users into the database. The roles are taken from the
+// --role flags in args, a user without flags only gets the USER role.
+func UserAdd(log *logger.Logger, cfg sqldb.Config, name, email, password string, args []string) error {
+	roles, err := parseRoleFlags("useradd", args)
+	if name == "" || email == "" || password == "" || err != nil {
+		fmt.Println("help: useradd <name> <email> <password> [--role=<ROLE>]...")
 		return ErrHelp
 	}
 
+	if len(roles) == 0 {
+		roles = []userbus.Role{userbus.Roles.User}
+	}
+
 	db, err := sqldb.Open(cfg)
 	if err != nil {
 		return fmt.Errorf("connect database: %w", err)
@@ -38,7 +44,10 @@ func UserAdd(log *logger.Logger, cfg sqldb.Config, name, email, password string)
 		Name:     userbus.MustParseName(name),
 		Email:    *addr,
 		Password: password,
-		Roles:    []userbus.Role{userbus.Roles.Admin, userbus.Roles.User},
+		Roles:    roles,
+
+		// users created by an operator don't need to confirm their email
+		EmailConfirmed: true,
 	}
 
 	usr, err := userBus.Create(ctx, nu)
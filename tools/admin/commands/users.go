@@ -0,0 +1,333 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/audit/auditstore/auditdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/lockout/lockoutstore/lockoutdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userbus"
+	"github.com/nhannguyenacademy/ecommerce/internal/domain/user/userstore/userdb"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/page"
+	"github.com/nhannguyenacademy/ecommerce/internal/sdk/sdkbus/sqldb"
+	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
+)
+
+// roleFlags collects the roles given by repeated or comma separated
+// --role flags.
+type roleFlags []userbus.Role
+
+func (r *roleFlags) String() string {
+	return strings.Join(userbus.ParseRolesToString(*r), ",")
+}
+
+func (r *roleFlags) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		role, err := userbus.ParseRole(strings.ToUpper(strings.TrimSpace(v)))
+		if err != nil {
+			return err
+		}
+		if !userbus.RolesList(*r).Contains(role) {
+			*r = append(*r, role)
+		}
+	}
+
+	return nil
+}
+
+// parseRoleFlags parses the --role flags found in args.
+func parseRoleFlags(name string, args []string) ([]userbus.Role, error) {
+	var roles roleFlags
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&roles, "role", "role of the user, can be repeated")
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("parse flags: %w", err)
+	}
+
+	return roles, nil
+}
+
+// UsersList prints the users matching the filter flags.
+func UsersList(log *logger.Logger, cfg sqldb.Config, args []string) error {
+	var (
+		email   string
+		role    string
+		enabled string
+		pg      string
+		rows    string
+	)
+
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&email, "email", "", "part of the email")
+	fs.StringVar(&role, "role", "", "role of the users")
+	fs.StringVar(&enabled, "enabled", "", "true or false")
+	fs.StringVar(&pg, "page", "1", "page number")
+	fs.StringVar(&rows, "rows", "50", "rows per page")
+	if err := fs.Parse(args); err != nil {
+		fmt.Println("help: users list [--email=<part>] [--role=<ROLE>] [--enabled=<true|false>] [--page=<n>] [--rows=<n>]")
+		return ErrHelp
+	}
+
+	var filter userbus.QueryFilter
+	if email != "" {
+		filter.Email = &email
+	}
+
+	if role != "" {
+		r, err := userbus.ParseRole(strings.ToUpper(role))
+		if err != nil {
+			return fmt.Errorf("parse role: %w", err)
+		}
+		filter.Role = &r
+	}
+
+	switch enabled {
+	case "":
+	case "true", "false":
+		e := enabled == "true"
+		filter.Enabled = &e
+	default:
+		return fmt.Errorf("parse enabled: %q is not true or false", enabled)
+	}
+
+	p, err := page.Parse(pg, rows)
+	if err != nil {
+		return fmt.Errorf("parse page: %w", err)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userBus := userbus.NewBusiness(log, userdb.NewStore(log, db))
+
+	users, err := userBus.Query(ctx, filter, userbus.DefaultSortBy, p)
+	if err != nil {
+		return fmt.Errorf("query users: %w", err)
+	}
+
+	total, err := userBus.Count(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("count users: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tNAME\tROLES\tENABLED\tCONFIRMED\tCREATED")
+	for _, usr := range users {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%t\t%s\n",
+			usr.ID,
+			usr.Email.Address,
+			usr.Name,
+			strings.Join(userbus.ParseRolesToString(usr.Roles), ","),
+			usr.Enabled,
+			usr.EmailConfirmed,
+			usr.DateCreated.Format(time.RFC3339),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	fmt.Printf("page %d, %d of %d users\n", p.Number(), len(users), total)
+	return nil
+}
+
+// UsersRoles replaces the roles of the user with the roles of the --role flags.
+func UsersRoles(log *logger.Logger, cfg sqldb.Config, userID string, args []string) error {
+	roles, err := parseRoleFlags("users roles", args)
+	if userID == "" || err != nil || len(roles) == 0 {
+		fmt.Println("help: users roles <user_id> --role=<ROLE> [--role=...]")
+		return ErrHelp
+	}
+
+	return updateUser(log, cfg, userID, func(ctx context.Context, bus usersBus, usr userbus.User) error {
+		updated, err := bus.user.Update(ctx, usr, userbus.UpdateUser{Roles: roles})
+		if err != nil {
+			return fmt.Errorf("update roles: %w", err)
+		}
+
+		_, err = bus.audit.Record(ctx, auditbus.NewAudit{
+			UserID: usr.ID,
+			Action: auditbus.ActionRolesChanged,
+			Details: map[string]any{
+				"source": "admin",
+				"from":   userbus.ParseRolesToString(usr.Roles),
+				"to":     userbus.ParseRolesToString(updated.Roles),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+
+		fmt.Printf("roles of user %s: %s\n", usr.ID, strings.Join(userbus.ParseRolesToString(updated.Roles), ","))
+		return nil
+	})
+}
+
+// UsersEnable enables or disables the account of the user.
+func UsersEnable(log *logger.Logger, cfg sqldb.Config, userID string, enabled bool) error {
+	if userID == "" {
+		fmt.Println("help: users <enable|disable> <user_id>")
+		return ErrHelp
+	}
+
+	return updateUser(log, cfg, userID, func(ctx context.Context, bus usersBus, usr userbus.User) error {
+		if _, err := bus.user.Update(ctx, usr, userbus.UpdateUser{Enabled: &enabled}); err != nil {
+			return fmt.Errorf("update enabled: %w", err)
+		}
+
+		action := auditbus.ActionUserDisabled
+		if enabled {
+			action = auditbus.ActionUserEnabled
+		}
+
+		_, err := bus.audit.Record(ctx, auditbus.NewAudit{
+			UserID:  usr.ID,
+			Action:  action,
+			Details: map[string]any{"source": "admin"},
+		})
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+
+		fmt.Printf("user %s enabled: %t\n", usr.ID, enabled)
+		return nil
+	})
+}
+
+// UsersConfirmEmail marks the email of the user as confirmed.
+func UsersConfirmEmail(log *logger.Logger, cfg sqldb.Config, userID string) error {
+	if userID == "" {
+		fmt.Println("help: users confirm-email <user_id>")
+		return ErrHelp
+	}
+
+	return updateUser(log, cfg, userID, func(ctx context.Context, bus usersBus, usr userbus.User) error {
+		if _, err := bus.user.ForceConfirmEmail(ctx, usr); err != nil {
+			return fmt.Errorf("confirm email: %w", err)
+		}
+
+		_, err := bus.audit.Record(ctx, auditbus.NewAudit{
+			UserID:  usr.ID,
+			Action:  auditbus.ActionEmailConfirmed,
+			Details: map[string]any{"source": "admin"},
+		})
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+
+		fmt.Println("email confirmed for user:", usr.ID)
+		return nil
+	})
+}
+
+// UsersUnlock clears the failed logins and the lockout of the account of the
+// user.
+func UsersUnlock(log *logger.Logger, cfg sqldb.Config, userID string) error {
+	if userID == "" {
+		fmt.Println("help: users unlock <user_id>")
+		return ErrHelp
+	}
+
+	return updateUser(log, cfg, userID, func(ctx context.Context, bus usersBus, usr userbus.User) error {
+		if err := bus.lockout.Reset(ctx, usr.Email.Address); err != nil {
+			return fmt.Errorf("unlock: %w", err)
+		}
+
+		_, err := bus.audit.Record(ctx, auditbus.NewAudit{
+			UserID:  usr.ID,
+			Action:  auditbus.ActionUserUnlocked,
+			Details: map[string]any{"source": "admin"},
+		})
+		if err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+
+		fmt.Println("account unlocked for user:", usr.ID)
+		return nil
+	})
+}
+
+// usersBus holds the businesses used to manage a user, bound to the
+// transaction of the command.
+type usersBus struct {
+	user    *userbus.Business
+	audit   *auditbus.Business
+	lockout *lockoutbus.Business
+}
+
+// updateUser runs fn on the user inside a transaction.
+func updateUser(log *logger.Logger, cfg sqldb.Config, userID string, fn func(ctx context.Context, bus usersBus, usr userbus.User) error) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return fmt.Errorf("parse user id: %w", err)
+	}
+
+	db, err := sqldb.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := sqldb.NewBeginner(db).Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	userBus, err := userbus.NewBusiness(log, userdb.NewStore(log, db)).NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	auditBus, err := auditbus.NewBusiness(log, auditdb.NewStore(log, db)).NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	lockoutBus, err := lockoutbus.NewBusiness(log, lockoutdb.NewStore(log, db), lockoutbus.Config{}).NewWithTx(tx)
+	if err != nil {
+		return fmt.Errorf("new with tx: %w", err)
+	}
+
+	usr, err := userBus.QueryByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("retrieve user: %w", err)
+	}
+
+	bus := usersBus{
+		user:    userBus,
+		audit:   auditBus,
+		lockout: lockoutBus,
+	}
+
+	if err := fn(ctx, bus, usr); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	return nil
+}
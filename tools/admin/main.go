@@ -11,6 +11,7 @@ import (
 	"github.com/nhannguyenacademy/ecommerce/tools/admin/commands"
 	"io"
 	"os"
+	"time"
 
 	"github.com/ardanlabs/conf/v3"
 	"github.com/nhannguyenacademy/ecommerce/pkg/logger"
@@ -31,8 +32,11 @@ type config struct {
 		DisableTLS   bool   `conf:"default:true"`
 	}
 	Auth struct {
-		KeysFolder string `conf:"default:configs/keys/"`
-		DefaultKID string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+		KeysFolder string        `conf:"default:configs/keys/"`
+		DefaultKID string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+		Issuer     string        `conf:"default:ecommerce"`
+		Audiences  []string      `conf:"default:ecommerce-api"`
+		TokenTTL   time.Duration `conf:"default:1h"`
 	}
 }
 
@@ -116,25 +120,100 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 		name := args.Num(1)
 		email := args.Num(2)
 		password := args.Num(3)
-		if err := commands.UserAdd(log, dbConfig, name, email, password); err != nil {
+		if err := commands.UserAdd(log, dbConfig, name, email, password, flagArgs(args, 4)); err != nil {
 			return fmt.Errorf("adding user: %w", err)
 		}
 
+	case "users":
+		switch args.Num(1) {
+		case "list":
+			if err := commands.UsersList(log, dbConfig, flagArgs(args, 2)); err != nil {
+				return fmt.Errorf("listing users: %w", err)
+			}
+
+		case "roles":
+			if err := commands.UsersRoles(log, dbConfig, args.Num(2), flagArgs(args, 3)); err != nil {
+				return fmt.Errorf("changing roles: %w", err)
+			}
+
+		case "enable", "disable":
+			if err := commands.UsersEnable(log, dbConfig, args.Num(2), args.Num(1) == "enable"); err != nil {
+				return fmt.Errorf("changing enabled: %w", err)
+			}
+
+		case "confirm-email":
+			if err := commands.UsersConfirmEmail(log, dbConfig, args.Num(2)); err != nil {
+				return fmt.Errorf("confirming email: %w", err)
+			}
+
+		case "unlock":
+			if err := commands.UsersUnlock(log, dbConfig, args.Num(2)); err != nil {
+				return fmt.Errorf("unlocking user: %w", err)
+			}
+
+		default:
+			fmt.Println("help: users <list|roles|enable|disable|confirm-email|unlock> [<user_id>] [flags]")
+			return commands.ErrHelp
+		}
+
+	case "products":
+		switch args.Num(1) {
+		case "import":
+			dryRun := args.Num(3) == "dry-run"
+			if err := commands.ProductsImport(log, dbConfig, args.Num(2), dryRun); err != nil {
+				return fmt.Errorf("importing products: %w", err)
+			}
+
+		case "export":
+			if err := commands.ProductsExport(log, dbConfig, args.Num(2)); err != nil {
+				return fmt.Errorf("exporting products: %w", err)
+			}
+
+		default:
+			fmt.Println("help: products <import|export> <file.csv|file.ndjson>")
+			return commands.ErrHelp
+		}
+
+	case "stock":
+		switch args.Num(1) {
+		case "reconcile":
+			if err := commands.StockReconcile(log, dbConfig); err != nil {
+				return fmt.Errorf("reconciling stock: %w", err)
+			}
+
+		default:
+			fmt.Println("help: stock reconcile")
+			return commands.ErrHelp
+		}
+
+	case "sessions":
+		switch args.Num(1) {
+		case "revoke":
+			if err := commands.SessionsRevoke(log, dbConfig, args.Num(2)); err != nil {
+				return fmt.Errorf("revoking sessions: %w", err)
+			}
+
+		default:
+			fmt.Println("help: sessions revoke <user_id>")
+			return commands.ErrHelp
+		}
+
 	case "genkey":
-		if err := commands.GenKey(); err != nil {
+		if err := commands.GenKey(flagArgs(args, 1)); err != nil {
 			return fmt.Errorf("key generation: %w", err)
 		}
 
+	case "rotate-key":
+		if err := commands.RotateKey(cfg.Auth.KeysFolder, cfg.Auth.DefaultKID, flagArgs(args, 1)); err != nil {
+			return fmt.Errorf("key rotation: %w", err)
+		}
+
 	case "gentoken":
 		userID, err := uuid.Parse(args.Num(1))
 		if err != nil {
 			return fmt.Errorf("generating token: %w", err)
 		}
-		kid := args.Num(2)
-		if kid == "" {
-			kid = cfg.Auth.DefaultKID
-		}
-		if err := commands.GenToken(log, dbConfig, cfg.Auth.KeysFolder, userID, kid); err != nil {
+		if err := commands.GenToken(log, dbConfig, cfg.Auth.KeysFolder, cfg.Auth.DefaultKID, cfg.Auth.Issuer, cfg.Auth.Audiences, userID, args.Num(2), cfg.Auth.TokenTTL); err != nil {
 			return fmt.Errorf("generating token: %w", err)
 		}
 
@@ -142,8 +221,12 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 		fmt.Println("migrate:    create the schema in the database")
 		fmt.Println("seed:       add data to the database")
 		fmt.Println("useradd:    add a new user to the database")
-		fmt.Println("users:      get a list of users from the database")
+		fmt.Println("users:      list users and manage their roles, status and email")
+		fmt.Println("products:   import or export the product catalog as csv or ndjson")
+		fmt.Println("stock:      reconcile product quantities with the stock movements")
+		fmt.Println("sessions:   revoke every session of a user")
 		fmt.Println("genkey:     generate a set of private/public key files")
+		fmt.Println("rotate-key: stage a new signing key that replaces the active one after -activate-after")
 		fmt.Println("gentoken:   generate a JWT for a user with claims")
 		fmt.Println("provide a command to get more help.")
 		return commands.ErrHelp
@@ -151,3 +234,13 @@ func processCommands(args conf.Args, log *logger.Logger, cfg config) error {
 
 	return nil
 }
+
+// flagArgs returns the arguments following the first n positional arguments,
+// these hold the flags of the command.
+func flagArgs(args conf.Args, n int) []string {
+	if len(args) <= n {
+		return nil
+	}
+
+	return args[n:]
+}